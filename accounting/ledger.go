@@ -0,0 +1,245 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gpu-pro/energy"
+)
+
+// Usage accumulates GPU consumption for a user or group
+type Usage struct {
+	GPUHours         float64 `json:"gpu_hours"`
+	GPUMemoryGBHours float64 `json:"gpu_memory_gb_hours"`
+	EnergyKWh        float64 `json:"energy_kwh"`
+}
+
+func (u *Usage) add(o Usage) {
+	u.GPUHours += o.GPUHours
+	u.GPUMemoryGBHours += o.GPUMemoryGBHours
+	u.EnergyKWh += o.EnergyKWh
+}
+
+// Record is the usage of one user (in one group) during one hour
+type Record struct {
+	Hour  time.Time `json:"hour"` // Start of the hour (UTC)
+	User  string    `json:"user"`
+	Group string    `json:"group"`
+	Usage
+}
+
+// ReportEntry is the aggregated usage of one user or group over a report period
+type ReportEntry struct {
+	Key string `json:"key"`
+	Usage
+}
+
+// Ledger accumulates per-user GPU usage in hourly buckets and persists it to a JSON file
+type Ledger struct {
+	path      string
+	meter     *energy.Meter      // Same measurement as the energy tracker, so both report the same kWh
+	records   map[string]*Record // "hour|user|group" -> record
+	retention time.Duration      // Records older than this are dropped on save (0 keeps everything)
+	mu        sync.RWMutex
+}
+
+// NewLedger creates a ledger backed by the given file, loading previously recorded usage
+func NewLedger(path string) *Ledger {
+	l := &Ledger{
+		path:    path,
+		meter:   energy.NewMeter(),
+		records: make(map[string]*Record),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return l
+	}
+
+	var records []*Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return l
+	}
+	for _, r := range records {
+		l.records[recordKey(r.Hour, r.User, r.Group)] = r
+	}
+	return l
+}
+
+// SetRetention sets how long hourly records are kept; older ones are dropped on save
+func (l *Ledger) SetRetention(d time.Duration) {
+	l.mu.Lock()
+	l.retention = d
+	l.mu.Unlock()
+}
+
+func recordKey(hour time.Time, user, group string) string {
+	return hour.Format(time.RFC3339) + "|" + user + "|" + group
+}
+
+// Record attributes one sampling interval of GPU usage to the owners of the running processes.
+// Each GPU is split between its processes by their share of used GPU memory (evenly if unknown).
+// Energy is measured by an energy.Meter, from the NVML energy counter where available.
+func (l *Ledger) Record(gpus map[string]interface{}, processes []map[string]interface{}, interval time.Duration, at time.Time) {
+	hours := interval.Hours()
+	if hours <= 0 {
+		return
+	}
+
+	// Group processes by the GPU they run on
+	byGPU := make(map[string][]map[string]interface{})
+	for _, proc := range processes {
+		if gpuID, ok := proc["gpu_id"].(string); ok {
+			byGPU[gpuID] = append(byGPU[gpuID], proc)
+		}
+	}
+
+	hour := at.UTC().Truncate(time.Hour)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Measure every GPU, busy or not, to keep the energy counter baselines current
+	energyKWh := make(map[string]float64, len(gpus))
+	for gpuID, gpu := range gpus {
+		if data, ok := gpu.(map[string]interface{}); ok {
+			energyKWh[gpuID] = l.meter.Delta(gpuID, data, interval)
+		}
+	}
+
+	for gpuID, procs := range byGPU {
+
+		totalMemory := 0.0
+		for _, proc := range procs {
+			if mem, ok := proc["memory"].(float64); ok {
+				totalMemory += mem
+			}
+		}
+
+		for _, proc := range procs {
+			memory, _ := proc["memory"].(float64)
+			share := 1.0 / float64(len(procs))
+			if totalMemory > 0 {
+				share = memory / totalMemory
+			}
+
+			user, _ := proc["user"].(string)
+			if user == "" {
+				user = "unknown"
+			}
+			group, _ := proc["group"].(string)
+			if group == "" {
+				group = "unknown"
+			}
+
+			key := recordKey(hour, user, group)
+			record, ok := l.records[key]
+			if !ok {
+				record = &Record{Hour: hour, User: user, Group: group}
+				l.records[key] = record
+			}
+			record.add(Usage{
+				GPUHours:         share * hours,
+				GPUMemoryGBHours: memory / 1024 * hours,
+				EnergyKWh:        energyKWh[gpuID] * share,
+			})
+		}
+	}
+}
+
+// Report aggregates usage in [from, to) by "user" or "group"
+func (l *Ledger) Report(from, to time.Time, groupBy string) []ReportEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	totals := make(map[string]*Usage)
+	for _, r := range l.records {
+		if r.Hour.Before(from.UTC().Truncate(time.Hour)) || !r.Hour.Before(to) {
+			continue
+		}
+
+		key := r.User
+		if groupBy == "group" {
+			key = r.Group
+		}
+		if totals[key] == nil {
+			totals[key] = &Usage{}
+		}
+		totals[key].add(r.Usage)
+	}
+
+	entries := make([]ReportEntry, 0, len(totals))
+	for key, usage := range totals {
+		entries = append(entries, ReportEntry{Key: key, Usage: *usage})
+	}
+
+	// Largest consumers first
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].GPUHours != entries[j].GPUHours {
+			return entries[i].GPUHours > entries[j].GPUHours
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// WriteCSV writes report entries as CSV with a header row
+func WriteCSV(w io.Writer, groupBy string, entries []ReportEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{groupBy, "gpu_hours", "gpu_memory_gb_hours", "energy_kwh"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{
+			e.Key,
+			strconv.FormatFloat(e.GPUHours, 'f', 4, 64),
+			strconv.FormatFloat(e.GPUMemoryGBHours, 'f', 4, 64),
+			strconv.FormatFloat(e.EnergyKWh, 'f', 4, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Save drops records past the retention period and writes the rest to the ledger file
+func (l *Ledger) Save() error {
+	l.mu.Lock()
+	cutoff := time.Now().Add(-l.retention).UTC().Truncate(time.Hour)
+	records := make([]Record, 0, len(l.records))
+	for key, r := range l.records {
+		if l.retention > 0 && r.Hour.Before(cutoff) {
+			delete(l.records, key)
+			continue
+		}
+		records = append(records, *r)
+	}
+	l.mu.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].Hour.Equal(records[j].Hour) {
+			return records[i].Hour.Before(records[j].Hour)
+		}
+		return records[i].User < records[j].User
+	})
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write atomically so a crash mid-write never truncates the ledger
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, l.path)
+}
@@ -0,0 +1,143 @@
+package accounting
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gpu-pro/energy"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestLedgerRecordSplitsByMemory(t *testing.T) {
+	ledger := NewLedger(filepath.Join(t.TempDir(), "ledger.json"))
+	at := time.Date(2026, 10, 5, 12, 30, 0, 0, time.UTC)
+
+	gpus := map[string]interface{}{
+		"0": map[string]interface{}{"power_draw": 300.0},
+	}
+	processes := []map[string]interface{}{
+		{"gpu_id": "0", "user": "alice", "group": "ml", "memory": 3072.0},
+		{"gpu_id": "0", "user": "bob", "group": "vision", "memory": 1024.0},
+	}
+
+	ledger.Record(gpus, processes, time.Hour, at)
+
+	entries := ledger.Report(at.Add(-24*time.Hour), at.Add(24*time.Hour), "user")
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	alice := entries[0]
+	if alice.Key != "alice" {
+		t.Fatalf("largest consumer = %s, want alice", alice.Key)
+	}
+	if !almostEqual(alice.GPUHours, 0.75) {
+		t.Errorf("alice GPU hours = %f, want 0.75", alice.GPUHours)
+	}
+	if !almostEqual(alice.GPUMemoryGBHours, 3) {
+		t.Errorf("alice GPU memory GB hours = %f, want 3", alice.GPUMemoryGBHours)
+	}
+	if !almostEqual(alice.EnergyKWh, 0.225) {
+		t.Errorf("alice energy = %f kWh, want 0.225", alice.EnergyKWh)
+	}
+
+	groups := ledger.Report(at.Add(-24*time.Hour), at.Add(24*time.Hour), "group")
+	if len(groups) != 2 || groups[0].Key != "ml" {
+		t.Errorf("unexpected group report: %+v", groups)
+	}
+}
+
+func TestLedgerReportRangeAndPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	ledger := NewLedger(path)
+
+	processes := []map[string]interface{}{{"gpu_id": "0", "user": "alice", "group": "ml", "memory": 1024.0}}
+	september := time.Date(2026, 9, 30, 23, 0, 0, 0, time.UTC)
+	october := time.Date(2026, 10, 1, 1, 0, 0, 0, time.UTC)
+	ledger.Record(map[string]interface{}{}, processes, time.Hour, september)
+	ledger.Record(map[string]interface{}{}, processes, 2*time.Hour, october)
+
+	if err := ledger.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reloaded := NewLedger(path)
+	entries := reloaded.Report(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), "user")
+	if len(entries) != 1 || !almostEqual(entries[0].GPUHours, 2) {
+		t.Fatalf("October report = %+v, want 2 GPU hours for alice", entries)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, "user", entries); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != "user,gpu_hours,gpu_memory_gb_hours,energy_kwh" || !strings.HasPrefix(lines[1], "alice,2.0000,") {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestLedgerRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	ledger := NewLedger(path)
+	ledger.SetRetention(30 * 24 * time.Hour)
+
+	gpus := map[string]interface{}{"0": map[string]interface{}{"power_draw": 100.0}}
+	processes := []map[string]interface{}{{"gpu_id": "0", "user": "alice", "group": "ml"}}
+	now := time.Now()
+	ledger.Record(gpus, processes, time.Hour, now.Add(-60*24*time.Hour))
+	ledger.Record(gpus, processes, time.Hour, now)
+
+	if err := ledger.Save(); err != nil {
+		t.Fatal(err)
+	}
+	entries := NewLedger(path).Report(now.Add(-90*24*time.Hour), now.Add(time.Hour), "user")
+	if len(entries) != 1 || !almostEqual(entries[0].GPUHours, 1) {
+		t.Errorf("entries after save = %+v, want only the last hour", entries)
+	}
+}
+
+func TestLedgerEnergyMatchesTracker(t *testing.T) {
+	dir := t.TempDir()
+	ledger := NewLedger(filepath.Join(dir, "ledger.json"))
+	tracker := energy.NewTracker(filepath.Join(dir, "energy.json"), energy.Pricing{})
+
+	processes := []map[string]interface{}{
+		{"pid": "1", "name": "train", "gpu_id": "0", "user": "alice", "group": "ml", "memory": 3000.0},
+		{"pid": "2", "name": "eval", "gpu_id": "0", "user": "bob", "group": "ml", "memory": 1000.0},
+	}
+	at := time.Now()
+	// The NVML counter disagrees with power draw; both must use the counter
+	for i, counterWh := range []float64{5000, 5400, 6000} {
+		gpus := map[string]interface{}{
+			"0": map[string]interface{}{"uuid": "GPU-a", "energy_consumption_wh": counterWh, "power_draw": 50.0},
+		}
+		sampleAt := at.Add(time.Duration(i) * time.Minute)
+		ledger.Record(gpus, processes, time.Minute, sampleAt)
+		tracker.Record(gpus, processes, time.Minute, sampleAt)
+	}
+
+	tracked := map[string]float64{}
+	for _, u := range tracker.Summary()["users"].([]energy.Consumption) {
+		tracked[u.Key] = u.KWh
+	}
+	entries := ledger.Report(at.Add(-time.Hour), at.Add(time.Hour), "user")
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	for _, e := range entries {
+		if !almostEqual(e.EnergyKWh, tracked[e.Key]) {
+			t.Errorf("%s ledger energy = %v kWh, tracker = %v kWh", e.Key, e.EnergyKWh, tracked[e.Key])
+		}
+	}
+	if !almostEqual(entries[0].EnergyKWh, 0.75) {
+		t.Errorf("alice energy = %v kWh, want 0.75 from the counter", entries[0].EnergyKWh)
+	}
+}
@@ -10,25 +10,66 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"sort"
+	"sync"
 	"time"
 )
 
 const (
-	// Analytics backend URL - update this to your deployed Worker URL
-	analyticsURL = "https://a.ulixlab.com/heartbeat"
-	// Heartbeat interval (5 minutes)
-	heartbeatInterval = 5 * time.Minute
+	// DefaultEndpoint is the public analytics backend
+	DefaultEndpoint = "https://a.ulixlab.com/heartbeat"
+	// DefaultInterval is how often heartbeats are sent
+	DefaultInterval = 5 * time.Minute
 )
 
+// Settings control whether and where heartbeats are sent
+type Settings struct {
+	Enabled  bool
+	Endpoint string        // Collector URL; empty for DefaultEndpoint
+	DryRun   bool          // Log the payload instead of sending it
+	Interval time.Duration // Zero for DefaultInterval
+}
+
+var (
+	settings   = Settings{Enabled: true}
+	clients    = make(map[*HeartbeatClient]bool) // Started clients, reported by CurrentStatus
+	settingsMu sync.RWMutex
+)
+
+// Configure sets the heartbeat settings. It applies to clients started afterwards,
+// so it should be called before creating the monitor or hub.
+func Configure(s Settings) {
+	settingsMu.Lock()
+	settings = s
+	settingsMu.Unlock()
+}
+
+func currentSettings() Settings {
+	settingsMu.RLock()
+	s := settings
+	settingsMu.RUnlock()
+	if s.Endpoint == "" {
+		s.Endpoint = DefaultEndpoint
+	}
+	if s.Interval <= 0 {
+		s.Interval = DefaultInterval
+	}
+	return s
+}
+
 // HeartbeatClient manages analytics heartbeats
 type HeartbeatClient struct {
 	clientID  string
 	version   string
 	appType   string
 	gpuInfo   string
+	settings  Settings // Taken when the client starts
+	lastSent  time.Time
+	lastError string
 	ticker    *time.Ticker
 	stopChan  chan bool
 	isRunning bool
+	mu        sync.Mutex
 }
 
 // HeartbeatPayload represents the data sent to analytics backend
@@ -68,25 +109,36 @@ func generateClientID() string {
 	return hex.EncodeToString(hash[:])[:32]
 }
 
-// Start begins sending heartbeats
+// Start begins sending heartbeats. A disabled client sends nothing but still reports
+// the payload it would send in CurrentStatus.
 func (hb *HeartbeatClient) Start() {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
 	if hb.isRunning {
 		return
 	}
 
 	hb.isRunning = true
-	//log.Println("📡 Starting analytics heartbeat (interval: 5 minutes)")
+	hb.settings = currentSettings()
+	settingsMu.Lock()
+	clients[hb] = true
+	settingsMu.Unlock()
+
+	if !hb.settings.Enabled {
+		return
+	}
 
 	// Send initial heartbeat
 	go hb.sendHeartbeat()
 
 	// Setup ticker for periodic heartbeats
-	hb.ticker = time.NewTicker(heartbeatInterval)
+	hb.ticker = time.NewTicker(hb.settings.Interval)
+	ticker := hb.ticker
 
 	go func() {
 		for {
 			select {
-			case <-hb.ticker.C:
+			case <-ticker.C:
 				hb.sendHeartbeat()
 			case <-hb.stopChan:
 				return
@@ -97,28 +149,36 @@ func (hb *HeartbeatClient) Start() {
 
 // Stop stops sending heartbeats
 func (hb *HeartbeatClient) Stop() {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
 	if !hb.isRunning {
 		return
 	}
 
 	hb.isRunning = false
+	settingsMu.Lock()
+	delete(clients, hb)
+	settingsMu.Unlock()
 	if hb.ticker != nil {
 		hb.ticker.Stop()
 	}
 	close(hb.stopChan)
-	//log.Println("📡 Analytics heartbeat stopped")
 }
 
 // SetGPUInfo updates GPU information
 func (hb *HeartbeatClient) SetGPUInfo(gpuInfo string) {
+	hb.mu.Lock()
 	hb.gpuInfo = gpuInfo
+	hb.mu.Unlock()
 }
 
-// sendHeartbeat sends a heartbeat to the analytics backend
-func (hb *HeartbeatClient) sendHeartbeat() {
+// Payload returns the heartbeat the client sends next
+func (hb *HeartbeatClient) Payload() HeartbeatPayload {
 	hostname, _ := os.Hostname()
 
-	payload := HeartbeatPayload{
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	return HeartbeatPayload{
 		ClientID: hb.clientID,
 		Hostname: hostname,
 		AppType:  hb.appType,
@@ -126,29 +186,106 @@ func (hb *HeartbeatClient) sendHeartbeat() {
 		OSInfo:   fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
 		Version:  hb.version,
 	}
+}
 
-	jsonData, err := json.Marshal(payload)
+// sendHeartbeat sends a heartbeat to the analytics backend, or logs it in dry-run mode
+func (hb *HeartbeatClient) sendHeartbeat() {
+	jsonData, err := json.Marshal(hb.Payload())
 	if err != nil {
 		log.Printf("⚠️  Failed to marshal heartbeat payload: %v", err)
 		return
 	}
 
+	hb.mu.Lock()
+	s := hb.settings
+	hb.mu.Unlock()
+
+	if s.DryRun {
+		log.Printf("📡 Analytics heartbeat (dry run, not sent to %s): %s", s.Endpoint, jsonData)
+		hb.recordSend(nil)
+		return
+	}
+
 	// Send heartbeat in background (don't block if it fails)
 	go func() {
 		client := &http.Client{
 			Timeout: 10 * time.Second,
 		}
 
-		resp, err := client.Post(analyticsURL, "application/json", bytes.NewBuffer(jsonData))
+		resp, err := client.Post(s.Endpoint, "application/json", bytes.NewBuffer(jsonData))
 		if err != nil {
 			// Silently fail - don't spam logs if analytics is down
+			hb.recordSend(err)
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			// Silently fail
+			hb.recordSend(fmt.Errorf("collector returned %s", resp.Status))
 			return
 		}
+		hb.recordSend(nil)
 	}()
 }
+
+func (hb *HeartbeatClient) recordSend(err error) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	hb.lastSent = time.Now()
+	hb.lastError = ""
+	if err != nil {
+		hb.lastError = err.Error()
+	}
+}
+
+// ClientStatus is one running heartbeat client
+type ClientStatus struct {
+	Payload   HeartbeatPayload `json:"payload"`
+	LastSent  *time.Time       `json:"last_sent,omitempty"` // Last attempt (logged, in dry-run mode)
+	LastError string           `json:"last_error,omitempty"`
+}
+
+// Status describes the heartbeat settings and what each client sends
+type Status struct {
+	Enabled  bool           `json:"enabled"`
+	DryRun   bool           `json:"dry_run"`
+	Endpoint string         `json:"endpoint"`
+	Interval float64        `json:"interval"` // Seconds
+	Clients  []ClientStatus `json:"clients"`
+}
+
+// CurrentStatus returns the heartbeat settings and the payloads of the running clients
+func CurrentStatus() Status {
+	s := currentSettings()
+	status := Status{
+		Enabled:  s.Enabled,
+		DryRun:   s.DryRun,
+		Endpoint: s.Endpoint,
+		Interval: s.Interval.Seconds(),
+		Clients:  []ClientStatus{},
+	}
+
+	settingsMu.RLock()
+	running := make([]*HeartbeatClient, 0, len(clients))
+	for hb := range clients {
+		running = append(running, hb)
+	}
+	settingsMu.RUnlock()
+
+	for _, hb := range running {
+		cs := ClientStatus{Payload: hb.Payload()}
+		hb.mu.Lock()
+		if !hb.lastSent.IsZero() {
+			sent := hb.lastSent
+			cs.LastSent = &sent
+		}
+		cs.LastError = hb.lastError
+		hb.mu.Unlock()
+		status.Clients = append(status.Clients, cs)
+	}
+	sort.Slice(status.Clients, func(i, j int) bool {
+		a, b := status.Clients[i].Payload, status.Clients[j].Payload
+		return a.AppType+a.Version < b.AppType+b.Version
+	})
+	return status
+}
@@ -0,0 +1,79 @@
+package analytics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	defer Configure(Settings{Enabled: true})
+
+	received := make(chan HeartbeatPayload, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p HeartbeatPayload
+		json.NewDecoder(r.Body).Decode(&p)
+		received <- p
+	}))
+	defer collector.Close()
+
+	expectNone := func(name string) {
+		t.Helper()
+		select {
+		case p := <-received:
+			t.Errorf("%s client sent %+v", name, p)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// A disabled client sends nothing but still shows its payload
+	Configure(Settings{Enabled: false, Endpoint: collector.URL})
+	disabled := NewHeartbeatClient("v2.0", "tui")
+	disabled.SetGPUInfo("NVIDIA H100")
+	disabled.Start()
+	expectNone("disabled")
+	status := CurrentStatus()
+	if status.Enabled || status.Endpoint != collector.URL || status.Interval != DefaultInterval.Seconds() {
+		t.Errorf("status = %+v", status)
+	}
+	if len(status.Clients) != 1 || status.Clients[0].Payload.GPUInfo != "NVIDIA H100" || status.Clients[0].LastSent != nil {
+		t.Errorf("clients = %+v", status.Clients)
+	}
+	disabled.Stop()
+
+	// Dry run logs instead of sending
+	Configure(Settings{Enabled: true, DryRun: true, Endpoint: collector.URL})
+	dryRun := NewHeartbeatClient("v2.0", "webui")
+	dryRun.Start()
+	expectNone("dry-run")
+	if status := CurrentStatus(); len(status.Clients) != 1 || status.Clients[0].LastSent == nil {
+		t.Errorf("dry-run status = %+v", status)
+	}
+	dryRun.Stop()
+
+	// Enabled clients send to the configured collector on start and every interval
+	Configure(Settings{Enabled: true, Endpoint: collector.URL, Interval: 50 * time.Millisecond})
+	hb := NewHeartbeatClient("v2.0-hub", "webui")
+	hb.Start()
+	defer hb.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case p := <-received:
+			if p.Version != "v2.0-hub" || p.ClientID != hb.Payload().ClientID {
+				t.Errorf("payload = %+v", p)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("heartbeat %d not received", i+1)
+		}
+	}
+
+	// Settings changed later do not affect a running client
+	Configure(Settings{Enabled: false})
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Error("running client stopped sending after Configure")
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxAuditEntries caps how many entries the audit API returns
+const maxAuditEntries = 1000
+
+// auditReadChunk is how much of the audit log is read at a time, from the end
+const auditReadChunk = 64 * 1024
+
+// AuditEntry records one privileged call
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`           // "anonymous" without authentication
+	Role     string    `json:"role,omitempty"` // Role of the caller
+	Auth     string    `json:"auth,omitempty"` // "token", "basic", "session", "oidc" or "certificate"
+	Method   string    `json:"method"`
+	Path     string    `json:"path"`
+	Query    string    `json:"query,omitempty"`
+	Status   int       `json:"status"`
+	RemoteIP string    `json:"remote_ip"`
+}
+
+// AuditLog appends audit entries to a JSON lines file
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewAuditLog creates an audit log writing to the given file
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+// Record appends an entry to the log
+func (l *AuditLog) Record(e AuditEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Entries returns the most recent entries, newest first. The log only grows,
+// so it is read backwards from the end until limit entries are found.
+func (l *AuditLog) Entries(limit int) ([]AuditEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries := []AuditEntry{}
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// partial holds the start of the line that continues into the chunk read before
+	var partial []byte
+	offset := info.Size()
+	for offset > 0 && len(entries) < limit {
+		n := int64(auditReadChunk)
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		chunk := make([]byte, n, n+int64(len(partial)))
+		if _, err := f.ReadAt(chunk, offset); err != nil {
+			return nil, err
+		}
+		chunk = append(chunk, partial...)
+
+		// Every line after the first newline is complete; the first may continue further back
+		lines := bytes.Split(chunk, []byte{'\n'})
+		partial = lines[0]
+		for k := len(lines) - 1; k > 0 && len(entries) < limit; k-- {
+			entries = appendAuditEntry(entries, lines[k])
+		}
+	}
+	if offset == 0 && len(entries) < limit {
+		entries = appendAuditEntry(entries, partial)
+	}
+	return entries, nil
+}
+
+// appendAuditEntry appends the entry encoded in line, skipping blank or corrupt lines
+func appendAuditEntry(entries []AuditEntry, line []byte) []AuditEntry {
+	var e AuditEntry
+	if len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &e) == nil {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Audit records every call that needs more than the viewer role, including
+// denied ones. Install it before Middleware so rejected calls are seen too.
+func Audit(l *AuditLog) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if routePath := RoutePath(c); publicPaths[routePath] || RequiredRole(c.Method(), routePath) == RoleViewer {
+			return c.Next()
+		}
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+		entry := AuditEntry{
+			Time:     time.Now(),
+			User:     "anonymous",
+			Method:   c.Method(),
+			Path:     c.Path(),
+			Query:    string(c.Request().URI().QueryString()),
+			Status:   status,
+			RemoteIP: c.IP(),
+		}
+		if identity, ok := IdentityFrom(c); ok {
+			entry.User = identity.Name
+			entry.Role = identity.Role.String()
+			entry.Auth = identity.Method
+		}
+		if recordErr := l.Record(entry); recordErr != nil {
+			log.Printf("Failed to write audit log: %v", recordErr)
+		}
+		return err
+	}
+}
+
+// RegisterAuditHandlers exposes the audit trail
+func RegisterAuditHandlers(app *fiber.App, l *AuditLog) {
+	// Most recent privileged calls, newest first (?limit=100)
+	app.Get("/api/v1/audit", func(c *fiber.Ctx) error {
+		limit, err := strconv.Atoi(c.Query("limit", "100"))
+		if err != nil || limit <= 0 || limit > maxAuditEntries {
+			limit = maxAuditEntries
+		}
+		entries, err := l.Entries(limit)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"entries": entries,
+		})
+	})
+}
@@ -0,0 +1,233 @@
+// Package auth authenticates dashboard, REST and WebSocket requests with static API
+// tokens, HTTP basic auth against bcrypt-hashed users, and browser session cookies
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SessionCookie is the name of the browser session cookie
+const SessionCookie = "gpu_pro_session"
+
+// basicCacheTTL is how long verified basic auth credentials skip the bcrypt check
+const basicCacheTTL = time.Minute
+
+// ErrInvalidCredentials is returned when a login fails
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	Name   string `json:"name"`
+	Method string `json:"method"` // "token", "basic", "session", "oidc" or "certificate"
+	Role   Role   `json:"role"`
+}
+
+type session struct {
+	user    string
+	method  string // "session" for password logins, "oidc" for single sign-on
+	role    Role   // Role granted at login; 0 looks it up by user
+	expires time.Time
+}
+
+// Authenticator verifies credentials and keeps browser sessions in memory
+type Authenticator struct {
+	tokens      map[string]string // API token -> token name
+	users       map[string][]byte // user -> bcrypt hash
+	sessionTTL  time.Duration
+	sessions    map[string]*session
+	basicCache  map[string]time.Time // sha256(user:password) -> expiry
+	roles       map[string]Role      // user or token name -> role
+	defaultRole Role
+	oidc        *OIDCProvider
+	now         func() time.Time
+	mu          sync.Mutex
+}
+
+// NewAuthenticator creates an authenticator from API tokens ("name:token" or a bare token)
+// and an htpasswd-style users file with bcrypt hashes ("user:$2y$..." per line)
+func NewAuthenticator(tokens []string, usersFile string, sessionTTL time.Duration) (*Authenticator, error) {
+	a := &Authenticator{
+		tokens:      make(map[string]string),
+		users:       make(map[string][]byte),
+		sessionTTL:  sessionTTL,
+		sessions:    make(map[string]*session),
+		basicCache:  make(map[string]time.Time),
+		roles:       make(map[string]Role),
+		defaultRole: RoleViewer,
+		now:         time.Now,
+	}
+
+	for i, entry := range tokens {
+		name, token, ok := strings.Cut(entry, ":")
+		if !ok {
+			name, token = fmt.Sprintf("token-%d", i+1), entry
+		}
+		if token == "" {
+			return nil, fmt.Errorf("empty API token %q", name)
+		}
+		a.tokens[token] = name
+	}
+
+	if usersFile != "" {
+		if err := a.loadUsers(usersFile); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// loadUsers reads bcrypt-hashed users from an htpasswd-style file
+func (a *Authenticator) loadUsers(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(text, ":")
+		if !ok || user == "" {
+			return fmt.Errorf("%s:%d: expected user:bcrypt-hash", path, line)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("%s:%d: user %s: %v (only bcrypt hashes are supported)", path, line, user, err)
+		}
+		a.users[user] = []byte(hash)
+	}
+	return scanner.Err()
+}
+
+// Enabled reports whether any credentials are configured
+func (a *Authenticator) Enabled() bool {
+	return len(a.tokens) > 0 || len(a.users) > 0 || a.oidc != nil
+}
+
+// HasUsers reports whether password logins are configured
+func (a *Authenticator) HasUsers() bool {
+	return len(a.users) > 0
+}
+
+// CheckToken returns the name of a valid API token
+func (a *Authenticator) CheckToken(token string) (string, bool) {
+	name := ""
+	found := false
+	// Compare against every token so timing does not reveal which prefix matched
+	for t, n := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			name, found = n, true
+		}
+	}
+	return name, found
+}
+
+// CheckPassword verifies a user's password
+func (a *Authenticator) CheckPassword(user, password string) bool {
+	sum := sha256.Sum256([]byte(user + ":" + password))
+	key := hex.EncodeToString(sum[:])
+
+	a.mu.Lock()
+	expires, cached := a.basicCache[key]
+	a.mu.Unlock()
+	if cached && a.now().Before(expires) {
+		return true
+	}
+
+	hash, ok := a.users[user]
+	if !ok || bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return false
+	}
+
+	a.mu.Lock()
+	a.basicCache[key] = a.now().Add(basicCacheTTL)
+	a.mu.Unlock()
+	return true
+}
+
+// Login verifies a password and starts a session, returning its ID
+func (a *Authenticator) Login(user, password string) (string, error) {
+	if !a.CheckPassword(user, password) {
+		return "", ErrInvalidCredentials
+	}
+	return a.startSession(session{user: user, method: "session"})
+}
+
+// startSession stores a new session and returns its ID
+func (a *Authenticator) startSession(s session) (string, error) {
+	id, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	for sid, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, sid)
+		}
+	}
+	for k, expires := range a.basicCache {
+		if now.After(expires) {
+			delete(a.basicCache, k)
+		}
+	}
+	s.expires = now.Add(a.sessionTTL)
+	a.sessions[id] = &s
+	return id, nil
+}
+
+// Logout ends a session
+func (a *Authenticator) Logout(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, id)
+}
+
+// CheckSession returns the user of a live session
+func (a *Authenticator) CheckSession(id string) (string, bool) {
+	s, ok := a.session(id)
+	return s.user, ok
+}
+
+// session returns a live session
+func (a *Authenticator) session(id string) (session, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.sessions[id]
+	if !ok {
+		return session{}, false
+	}
+	if a.now().After(s.expires) {
+		delete(a.sessions, id)
+		return session{}, false
+	}
+	return *s, true
+}
+
+// randomString returns 32 random bytes, base64url encoded
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
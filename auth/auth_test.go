@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestAuthenticator returns an authenticator with one token ("ci") and one user (alice/secret)
+func newTestAuthenticator(t *testing.T) *Authenticator {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usersFile := filepath.Join(t.TempDir(), "users")
+	if err := os.WriteFile(usersFile, []byte("# GPU Pro users\nalice:"+string(hash)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewAuthenticator([]string{"ci:s3cr3t-token"}, usersFile, time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+	return a
+}
+
+func TestCredentialsAndSessions(t *testing.T) {
+	a := newTestAuthenticator(t)
+
+	if name, ok := a.CheckToken("s3cr3t-token"); !ok || name != "ci" {
+		t.Errorf("CheckToken = %q, %v", name, ok)
+	}
+	if _, ok := a.CheckToken("s3cr3t"); ok {
+		t.Error("CheckToken accepted a token prefix")
+	}
+	if !a.CheckPassword("alice", "secret") || a.CheckPassword("alice", "wrong") || a.CheckPassword("bob", "secret") {
+		t.Error("CheckPassword verified the wrong credentials")
+	}
+
+	if _, err := a.Login("alice", "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("Login with a wrong password error = %v", err)
+	}
+	id, err := a.Login("alice", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if user, ok := a.CheckSession(id); !ok || user != "alice" {
+		t.Errorf("CheckSession = %q, %v", user, ok)
+	}
+
+	// Sessions expire after their TTL
+	now := time.Now()
+	a.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if _, ok := a.CheckSession(id); ok {
+		t.Error("expired session still valid")
+	}
+
+	a.now = time.Now
+	id, _ = a.Login("alice", "secret")
+	a.Logout(id)
+	if _, ok := a.CheckSession(id); ok {
+		t.Error("session valid after logout")
+	}
+
+	// Plain-text and other non-bcrypt hashes are rejected at startup
+	bad := filepath.Join(t.TempDir(), "users")
+	os.WriteFile(bad, []byte("bob:{SHA}abc\n"), 0600)
+	if _, err := NewAuthenticator(nil, bad, time.Hour); err == nil {
+		t.Error("NewAuthenticator accepted a non-bcrypt hash")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	a := newTestAuthenticator(t)
+	app := fiber.New()
+	app.Use(Middleware(a))
+	RegisterHandlers(app, a, []byte("<form></form>"))
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("dashboard") })
+	app.Get("/socket.io/", func(c *fiber.Ctx) error { return c.SendString("upgrade") })
+
+	do := func(req *http.Request) *http.Response {
+		t.Helper()
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Unauthenticated API calls and WebSocket upgrades are rejected, browsers are sent to the login page
+	if resp := do(httptest.NewRequest("GET", "/api/v1/auth/me", nil)); resp.StatusCode != 401 || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Errorf("unauthenticated API status = %d", resp.StatusCode)
+	}
+	upgrade := httptest.NewRequest("GET", "/socket.io/", nil)
+	upgrade.Header.Set("Connection", "Upgrade")
+	upgrade.Header.Set("Upgrade", "websocket")
+	if resp := do(upgrade); resp.StatusCode != 401 {
+		t.Errorf("unauthenticated WebSocket upgrade status = %d", resp.StatusCode)
+	}
+	page := httptest.NewRequest("GET", "/", nil)
+	page.Header.Set("Accept", "text/html")
+	if resp := do(page); resp.StatusCode != 302 || resp.Header.Get("Location") != "/login" {
+		t.Errorf("browser status = %d, location = %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if resp := do(httptest.NewRequest("GET", "/login", nil)); resp.StatusCode != 200 {
+		t.Errorf("login page status = %d", resp.StatusCode)
+	}
+
+	// Bearer token and basic auth
+	req := httptest.NewRequest("GET", "/socket.io/", nil)
+	req.Header.Set("Authorization", "Bearer s3cr3t-token")
+	if resp := do(req); resp.StatusCode != 200 {
+		t.Errorf("token status = %d", resp.StatusCode)
+	}
+	req = httptest.NewRequest("GET", "/api/v1/auth/me", nil)
+	req.SetBasicAuth("alice", "secret")
+	if resp := do(req); resp.StatusCode != 200 {
+		t.Errorf("basic auth status = %d", resp.StatusCode)
+	}
+	req = httptest.NewRequest("GET", "/api/v1/auth/me", nil)
+	req.SetBasicAuth("alice", "wrong")
+	if resp := do(req); resp.StatusCode != 401 {
+		t.Errorf("wrong basic auth status = %d", resp.StatusCode)
+	}
+
+	// Form login sets a session cookie that authenticates later requests
+	login := httptest.NewRequest("POST", "/login", strings.NewReader("username=alice&password=secret"))
+	login.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := do(login)
+	if resp.StatusCode != 303 || resp.Header.Get("Location") != "/" {
+		t.Fatalf("login status = %d, location = %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == SessionCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("login cookie = %+v", cookie)
+	}
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	if resp := do(req); resp.StatusCode != 200 {
+		t.Errorf("session status = %d", resp.StatusCode)
+	}
+
+	login = httptest.NewRequest("POST", "/login", strings.NewReader(`{"username":"alice","password":"nope"}`))
+	login.Header.Set("Content-Type", "application/json")
+	if resp := do(login); resp.StatusCode != 401 {
+		t.Errorf("JSON login with a wrong password status = %d", resp.StatusCode)
+	}
+}
+
+func TestRolesAndAudit(t *testing.T) {
+	a := newTestAuthenticator(t)
+	if err := a.SetRoles(map[string]string{"alice": "operator", "ci": "admin"}, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetRoles(map[string]string{"bob": "root"}, "viewer"); err == nil {
+		t.Error("SetRoles accepted an unknown role")
+	}
+	a.users["bob"] = a.users["alice"] // bob/secret, a viewer
+
+	auditLog := NewAuditLog(filepath.Join(t.TempDir(), "gpu-audit.log"))
+	app := fiber.New()
+	app.Use(Audit(auditLog))
+	app.Use(Middleware(a))
+	RegisterAuditHandlers(app, auditLog)
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/socket.io/", ok)
+	app.Post("/api/alert-thresholds", ok)
+	app.Get("/api/largest-files", ok)
+	app.Delete("/api/v1/nodes/:node", ok)
+
+	tests := []struct {
+		user, method, path string
+		want               int
+	}{
+		{"bob", "GET", "/socket.io/", 200},
+		{"bob", "POST", "/api/alert-thresholds", 403},
+		{"alice", "POST", "/api/alert-thresholds", 200},
+		{"alice", "GET", "/api/largest-files", 403},
+		{"alice", "DELETE", "/api/v1/nodes/gpu01", 403},
+		{"", "DELETE", "/api/v1/nodes/gpu01", 200}, // admin token
+		{"bob", "GET", "/api/v1/audit", 403},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.user == "" {
+			req.Header.Set("Authorization", "Bearer s3cr3t-token")
+		} else {
+			req.SetBasicAuth(tt.user, "secret")
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s as %q = %d, want %d", tt.method, tt.path, tt.user, resp.StatusCode, tt.want)
+		}
+	}
+
+	// Reads by viewers are not audited; everything else is, including denied calls
+	entries, err := auditLog.Entries(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("audit entries = %+v, want 6", entries)
+	}
+	if e := entries[len(entries)-1]; e.User != "bob" || e.Method != "POST" || e.Status != 403 {
+		t.Errorf("oldest entry = %+v", e)
+	}
+	if e := entries[1]; e.User != "ci" || e.Role != "admin" || e.Auth != "token" || e.Path != "/api/v1/nodes/gpu01" || e.Status != 200 {
+		t.Errorf("node removal entry = %+v", e)
+	}
+}
+
+func TestMixedCasePaths(t *testing.T) {
+	a := newTestAuthenticator(t)
+	if err := a.SetRoles(map[string]string{"alice": "operator"}, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Routes match case-insensitively and with a trailing slash, so the checks must too
+	auditLog := NewAuditLog(filepath.Join(t.TempDir(), "gpu-audit.log"))
+	app := fiber.New()
+	app.Use(Audit(auditLog))
+	app.Use(Middleware(a))
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/api/largest-files", ok)
+	app.Post("/api/v1/nodes", ok)
+
+	for _, tt := range []struct{ method, path string }{
+		{"GET", "/API/Largest-Files"},
+		{"GET", "/api/largest-files/"},
+		{"POST", "/API/V1/NODES"},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.SetBasicAuth("alice", "secret")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 403 {
+			t.Errorf("%s %s as operator = %d, want 403", tt.method, tt.path, resp.StatusCode)
+		}
+	}
+
+	entries, err := auditLog.Entries(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Path != "/API/V1/NODES" {
+		t.Errorf("audit entries = %+v, want all 3 calls as sent", entries)
+	}
+}
+
+func TestCertificateIdentity(t *testing.T) {
+	a := newTestAuthenticator(t)
+	if err := a.SetRoles(map[string]string{"ci": "admin", "cert:hub": "operator"}, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	verified := func(cn string) *tls.ConnectionState {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+
+	// A certificate named like a token does not get the token's role
+	id, ok := certificateIdentity(verified("ci"))
+	if !ok || id.Name != "cert:ci" || a.roleOf(id.Name) != RoleViewer {
+		t.Errorf("certificate ci = %+v (role %s), want cert:ci with the default role", id, a.roleOf(id.Name))
+	}
+	if id, _ := certificateIdentity(verified("hub")); a.roleOf(id.Name) != RoleOperator {
+		t.Errorf("certificate hub role = %s, want operator from cert:hub", a.roleOf(id.Name))
+	}
+	if _, ok := certificateIdentity(&tls.ConnectionState{}); ok {
+		t.Error("unverified connection identified")
+	}
+}
+
+func TestAuditEntriesReadFromEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gpu-audit.log")
+	auditLog := NewAuditLog(path)
+
+	// Enough entries to span several read chunks
+	const total = 1500
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		if err := auditLog.Record(AuditEntry{Time: start.Add(time.Duration(i) * time.Second), User: "alice", Method: "POST", Path: "/api/v1/reservations", Status: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := auditLog.Entries(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 || entries[0].Status != total-1 || entries[4].Status != total-5 {
+		t.Errorf("latest entries = %+v, want the 5 newest first", entries)
+	}
+
+	entries, err = auditLog.Entries(total + 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != total {
+		t.Fatalf("got %d entries, want all %d", len(entries), total)
+	}
+	for i, e := range entries {
+		if e.Status != total-1-i {
+			t.Fatalf("entries[%d].Status = %d, want %d", i, e.Status, total-1-i)
+		}
+	}
+}
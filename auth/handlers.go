@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// identityKey is the fiber.Ctx locals key holding the request's Identity
+const identityKey = "identity"
+
+// rejectedKey is the fiber.Ctx locals key set when a request's token or password was wrong
+const rejectedKey = "credentials_rejected"
+
+// publicPaths are reachable without credentials. Agents authenticate with AGENT_TOKEN on their own.
+var publicPaths = map[string]bool{
+	"/login":                 true,
+	"/logout":                true,
+	"/favicon.ico":           true,
+	"/api/v1/agents/connect": true,
+	"/auth/oidc/login":       true,
+	"/auth/oidc/callback":    true,
+}
+
+// RoutePath returns the request path the way routes match it: case-insensitively and
+// ignoring a trailing slash. Access checks use it so that "/API/Largest-Files" is
+// treated like "/api/largest-files", the route it reaches.
+func RoutePath(c *fiber.Ctx) string {
+	return strings.ToLower(path.Clean(c.Path()))
+}
+
+// IdentityFrom returns the authenticated caller of a request
+func IdentityFrom(c *fiber.Ctx) (Identity, bool) {
+	id, ok := c.Locals(identityKey).(Identity)
+	return id, ok
+}
+
+// PresentsCredentials reports whether a request carries an API token or password to
+// check: an Authorization header, or a password login under any spelling of its route
+func PresentsCredentials(c *fiber.Ctx) bool {
+	return c.Get(fiber.HeaderAuthorization) != "" || (c.Method() == fiber.MethodPost && RoutePath(c) == "/login")
+}
+
+// CredentialsRejected reports whether the API token or password of a handled request was wrong
+func CredentialsRejected(c *fiber.Ctx) bool {
+	rejected, _ := c.Locals(rejectedKey).(bool)
+	return rejected
+}
+
+// authenticate checks, in order, a bearer token, basic auth credentials, the session cookie
+// and a verified client certificate
+func (a *Authenticator) authenticate(c *fiber.Ctx) (Identity, bool) {
+	header := c.Get(fiber.HeaderAuthorization)
+	if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+		if name, ok := a.CheckToken(strings.TrimSpace(token)); ok {
+			return Identity{Name: name, Method: "token"}, true
+		}
+		return Identity{}, false
+	}
+	if encoded, ok := strings.CutPrefix(header, "Basic "); ok {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return Identity{}, false
+		}
+		user, password, _ := strings.Cut(string(decoded), ":")
+		if a.CheckPassword(user, password) {
+			return Identity{Name: user, Method: "basic"}, true
+		}
+		return Identity{}, false
+	}
+	if id := c.Cookies(SessionCookie); id != "" {
+		if s, ok := a.session(id); ok {
+			return Identity{Name: s.user, Method: s.method, Role: s.role}, true
+		}
+	}
+	return certificateIdentity(c.Context().TLSConnectionState())
+}
+
+// CertificatePrefix starts the names of callers identified by a client certificate, so a
+// certificate's common name never stands for a user or token of the same name
+const CertificatePrefix = "cert:"
+
+// certificateIdentity identifies the holder of a client certificate verified against
+// TLS_CLIENT_CA_FILE as "cert:<common name>"
+func certificateIdentity(state *tls.ConnectionState) (Identity, bool) {
+	if state != nil && len(state.VerifiedChains) > 0 {
+		if name := state.VerifiedChains[0][0].Subject.CommonName; name != "" {
+			return Identity{Name: CertificatePrefix + name, Method: "certificate"}, true
+		}
+	}
+	return Identity{}, false
+}
+
+// Middleware rejects unauthenticated requests, including WebSocket upgrades, and
+// requests the caller's role does not allow. Browsers asking for a page are sent
+// to the login form instead.
+func Middleware(a *Authenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if publicPaths[RoutePath(c)] {
+			return c.Next()
+		}
+
+		if identity, ok := a.authenticate(c); ok {
+			if identity.Role == 0 {
+				identity.Role = a.roleOf(identity.Name)
+			}
+			c.Locals(identityKey, identity)
+			if required := RequiredRole(c.Method(), RoutePath(c)); identity.Role < required {
+				return c.Status(403).JSON(fiber.Map{
+					"error": fmt.Sprintf("%s role required", required),
+				})
+			}
+			return c.Next()
+		}
+
+		if c.Get(fiber.HeaderAuthorization) != "" {
+			c.Locals(rejectedKey, true)
+		}
+		if c.Method() == fiber.MethodGet && (a.HasUsers() || a.oidc != nil) && strings.Contains(c.Get(fiber.HeaderAccept), "text/html") {
+			return c.Redirect("/login")
+		}
+		if a.HasUsers() {
+			c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="GPU Pro"`)
+		}
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Authentication required",
+		})
+	}
+}
+
+// RegisterHandlers serves the login page, password and single sign-on logins with a
+// session cookie, logout, and the caller's identity. loginPage is an html/template
+// receiving .Password and .SSO, the login methods available.
+func RegisterHandlers(app *fiber.App, a *Authenticator, loginPage []byte) error {
+	tmpl, err := template.New("login").Parse(string(loginPage))
+	if err != nil {
+		return err
+	}
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, map[string]bool{"Password": a.HasUsers(), "SSO": a.oidc != nil}); err != nil {
+		return err
+	}
+
+	app.Get("/login", func(c *fiber.Ctx) error {
+		c.Set("Content-Type", "text/html")
+		return c.Send(page.Bytes())
+	})
+
+	if a.oidc != nil {
+		registerOIDCHandlers(app, a)
+	}
+
+	// Log in with a form post or {"username": ..., "password": ...}
+	app.Post("/login", func(c *fiber.Ctx) error {
+		var req struct {
+			Username string `json:"username" form:"username"`
+			Password string `json:"password" form:"password"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+		isForm := !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON)
+
+		id, err := a.Login(req.Username, req.Password)
+		if err != nil {
+			if errors.Is(err, ErrInvalidCredentials) {
+				c.Locals(rejectedKey, true)
+			}
+			if isForm {
+				return c.Redirect("/login?error=1", fiber.StatusSeeOther)
+			}
+			return c.Status(401).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Cookie(&fiber.Cookie{
+			Name:     SessionCookie,
+			Value:    id,
+			Path:     "/",
+			Expires:  time.Now().Add(a.sessionTTL),
+			HTTPOnly: true,
+			Secure:   c.Protocol() == "https",
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		if isForm {
+			return c.Redirect("/", fiber.StatusSeeOther)
+		}
+		return c.JSON(fiber.Map{
+			"user": req.Username,
+		})
+	})
+
+	logout := func(c *fiber.Ctx) error {
+		if id := c.Cookies(SessionCookie); id != "" {
+			a.Logout(id)
+		}
+		c.ClearCookie(SessionCookie)
+		return c.Redirect("/login", fiber.StatusSeeOther)
+	}
+	app.Get("/logout", logout)
+	app.Post("/logout", logout)
+
+	// The authenticated caller
+	app.Get("/api/v1/auth/me", func(c *fiber.Ctx) error {
+		identity, _ := IdentityFrom(c)
+		return c.JSON(identity)
+	})
+	return nil
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+)
+
+// oidcStateCookie binds a login in progress to the browser that started it
+const oidcStateCookie = "gpu_pro_oidc_state"
+
+// oidcLoginTimeout is how long a user has to complete a login at the identity provider
+const oidcLoginTimeout = 10 * time.Minute
+
+// OIDCConfig configures single sign-on with an OpenID Connect identity provider
+type OIDCConfig struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string            // .../auth/oidc/callback as registered with the provider
+	Scopes        []string          // Requested in addition to "openid"
+	UsernameClaim string            // Claim naming the user (default "sub"; "email" must be verified)
+	GroupsClaim   string            // Claim listing the user's groups
+	GroupRoles    map[string]string // Group -> role
+}
+
+// pendingLogin is a login started at the identity provider but not yet completed
+type pendingLogin struct {
+	verifier string // PKCE code verifier
+	nonce    string
+	expires  time.Time
+}
+
+// OIDCProvider logs users in with the authorization code flow and PKCE
+type OIDCProvider struct {
+	oauth2        oauth2.Config
+	verifier      *oidc.IDTokenVerifier
+	usernameClaim string
+	groupsClaim   string
+	groupRoles    map[string]Role
+	pending       map[string]*pendingLogin // state -> login
+	mu            sync.Mutex
+}
+
+// NewOIDCProvider discovers the identity provider's endpoints and keys
+func NewOIDCProvider(ctx context.Context, cfg OIDCConfig) (*OIDCProvider, error) {
+	groupRoles := make(map[string]Role, len(cfg.GroupRoles))
+	for group, roleName := range cfg.GroupRoles {
+		role, err := ParseRole(roleName)
+		if err != nil {
+			return nil, fmt.Errorf("role of group %s: %w", group, err)
+		}
+		groupRoles[group] = role
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("discover OIDC provider %s: %w", cfg.IssuerURL, err)
+	}
+
+	p := &OIDCProvider{
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, cfg.Scopes...),
+		},
+		verifier:      provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		usernameClaim: cfg.UsernameClaim,
+		groupsClaim:   cfg.GroupsClaim,
+		groupRoles:    groupRoles,
+		pending:       make(map[string]*pendingLogin),
+	}
+	if p.usernameClaim == "" {
+		p.usernameClaim = "sub"
+	}
+	if p.groupsClaim == "" {
+		p.groupsClaim = "groups"
+	}
+	return p, nil
+}
+
+// SetOIDC enables single sign-on through an identity provider
+func (a *Authenticator) SetOIDC(p *OIDCProvider) {
+	a.oidc = p
+}
+
+// start begins a login, returning the state and the identity provider URL to redirect to
+func (p *OIDCProvider) start() (string, string, error) {
+	state, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	p.mu.Lock()
+	now := time.Now()
+	for s, login := range p.pending {
+		if now.After(login.expires) {
+			delete(p.pending, s)
+		}
+	}
+	p.pending[state] = &pendingLogin{verifier: verifier, nonce: nonce, expires: now.Add(oidcLoginTimeout)}
+	p.mu.Unlock()
+
+	url := p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	return state, url, nil
+}
+
+// finish completes a login with the authorization code, returning the user and the role
+// granted by their groups (0 if none of them is mapped)
+func (p *OIDCProvider) finish(ctx context.Context, state, code string) (string, Role, error) {
+	p.mu.Lock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	p.mu.Unlock()
+	if !ok || time.Now().After(login.expires) {
+		return "", 0, errors.New("unknown or expired login")
+	}
+
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(login.verifier))
+	if err != nil {
+		return "", 0, fmt.Errorf("exchange code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return "", 0, errors.New("no id_token in token response")
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", 0, fmt.Errorf("verify id_token: %w", err)
+	}
+	if idToken.Nonce != login.nonce {
+		return "", 0, errors.New("id_token nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return "", 0, err
+	}
+
+	// Names users can change themselves, like preferred_username, could impersonate others
+	user, _ := claims[p.usernameClaim].(string)
+	if p.usernameClaim == "email" {
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return "", 0, errors.New("email address is not verified")
+		}
+	}
+	if user == "" {
+		return "", 0, fmt.Errorf("id_token has no %s claim", p.usernameClaim)
+	}
+	return OIDCPrefix + user, p.groupRole(claims[p.groupsClaim]), nil
+}
+
+// OIDCPrefix starts the names of single sign-on users, so an identity provider
+// account never stands for a local user or token of the same name
+const OIDCPrefix = "oidc:"
+
+// groupRole returns the highest role mapped from a groups claim (a list or a single string)
+func (p *OIDCProvider) groupRole(claim interface{}) Role {
+	var groups []string
+	switch v := claim.(type) {
+	case string:
+		groups = []string{v}
+	case []interface{}:
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	var best Role
+	for _, g := range groups {
+		if role := p.groupRoles[g]; role > best {
+			best = role
+		}
+	}
+	return best
+}
+
+// registerOIDCHandlers serves the redirect to the identity provider and its callback
+func registerOIDCHandlers(app *fiber.App, a *Authenticator) {
+	app.Get("/auth/oidc/login", func(c *fiber.Ctx) error {
+		state, url, err := a.oidc.start()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Cookie(&fiber.Cookie{
+			Name:     oidcStateCookie,
+			Value:    state,
+			Path:     "/auth/oidc",
+			Expires:  time.Now().Add(oidcLoginTimeout),
+			HTTPOnly: true,
+			Secure:   c.Protocol() == "https",
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		return c.Redirect(url)
+	})
+
+	app.Get("/auth/oidc/callback", func(c *fiber.Ctx) error {
+		state := c.Query("state")
+		c.Cookie(&fiber.Cookie{Name: oidcStateCookie, Path: "/auth/oidc", Expires: time.Unix(0, 0), HTTPOnly: true})
+		if msg := c.Query("error"); msg != "" {
+			log.Printf("OIDC login failed at the identity provider: %s %s", msg, c.Query("error_description"))
+			return c.Redirect("/login?error=sso", fiber.StatusSeeOther)
+		}
+		if state == "" || c.Cookies(oidcStateCookie) != state {
+			log.Printf("OIDC login rejected: state does not match this browser")
+			return c.Redirect("/login?error=sso", fiber.StatusSeeOther)
+		}
+
+		user, groupRole, err := a.oidc.finish(c.UserContext(), state, c.Query("code"))
+		if err != nil {
+			log.Printf("OIDC login failed: %v", err)
+			return c.Redirect("/login?error=sso", fiber.StatusSeeOther)
+		}
+
+		// Roles assigned by name in AUTH_ROLES take precedence over group roles
+		role := groupRole
+		if assigned, ok := a.assignedRole(user); ok {
+			role = assigned
+		}
+		id, err := a.startSession(session{user: user, method: "oidc", role: role})
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		c.Cookie(&fiber.Cookie{
+			Name:     SessionCookie,
+			Value:    id,
+			Path:     "/",
+			Expires:  time.Now().Add(a.sessionTTL),
+			HTTPOnly: true,
+			Secure:   c.Protocol() == "https",
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		return c.Redirect("/", fiber.StatusSeeOther)
+	})
+}
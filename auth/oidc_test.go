@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// mockOIDC is a minimal OpenID Connect provider. Codes are issued directly by the
+// test instead of through a login form at /authorize.
+type mockOIDC struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newMockOIDC(t *testing.T) *mockOIDC {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockOIDC{key: key, codes: make(map[string]mockGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                m.URL,
+			"authorization_endpoint":                m.URL + "/authorize",
+			"token_endpoint":                        m.URL + "/token",
+			"jwks_uri":                              m.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "test",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		m.mu.Lock()
+		grant, ok := m.codes[r.Form.Get("code")]
+		delete(m.codes, r.Form.Get("code"))
+		m.mu.Unlock()
+
+		// PKCE: the verifier must hash to the challenge sent to /authorize
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     m.sign(t, grant.claims),
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	return m
+}
+
+// authorize simulates a user signing in at the provider and returns the callback query
+func (m *mockOIDC) authorize(t *testing.T, authURL string, claims map[string]interface{}) url.Values {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("authorization request without PKCE: %s", authURL)
+	}
+
+	claims["iss"] = m.URL
+	claims["aud"] = q.Get("client_id")
+	claims["nonce"] = q.Get("nonce")
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+
+	code := "code-" + q.Get("state")[:8]
+	m.mu.Lock()
+	m.codes[code] = mockGrant{challenge: q.Get("code_challenge"), claims: claims}
+	m.mu.Unlock()
+	return url.Values{"code": {code}, "state": {q.Get("state")}}
+}
+
+// sign returns an RS256 JWT
+func (m *mockOIDC) sign(t *testing.T, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newMockOIDC(t)
+	defer idp.Close()
+
+	provider, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		IssuerURL:   idp.URL,
+		ClientID:    "gpu-pro",
+		RedirectURL: "http://gpu-pro.example.com/auth/oidc/callback",
+		GroupRoles:  map[string]string{"ml-admins": "admin", "ml-team": "operator"},
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+	a, err := NewAuthenticator(nil, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetOIDC(provider)
+	if err := a.SetRoles(map[string]string{"oidc:u3": "viewer", "root": "admin"}, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(Middleware(a))
+	if err := RegisterHandlers(app, a, []byte(`{{if .SSO}}sso{{end}}{{if .Password}}password{{end}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// login signs a user in through the provider and returns the session cookie
+	login := func(claims map[string]interface{}, tamper func(url.Values)) *http.Cookie {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", "/auth/oidc/login", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var stateCookie *http.Cookie
+		for _, c := range resp.Cookies() {
+			if c.Name == oidcStateCookie {
+				stateCookie = c
+			}
+		}
+		if resp.StatusCode != 302 || stateCookie == nil {
+			t.Fatalf("login start status = %d, state cookie = %v", resp.StatusCode, stateCookie)
+		}
+
+		query := idp.authorize(t, resp.Header.Get("Location"), claims)
+		if tamper != nil {
+			tamper(query)
+		}
+		req := httptest.NewRequest("GET", "/auth/oidc/callback?"+query.Encode(), nil)
+		req.AddCookie(stateCookie)
+		resp, err = app.Test(req, 5000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range resp.Cookies() {
+			if c.Name == SessionCookie && c.Value != "" {
+				return c
+			}
+		}
+		if resp.Header.Get("Location") != "/login?error=sso" {
+			t.Errorf("failed login location = %q", resp.Header.Get("Location"))
+		}
+		return nil
+	}
+	me := func(cookie *http.Cookie) Identity {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/v1/auth/me", nil)
+		req.AddCookie(cookie)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var raw struct {
+			Name, Method, Role string
+		}
+		json.NewDecoder(resp.Body).Decode(&raw)
+		role, _ := ParseRole(raw.Role)
+		return Identity{Name: raw.Name, Method: raw.Method, Role: role}
+	}
+
+	cookie := login(map[string]interface{}{"sub": "u1", "preferred_username": "alice", "groups": []string{"ml-team", "ml-admins"}}, nil)
+	if cookie == nil {
+		t.Fatal("OIDC login failed")
+	}
+	if id := me(cookie); id.Name != "oidc:u1" || id.Method != "oidc" || id.Role != RoleAdmin {
+		t.Errorf("alice = %+v, want oidc:u1, admin via oidc", id)
+	}
+
+	// Without a mapped group the default role applies; AUTH_ROLES overrides groups
+	if id := me(login(map[string]interface{}{"sub": "u2", "email": "bob@example.com"}, nil)); id.Name != "oidc:u2" || id.Role != RoleViewer {
+		t.Errorf("bob = %+v, want viewer", id)
+	}
+	// A user naming themselves after a local user gets nothing of theirs
+	if id := me(login(map[string]interface{}{"sub": "u6", "preferred_username": "root"}, nil)); id.Name != "oidc:u6" || id.Role != RoleViewer {
+		t.Errorf("self-named root = %+v, want viewer oidc:u6", id)
+	}
+	if id := me(login(map[string]interface{}{"sub": "u3", "preferred_username": "carol", "groups": "ml-admins"}, nil)); id.Role != RoleViewer {
+		t.Errorf("carol = %+v, want viewer from AUTH_ROLES", id)
+	}
+
+	// Email addresses name users only once the provider verified them
+	provider.usernameClaim = "email"
+	if c := login(map[string]interface{}{"sub": "u7", "email": "dave@example.com"}, nil); c != nil {
+		t.Error("login with an unverified email succeeded")
+	}
+	if id := me(login(map[string]interface{}{"sub": "u7", "email": "dave@example.com", "email_verified": true}, nil)); id.Name != "oidc:dave@example.com" {
+		t.Errorf("verified email user = %+v, want oidc:dave@example.com", id)
+	}
+	provider.usernameClaim = "sub"
+
+	// A forged state or an unknown code are rejected
+	if c := login(map[string]interface{}{"sub": "u4"}, func(q url.Values) { q.Set("state", "forged") }); c != nil {
+		t.Error("login with a forged state succeeded")
+	}
+	if c := login(map[string]interface{}{"sub": "u5"}, func(q url.Values) { q.Set("code", "unknown") }); c != nil {
+		t.Error("login with an unknown code succeeded")
+	}
+
+	// The login page offers single sign-on only
+	resp, err := app.Test(httptest.NewRequest("GET", "/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := io.ReadAll(resp.Body); string(page) != "sso" {
+		t.Errorf("login page = %q, want sso only", page)
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role grants access to a class of operations. Higher roles include the lower ones.
+type Role int
+
+const (
+	RoleViewer   Role = iota + 1 // Dashboard, live stream and read-only API
+	RoleOperator                 // Alert thresholds, silences, processes and reservations
+	RoleAdmin                    // Filesystem scans, hub node registry and the audit trail
+)
+
+var roleNames = map[Role]string{
+	RoleViewer:   "viewer",
+	RoleOperator: "operator",
+	RoleAdmin:    "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "none"
+}
+
+// MarshalText encodes a role by name
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, error) {
+	for role, n := range roleNames {
+		if strings.EqualFold(strings.TrimSpace(name), n) {
+			return role, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %q (expected viewer, operator or admin)", name)
+}
+
+// adminPaths need the admin role for every method
+var adminPaths = []string{
+	"/api/largest-files",
+	"/api/home-directory",
+	"/api/v1/audit",
+	"/api/v1/files",
+}
+
+// RequiredRole returns the role needed for a request. Reads are open to viewers,
+// changes need an operator, and filesystem browsing, the audit trail and changes
+// to the hub's node registry need an admin.
+// path must be normalized with RoutePath.
+func RequiredRole(method, path string) Role {
+	for _, prefix := range adminPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return RoleAdmin
+		}
+	}
+
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return RoleViewer
+	}
+	if path == "/api/v1/nodes" || strings.HasPrefix(path, "/api/v1/nodes/") {
+		return RoleAdmin
+	}
+	return RoleOperator
+}
+
+// SetRoles assigns roles to users and API token names ("alice" -> "admin").
+// Everyone else gets defaultRole.
+func (a *Authenticator) SetRoles(roles map[string]string, defaultRole string) error {
+	parsed := make(map[string]Role, len(roles))
+	for name, roleName := range roles {
+		role, err := ParseRole(roleName)
+		if err != nil {
+			return fmt.Errorf("role of %s: %w", name, err)
+		}
+		parsed[name] = role
+	}
+	def, err := ParseRole(defaultRole)
+	if err != nil {
+		return fmt.Errorf("default role: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.roles = parsed
+	a.defaultRole = def
+	return nil
+}
+
+// roleOf returns the role of a user or API token name
+func (a *Authenticator) roleOf(name string) Role {
+	if role, ok := a.assignedRole(name); ok {
+		return role
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.defaultRole
+}
+
+// assignedRole returns the role explicitly assigned to a name
+func (a *Authenticator) assignedRole(name string) (Role, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	role, ok := a.roles[name]
+	return role, ok
+}
@@ -181,6 +181,12 @@ type dataMsg struct {
 // Initialize the model
 func initialModel() model {
 	cfg := config.Load()
+	analytics.Configure(analytics.Settings{
+		Enabled:  cfg.AnalyticsEnabled,
+		Endpoint: cfg.AnalyticsEndpoint,
+		DryRun:   cfg.AnalyticsDryRun,
+		Interval: time.Duration(cfg.AnalyticsInterval * float64(time.Second)),
+	})
 
 	// Initialize GPU monitor
 	mon := monitor.NewGPUMonitor()
@@ -1510,6 +1516,8 @@ func main() {
 		case "--debug-mfu":
 			debugMFU()
 			return
+		case "--reservations":
+			os.Exit(runReservations(os.Args[2:]))
 		case "--help":
 			printHelp()
 			return
@@ -1561,7 +1569,8 @@ func configureThresholds() {
 // Debug MFU calculation
 func debugMFU() {
 	fmt.Println("MFU Debug Information")
-	fmt.Println("====================\n")
+	fmt.Println("====================")
+	fmt.Println()
 
 	mon := monitor.NewGPUMonitor()
 	if mon == nil {
@@ -1626,6 +1635,7 @@ func printHelp() {
 	fmt.Println("  gpu-pro-cli --view-alerts      View alert history")
 	fmt.Println("  gpu-pro-cli --config-thresholds View current threshold configuration")
 	fmt.Println("  gpu-pro-cli --debug-mfu        Show MFU debug information")
+	fmt.Println("  gpu-pro-cli --reservations     Manage GPU reservations (--reservations help)")
 	fmt.Println("  gpu-pro-cli --help             Show this help")
 	fmt.Println("\nInteractive Mode Controls:")
 	fmt.Println("  q, Ctrl+C    Quit")
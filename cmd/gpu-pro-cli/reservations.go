@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gpu-pro/config"
+)
+
+// reservation mirrors a lease returned by the reservations API
+type reservation struct {
+	ID    string    `json:"id"`
+	User  string    `json:"user"`
+	Node  string    `json:"node"`
+	GPUs  []string  `json:"gpus"`
+	Note  string    `json:"note"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// reservationViolation mirrors a violation returned by the reservations API
+type reservationViolation struct {
+	Node        string    `json:"node"`
+	GPUID       string    `json:"gpu_id"`
+	PID         string    `json:"pid"`
+	ProcessName string    `json:"process_name"`
+	User        string    `json:"user"`
+	ReservedBy  string    `json:"reserved_by"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// defaultServerURL is the GPU Pro server the reservation commands talk to
+func defaultServerURL() string {
+	if url := os.Getenv("GPU_PRO_URL"); url != "" {
+		return url
+	}
+	return fmt.Sprintf("http://localhost:%d", config.Load().Port)
+}
+
+// runReservations implements "gpu-pro-cli --reservations <command>" and returns the exit code
+func runReservations(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "--help" {
+		printReservationsHelp()
+		return 0
+	}
+
+	fs := flag.NewFlagSet("reservations "+args[0], flag.ContinueOnError)
+	server := fs.String("url", defaultServerURL(), "GPU Pro server (node or hub)")
+	gpus := fs.String("gpus", "", "comma-separated GPU UUIDs")
+	node := fs.String("node", "", "node the GPUs belong to (required on a hub)")
+	owner := fs.String("user", currentUser(), "user the GPUs are reserved for")
+	duration := fs.Duration("duration", 4*time.Hour, "reservation length")
+	by := fs.Duration("by", time.Hour, "extension length")
+	note := fs.String("note", "", "note shown with the reservation")
+
+	// Accept the reservation ID before or after the flags
+	rest := args[1:]
+	id := ""
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		id, rest = rest[0], rest[1:]
+	}
+	if err := fs.Parse(rest); err != nil {
+		return 2
+	}
+	if id == "" && fs.NArg() == 1 {
+		id = fs.Arg(0)
+	}
+	base := strings.TrimRight(*server, "/")
+
+	var err error
+	switch args[0] {
+	case "list":
+		err = listReservations(base)
+	case "add":
+		err = reservationRequest("POST", base+"/api/v1/reservations", map[string]interface{}{
+			"user":     *owner,
+			"node":     *node,
+			"gpus":     strings.Split(*gpus, ","),
+			"note":     *note,
+			"duration": duration.String(),
+		})
+	case "extend", "release":
+		if id == "" {
+			fmt.Fprintf(os.Stderr, "Usage: gpu-pro-cli --reservations %s <id>\n", args[0])
+			return 2
+		}
+		if args[0] == "extend" {
+			err = reservationRequest("PATCH", base+"/api/v1/reservations/"+id, map[string]interface{}{
+				"extend_by": by.String(),
+			})
+		} else {
+			err = reservationRequest("DELETE", base+"/api/v1/reservations/"+id, nil)
+		}
+	default:
+		printReservationsHelp()
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func listReservations(base string) error {
+	var result struct {
+		Reservations []reservation          `json:"reservations"`
+		Violations   []reservationViolation `json:"violations"`
+	}
+	req, err := http.NewRequest("GET", base+"/api/v1/reservations", nil)
+	if err != nil {
+		return err
+	}
+	setAuthHeader(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	if len(result.Reservations) == 0 {
+		fmt.Println("No reservations")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSER\tNODE\tGPUS\tFROM\tUNTIL\tNOTE")
+		for _, r := range result.Reservations {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.ID, r.User, r.Node, strings.Join(r.GPUs, ","),
+				r.Start.Local().Format("2006-01-02 15:04"), r.End.Local().Format("2006-01-02 15:04"), r.Note)
+		}
+		w.Flush()
+	}
+
+	if len(result.Violations) > 0 {
+		fmt.Println("\n⚠️  Processes on GPUs reserved by someone else:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NODE\tGPU\tPID\tPROCESS\tUSER\tRESERVED BY\tSINCE")
+		for _, v := range result.Violations {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				v.Node, v.GPUID, v.PID, v.ProcessName, v.User, v.ReservedBy, v.DetectedAt.Local().Format("15:04:05"))
+		}
+		w.Flush()
+	}
+	return nil
+}
+
+// reservationRequest sends a JSON request and prints the resulting reservation
+func reservationRequest(method, url string, body map[string]interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setAuthHeader(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	if method == "DELETE" {
+		fmt.Println("Reservation released")
+		return nil
+	}
+	var r reservation
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return err
+	}
+	fmt.Printf("Reservation %s: %s on %s for %s until %s\n",
+		r.ID, strings.Join(r.GPUs, ","), r.Node, r.User, r.End.Local().Format("2006-01-02 15:04"))
+	return nil
+}
+
+// setAuthHeader authenticates a request with the API token in GPU_PRO_TOKEN, if set
+func setAuthHeader(req *http.Request) {
+	if token := os.Getenv("GPU_PRO_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// checkResponse turns API error responses into errors
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiErr); err == nil && apiErr.Error != "" {
+		return fmt.Errorf("%s", apiErr.Error)
+	}
+	return fmt.Errorf("server returned %s", resp.Status)
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func printReservationsHelp() {
+	fmt.Println("Usage: gpu-pro-cli --reservations <command> [flags]")
+	fmt.Println("\nCommands:")
+	fmt.Println("  list                                   List reservations and conflicting processes")
+	fmt.Println("  add --gpus UUID[,UUID] [--node N] [--duration 4h] [--note TEXT] [--user U]")
+	fmt.Println("                                         Reserve GPUs")
+	fmt.Println("  extend <id> [--by 1h]                  Extend a reservation")
+	fmt.Println("  release <id>                           Release a reservation")
+	fmt.Println("\nFlags:")
+	fmt.Println("  --url URL    GPU Pro server, node or hub (default: $GPU_PRO_URL or http://localhost:$PORT)")
+	fmt.Println("\nSet GPU_PRO_TOKEN to an API token when the server requires authentication.")
+}
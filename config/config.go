@@ -21,39 +21,234 @@ type Config struct {
 	NvidiaSMI bool // Force nvidia-smi mode
 
 	// Multi-Node Configuration
-	Mode     string   // "default" (single node) or "hub" (aggregate multiple nodes)
-	NodeName string   // Node identifier
-	NodeURLs []string // Comma-separated URLs for hub mode
+	Mode           string            // "default" (single node) or "hub" (aggregate multiple nodes)
+	NodeName       string            // Node identifier
+	NodeLabels     map[string]string // Labels advertised to the hub (rack, datacenter, team, ...)
+	NodeURLs       []string          // Comma-separated URLs for hub mode
+	NodesFile      string            // Hub node registry, seeded from NodeURLs and managed via /api/v1/nodes
+	HubURL         string            // Hub to push samples to (agent mode, node side)
+	AgentToken     string            // Shared token authenticating push-mode agents with the hub
+	StaleIntervals int               // Online nodes without data for this many update intervals are reported stale
+
+	// Hub Node Discovery
+	DiscoverySRV      []string // DNS SRV names listing nodes
+	DiscoveryHosts    []string // DNS names (host or host:port) resolving to one node per address
+	DiscoveryFile     string   // Prometheus file_sd style targets file (JSON or YAML), watched for changes
+	DiscoveryScheme   string   // Scheme for discovered targets without one
+	DiscoveryInterval float64  // Discovery refresh interval (seconds)
+
+	// Kubernetes Attribution
+	PodResources       bool   // Map GPUs to pods via the kubelet PodResources API
+	PodResourcesSocket string // Path to the kubelet PodResources socket (empty for the kubelet default)
+
+	// Usage Accounting
+	AccountingEnabled   bool    // Record per-user GPU usage in the background
+	SampleInterval      float64 // Background sampling interval for accounting (seconds)
+	AccountingFile      string  // File the accounting ledger is persisted to
+	AccountingRetention float64 // Days of hourly usage records kept (0 keeps everything)
+
+	// Energy Tracking
+	EnergyTracking      bool    // Track GPU energy consumption in the background
+	EnergyFile          string  // File energy totals are persisted to
+	ElectricityPrice    float64 // Price per kWh used for cost estimates
+	ElectricityCurrency string  // Currency of ElectricityPrice
+	CarbonIntensity     float64 // Grid carbon intensity (gCO2 per kWh) used for emission estimates
+
+	// GPU Reservations
+	ReservationsEnabled bool   // Advisory GPU leases with warnings for processes of other users
+	ReservationsFile    string // File leases are persisted to
+
+	// Authentication
+	AuthTokens    []string // Static API tokens ("name:token" or a bare token)
+	AuthUsersFile string   // htpasswd-style file with bcrypt-hashed users for basic auth and the login form
+	SessionTTL    float64  // Lifetime of browser login sessions (hours)
+	NodeToken     string   // Bearer token the hub sends when connecting to nodes and child hubs
+
+	// Access Control
+	AuthRoles       map[string]string // User or token name -> role (viewer, operator, admin)
+	AuthDefaultRole string            // Role of authenticated callers not listed in AuthRoles
+	AuditLog        string            // File privileged calls are recorded to
+
+	// TLS
+	TLSCertFile     string // Server certificate; enables HTTPS, reloaded when rotated
+	TLSKeyFile      string // Server private key
+	TLSClientCAFile string // CA that client certificates must be signed by (mutual TLS)
+	TLSClientAuth   string // "require" or "optional" client certificates when TLSClientCAFile is set
+	TLSPeerCertFile string // Client certificate presented to nodes (hub) or to the hub (agent)
+	TLSPeerKeyFile  string // Private key of TLSPeerCertFile
+	TLSPeerCAFile   string // CA verifying nodes (hub) or the hub (agent) instead of the system roots
+
+	// OIDC Single Sign-On
+	OIDCIssuerURL     string            // OpenID Connect issuer; enables "Sign in with SSO"
+	OIDCClientID      string            // Client registered with the identity provider
+	OIDCClientSecret  string            // Client secret (empty for public clients, which rely on PKCE)
+	OIDCRedirectURL   string            // Callback URL registered with the provider (.../auth/oidc/callback)
+	OIDCScopes        []string          // Scopes requested in addition to "openid"
+	OIDCUsernameClaim string            // ID token claim naming the user
+	OIDCGroupsClaim   string            // ID token claim listing the user's groups
+	OIDCGroupRoles    map[string]string // Group -> role (viewer, operator, admin)
+
+	// Filesystem Scans
+	FileScanRoots    []string // Directories /api/largest-files may scan (default: home directory)
+	FileScanMaxDepth int      // Directory levels a scan descends
+	FileScanMaxFiles int      // Files a scan examines before stopping
+	FileScanTimeout  float64  // Wall time a scan may take (seconds)
+	FileScanCacheTTL float64  // How long scan results are reused (seconds)
+
+	// Filesystem Monitoring
+	FSExcludeTypes     []string // Filesystem types to skip in addition to pseudo filesystems
+	FSExcludeMounts    []string // Mount point globs to skip
+	FSPredictionWindow float64  // History the fill rate is computed over (seconds)
+
+	// Connection Geolocation
+	GeoIPProvider    string // auto, mmdb, ip-api or none
+	GeoIPDatabase    string // MaxMind-format City database (GeoLite2-City, DB-IP City Lite)
+	GeoIPASNDatabase string // Optional ASN database for the network operator (GeoLite2-ASN)
+	GeoIPURL         string // Endpoint of the ip-api provider (default: the free ip-api.com endpoint)
+
+	// Analytics Heartbeat
+	AnalyticsEnabled  bool    // Send the anonymous usage heartbeat
+	AnalyticsEndpoint string  // Collector URL (default: the public GPU Pro collector)
+	AnalyticsDryRun   bool    // Log the heartbeat instead of sending it
+	AnalyticsInterval float64 // Seconds between heartbeats
+
+	// Rate Limiting
+	RateLimit        int // Requests per minute from each client to other endpoints (0 for no limit)
+	RateLimitScans   int // Filesystem scans each client may start per minute; cached results are free
+	RateLimitHistory int // Requests per minute to alert history, accounting, energy and audit queries
+	RateLimitWrites  int // Requests per minute saving alert thresholds
+	RateLimitAuth    int // Wrong API tokens and passwords per minute from each IP address
+	BodyLimit        int // Largest request body accepted (bytes)
+	WSMaxClients     int // Concurrent dashboard WebSocket clients (0 for no limit)
+
+	// Reverse Proxy
+	ProxyHeader    string   // Header holding the client address set by a reverse proxy, e.g. X-Real-IP
+	TrustedProxies []string // Proxy addresses or CIDR ranges allowed to set ProxyHeader (empty: any)
 }
 
 // Default configuration values
 var (
-	DefaultHost              = "0.0.0.0"
-	DefaultPort              = 8889
-	DefaultUpdateInterval    = 0.5 // 500ms
-	DefaultNvidiaSMIInterval = 2.0 // 2s
+	DefaultHost                = "0.0.0.0"
+	DefaultPort                = 8889
+	DefaultUpdateInterval      = 0.5  // 500ms
+	DefaultNvidiaSMIInterval   = 2.0  // 2s
+	DefaultSampleInterval      = 15.0 // 15s
+	DefaultAccountingFile      = "gpu-accounting.json"
+	DefaultAccountingRetention = 400.0 // Days, a little over a year
+	DefaultNodesFile           = "hub-nodes.json"
+	DefaultDiscoveryInterval   = 30.0 // 30s
+	DefaultStaleIntervals      = 20
+	DefaultEnergyFile          = "gpu-energy.json"
+	DefaultReservationsFile    = "gpu-reservations.json"
+	DefaultSessionTTL          = 12.0 // 12h
+	DefaultAuditLog            = "gpu-audit.log"
+	DefaultFileScanMaxDepth    = 32
+	DefaultFileScanMaxFiles    = 1000000
+	DefaultFileScanTimeout     = 120.0  // 2m
+	DefaultFileScanCacheTTL    = 300.0  // 5m
+	DefaultFSPredictionWindow  = 3600.0 // 1h
+	DefaultAnalyticsInterval   = 300.0  // 5m
+	DefaultRateLimit           = 600    // 10/s
+	DefaultRateLimitScans      = 20
+	DefaultRateLimitHistory    = 60
+	DefaultRateLimitWrites     = 20
+	DefaultRateLimitAuth       = 10
+	DefaultBodyLimit           = 1 << 20 // 1 MB
+	DefaultWSMaxClients        = 100
 )
 
 // Load reads configuration from environment variables
 func Load() *Config {
 	cfg := &Config{
-		Host:              getEnv("HOST", DefaultHost),
-		Port:              getEnvInt("PORT", DefaultPort),
-		Debug:             getEnvBool("DEBUG", false),
-		UpdateInterval:    getEnvFloat("UPDATE_INTERVAL", DefaultUpdateInterval),
-		NvidiaSMIInterval: getEnvFloat("NVIDIA_SMI_INTERVAL", DefaultNvidiaSMIInterval),
-		NvidiaSMI:         getEnvBool("NVIDIA_SMI", false),
-		Mode:              getEnv("GPU_HOT_MODE", "default"),
-		NodeName:          getEnv("NODE_NAME", getHostname()),
+		Host:                getEnv("HOST", DefaultHost),
+		Port:                getEnvInt("PORT", DefaultPort),
+		Debug:               getEnvBool("DEBUG", false),
+		UpdateInterval:      getEnvFloat("UPDATE_INTERVAL", DefaultUpdateInterval),
+		NvidiaSMIInterval:   getEnvFloat("NVIDIA_SMI_INTERVAL", DefaultNvidiaSMIInterval),
+		NvidiaSMI:           getEnvBool("NVIDIA_SMI", false),
+		Mode:                getEnv("GPU_HOT_MODE", "default"),
+		NodeName:            getEnv("NODE_NAME", getHostname()),
+		NodeLabels:          getEnvMap("NODE_LABELS"),
+		NodesFile:           getEnv("HUB_NODES_FILE", DefaultNodesFile),
+		HubURL:              getEnv("HUB_URL", ""),
+		AgentToken:          getEnv("AGENT_TOKEN", ""),
+		StaleIntervals:      getEnvInt("HUB_STALE_INTERVALS", DefaultStaleIntervals),
+		DiscoverySRV:        getEnvList("DISCOVERY_DNS_SRV"),
+		DiscoveryHosts:      getEnvList("DISCOVERY_DNS_A"),
+		DiscoveryFile:       getEnv("DISCOVERY_FILE", ""),
+		DiscoveryScheme:     getEnv("DISCOVERY_SCHEME", "http"),
+		DiscoveryInterval:   getEnvFloat("DISCOVERY_INTERVAL", DefaultDiscoveryInterval),
+		PodResources:        getEnvBool("K8S_POD_RESOURCES", false),
+		PodResourcesSocket:  getEnv("KUBELET_POD_RESOURCES_SOCKET", ""),
+		AccountingEnabled:   getEnvBool("ACCOUNTING_ENABLED", false),
+		SampleInterval:      getEnvFloat("SAMPLE_INTERVAL", DefaultSampleInterval),
+		AccountingFile:      getEnv("ACCOUNTING_FILE", DefaultAccountingFile),
+		AccountingRetention: getEnvFloat("ACCOUNTING_RETENTION", DefaultAccountingRetention),
+		EnergyTracking:      getEnvBool("ENERGY_TRACKING", false),
+		EnergyFile:          getEnv("ENERGY_FILE", DefaultEnergyFile),
+		ElectricityPrice:    getEnvFloat("ELECTRICITY_PRICE", 0),
+		ElectricityCurrency: getEnv("ELECTRICITY_CURRENCY", "USD"),
+		CarbonIntensity:     getEnvFloat("CARBON_INTENSITY", 0),
+		ReservationsEnabled: getEnvBool("RESERVATIONS_ENABLED", false),
+		ReservationsFile:    getEnv("RESERVATIONS_FILE", DefaultReservationsFile),
+		AuthTokens:          getEnvList("AUTH_TOKENS"),
+		AuthUsersFile:       getEnv("AUTH_USERS_FILE", ""),
+		SessionTTL:          getEnvFloat("AUTH_SESSION_TTL", DefaultSessionTTL),
+		NodeToken:           getEnv("HUB_NODE_TOKEN", ""),
+		AuthRoles:           getEnvMap("AUTH_ROLES"),
+		AuthDefaultRole:     getEnv("AUTH_DEFAULT_ROLE", "viewer"),
+		AuditLog:            getEnv("AUDIT_LOG", DefaultAuditLog),
+		TLSCertFile:         getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:          getEnv("TLS_KEY_FILE", ""),
+		TLSClientCAFile:     getEnv("TLS_CLIENT_CA_FILE", ""),
+		TLSClientAuth:       getEnv("TLS_CLIENT_AUTH", "require"),
+		TLSPeerCertFile:     getEnv("TLS_PEER_CERT_FILE", ""),
+		TLSPeerKeyFile:      getEnv("TLS_PEER_KEY_FILE", ""),
+		TLSPeerCAFile:       getEnv("TLS_PEER_CA_FILE", ""),
+		OIDCIssuerURL:       getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:        getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:    getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:     getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:          getEnvList("OIDC_SCOPES"),
+		OIDCUsernameClaim:   getEnv("OIDC_USERNAME_CLAIM", "sub"),
+		OIDCGroupsClaim:     getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCGroupRoles:      getEnvMap("OIDC_GROUP_ROLES"),
+		FileScanRoots:       getEnvList("FILE_SCAN_ROOTS"),
+		FileScanMaxDepth:    getEnvInt("FILE_SCAN_MAX_DEPTH", DefaultFileScanMaxDepth),
+		FileScanMaxFiles:    getEnvInt("FILE_SCAN_MAX_FILES", DefaultFileScanMaxFiles),
+		FileScanTimeout:     getEnvFloat("FILE_SCAN_TIMEOUT", DefaultFileScanTimeout),
+		FileScanCacheTTL:    getEnvFloat("FILE_SCAN_CACHE_TTL", DefaultFileScanCacheTTL),
+		FSExcludeTypes:      getEnvList("FS_EXCLUDE_TYPES"),
+		FSExcludeMounts:     getEnvList("FS_EXCLUDE_MOUNTS"),
+		FSPredictionWindow:  getEnvFloat("FS_PREDICTION_WINDOW", DefaultFSPredictionWindow),
+		GeoIPProvider:       getEnv("GEOIP_PROVIDER", "auto"),
+		GeoIPDatabase:       getEnv("GEOIP_DATABASE", ""),
+		GeoIPASNDatabase:    getEnv("GEOIP_ASN_DATABASE", ""),
+		GeoIPURL:            getEnv("GEOIP_URL", ""),
+		AnalyticsEnabled:    getEnvBool("ANALYTICS_ENABLED", true),
+		AnalyticsEndpoint:   getEnv("ANALYTICS_ENDPOINT", ""),
+		AnalyticsDryRun:     getEnvBool("ANALYTICS_DRY_RUN", false),
+		AnalyticsInterval:   getEnvFloat("ANALYTICS_INTERVAL", DefaultAnalyticsInterval),
+		RateLimit:           getEnvInt("RATE_LIMIT", DefaultRateLimit),
+		RateLimitScans:      getEnvInt("RATE_LIMIT_SCANS", DefaultRateLimitScans),
+		RateLimitHistory:    getEnvInt("RATE_LIMIT_HISTORY", DefaultRateLimitHistory),
+		RateLimitWrites:     getEnvInt("RATE_LIMIT_WRITES", DefaultRateLimitWrites),
+		RateLimitAuth:       getEnvInt("RATE_LIMIT_AUTH_FAILURES", DefaultRateLimitAuth),
+		BodyLimit:           getEnvInt("HTTP_BODY_LIMIT", DefaultBodyLimit),
+		WSMaxClients:        getEnvInt("WS_MAX_CLIENTS", DefaultWSMaxClients),
+		ProxyHeader:         getEnv("PROXY_HEADER", ""),
+		TrustedProxies:      getEnvList("TRUSTED_PROXIES"),
 	}
 
 	// Parse NODE_URLS
-	if nodeURLsStr := os.Getenv("NODE_URLS"); nodeURLsStr != "" {
-		urls := strings.Split(nodeURLsStr, ",")
-		for _, url := range urls {
-			if trimmed := strings.TrimSpace(url); trimmed != "" {
-				cfg.NodeURLs = append(cfg.NodeURLs, trimmed)
-			}
+	cfg.NodeURLs = getEnvList("NODE_URLS")
+
+	if len(cfg.OIDCScopes) == 0 {
+		cfg.OIDCScopes = []string{"profile", "email"}
+	}
+	if len(cfg.FileScanRoots) == 0 {
+		if home, err := os.UserHomeDir(); err == nil {
+			cfg.FileScanRoots = []string{home}
 		}
 	}
 
@@ -92,6 +287,29 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// getEnvList parses a comma-separated list, skipping empty entries
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			list = append(list, trimmed)
+		}
+	}
+	return list
+}
+
+// getEnvMap parses comma-separated key=value pairs, skipping entries without a key
+func getEnvMap(key string) map[string]string {
+	m := make(map[string]string)
+	for _, item := range getEnvList(key) {
+		k, v, _ := strings.Cut(item, "=")
+		if k = strings.TrimSpace(k); k != "" {
+			m[k] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
 func getHostname() string {
 	hostname, err := os.Hostname()
 	if err != nil {
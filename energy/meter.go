@@ -0,0 +1,40 @@
+package energy
+
+import "time"
+
+// Meter turns GPU samples into the energy each GPU used during the sampling interval.
+// It prefers NVML's cumulative energy counter and integrates power draw when the counter
+// is unavailable. Every consumer of the same samples gets the same energy, so usage
+// accounting and energy tracking agree. A Meter is not safe for concurrent use.
+type Meter struct {
+	counters map[string]float64 // GPU UUID -> last energy counter reading (Wh)
+}
+
+// NewMeter creates a meter without counter baselines
+func NewMeter() *Meter {
+	return &Meter{counters: make(map[string]float64)}
+}
+
+// Delta returns the kWh a GPU used during the interval ending with this sample.
+// It must be called for every sample of a GPU to keep its counter baseline current.
+func (m *Meter) Delta(gpuID string, gpu map[string]interface{}, interval time.Duration) float64 {
+	uuid, _ := gpu["uuid"].(string)
+	if uuid == "" {
+		uuid = gpuID
+	}
+
+	if counterWh, ok := gpu["energy_consumption_wh"].(float64); ok {
+		last, hasCounter := m.counters[uuid]
+		m.counters[uuid] = counterWh
+		// The first reading only sets the baseline, and the counter resets when the driver
+		// reloads; skip those intervals rather than count garbage
+		if !hasCounter || counterWh < last {
+			return 0
+		}
+		return (counterWh - last) / 1000
+	}
+	if powerDraw, ok := gpu["power_draw"].(float64); ok {
+		return powerDraw * interval.Hours() / 1000
+	}
+	return 0
+}
@@ -0,0 +1,315 @@
+package energy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// processRetention is how long a finished process stays in the per-process breakdown
+const processRetention = 24 * time.Hour
+
+// Pricing converts energy into cost and carbon estimates
+type Pricing struct {
+	PricePerKWh     float64 `json:"price_per_kwh"`
+	Currency        string  `json:"currency"`
+	CarbonIntensity float64 `json:"carbon_intensity_g_per_kwh"`
+}
+
+// Cost returns the electricity cost of the given energy
+func (p Pricing) Cost(kwh float64) float64 {
+	return kwh * p.PricePerKWh
+}
+
+// CO2Kg returns the estimated emissions of the given energy in kg CO2
+func (p Pricing) CO2Kg(kwh float64) float64 {
+	return kwh * p.CarbonIntensity / 1000
+}
+
+// Consumption is the energy attributed to a GPU, process or user
+type Consumption struct {
+	Key   string  `json:"key"`
+	Name  string  `json:"name,omitempty"`
+	User  string  `json:"user,omitempty"`
+	KWh   float64 `json:"kwh"`
+	Cost  float64 `json:"cost"`
+	CO2Kg float64 `json:"co2_kg"`
+}
+
+type gpuState struct {
+	Name string  `json:"name"`
+	KWh  float64 `json:"kwh"`
+}
+
+type processState struct {
+	name     string
+	user     string
+	kwh      float64
+	lastSeen time.Time
+}
+
+// Tracker accumulates GPU energy per GPU, node, process and user, as measured by a Meter
+type Tracker struct {
+	path      string
+	pricing   Pricing
+	meter     *Meter
+	since     time.Time
+	gpus      map[string]*gpuState // GPU UUID -> state
+	users     map[string]float64   // user -> kWh
+	processes map[string]*processState
+	mu        sync.RWMutex
+}
+
+// persistedState is the on-disk form of the tracker totals
+type persistedState struct {
+	Since time.Time            `json:"since"`
+	GPUs  map[string]*gpuState `json:"gpus"`
+	Users map[string]float64   `json:"users"`
+}
+
+// NewTracker creates a tracker persisted to the given file, restoring previous totals
+func NewTracker(path string, pricing Pricing) *Tracker {
+	t := &Tracker{
+		path:      path,
+		pricing:   pricing,
+		meter:     NewMeter(),
+		since:     time.Now(),
+		gpus:      make(map[string]*gpuState),
+		users:     make(map[string]float64),
+		processes: make(map[string]*processState),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return t
+	}
+
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return t
+	}
+	if !state.Since.IsZero() {
+		t.since = state.Since
+	}
+	for uuid, gpu := range state.GPUs {
+		t.gpus[uuid] = gpu
+	}
+	for user, kwh := range state.Users {
+		t.users[user] = kwh
+	}
+	return t
+}
+
+// Record accounts one sampling interval of GPU energy
+func (t *Tracker) Record(gpus map[string]interface{}, processes []map[string]interface{}, interval time.Duration, at time.Time) {
+	byGPU := make(map[string][]map[string]interface{})
+	for _, proc := range processes {
+		if gpuID, ok := proc["gpu_id"].(string); ok {
+			byGPU[gpuID] = append(byGPU[gpuID], proc)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for gpuID, gpu := range gpus {
+		data, ok := gpu.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		uuid, _ := data["uuid"].(string)
+		if uuid == "" {
+			uuid = gpuID
+		}
+
+		state, exists := t.gpus[uuid]
+		if !exists {
+			state = &gpuState{}
+			t.gpus[uuid] = state
+		}
+		if name, ok := data["name"].(string); ok {
+			state.Name = name
+		}
+
+		deltaKWh := t.meter.Delta(gpuID, data, interval)
+		state.KWh += deltaKWh
+
+		t.attributeToProcesses(byGPU[gpuID], deltaKWh, at)
+	}
+
+	// Forget processes that finished long ago
+	for key, proc := range t.processes {
+		if at.Sub(proc.lastSeen) > processRetention {
+			delete(t.processes, key)
+		}
+	}
+}
+
+// attributeToProcesses splits a GPU's energy between its processes by used GPU memory
+func (t *Tracker) attributeToProcesses(procs []map[string]interface{}, kwh float64, at time.Time) {
+	if len(procs) == 0 {
+		return
+	}
+
+	totalMemory := 0.0
+	for _, proc := range procs {
+		if mem, ok := proc["memory"].(float64); ok {
+			totalMemory += mem
+		}
+	}
+
+	for _, proc := range procs {
+		share := 1.0 / float64(len(procs))
+		if mem, ok := proc["memory"].(float64); ok && totalMemory > 0 {
+			share = mem / totalMemory
+		}
+
+		pid, _ := proc["pid"].(string)
+		name, _ := proc["name"].(string)
+		user, _ := proc["user"].(string)
+		if user == "" {
+			user = "unknown"
+		}
+
+		key := pid + "|" + name
+		state, ok := t.processes[key]
+		if !ok {
+			state = &processState{name: name, user: user}
+			t.processes[key] = state
+		}
+		state.kwh += kwh * share
+		state.lastSeen = at
+
+		t.users[user] += kwh * share
+	}
+}
+
+// EnrichGPU adds tracked energy, cost and CO2 to a GPU entry
+func (t *Tracker) EnrichGPU(gpu map[string]interface{}) {
+	uuid, _ := gpu["uuid"].(string)
+
+	t.mu.RLock()
+	state, ok := t.gpus[uuid]
+	kwh := 0.0
+	if ok {
+		kwh = state.KWh
+	}
+	t.mu.RUnlock()
+
+	if !ok {
+		return
+	}
+	gpu["energy_kwh"] = kwh
+	gpu["energy_cost"] = t.pricing.Cost(kwh)
+	gpu["energy_co2_kg"] = t.pricing.CO2Kg(kwh)
+}
+
+// EnrichProcess adds tracked energy to a process entry
+func (t *Tracker) EnrichProcess(proc map[string]interface{}) {
+	pid, _ := proc["pid"].(string)
+	name, _ := proc["name"].(string)
+
+	t.mu.RLock()
+	state, ok := t.processes[pid+"|"+name]
+	kwh := 0.0
+	if ok {
+		kwh = state.kwh
+	}
+	t.mu.RUnlock()
+
+	if ok {
+		proc["energy_kwh"] = kwh
+	}
+}
+
+// Summary returns energy, cost and CO2 per node, GPU, user and process since tracking started
+func (t *Tracker) Summary() map[string]interface{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	nodeKWh := 0.0
+	gpus := make([]Consumption, 0, len(t.gpus))
+	for uuid, gpu := range t.gpus {
+		nodeKWh += gpu.KWh
+		gpus = append(gpus, t.consumption(uuid, gpu.Name, "", gpu.KWh))
+	}
+
+	users := make([]Consumption, 0, len(t.users))
+	for user, kwh := range t.users {
+		users = append(users, t.consumption(user, "", "", kwh))
+	}
+
+	processes := make([]Consumption, 0, len(t.processes))
+	for key, proc := range t.processes {
+		pid := key
+		if idx := len(key) - len(proc.name) - 1; idx >= 0 {
+			pid = key[:idx]
+		}
+		processes = append(processes, t.consumption(pid, proc.name, proc.user, proc.kwh))
+	}
+
+	sortByEnergy(gpus)
+	sortByEnergy(users)
+	sortByEnergy(processes)
+
+	return map[string]interface{}{
+		"since":     t.since.Format(time.RFC3339),
+		"pricing":   t.pricing,
+		"node":      t.consumption("node", "", "", nodeKWh),
+		"gpus":      gpus,
+		"users":     users,
+		"processes": processes,
+	}
+}
+
+func (t *Tracker) consumption(key, name, user string, kwh float64) Consumption {
+	return Consumption{
+		Key:   key,
+		Name:  name,
+		User:  user,
+		KWh:   kwh,
+		Cost:  t.pricing.Cost(kwh),
+		CO2Kg: t.pricing.CO2Kg(kwh),
+	}
+}
+
+func sortByEnergy(c []Consumption) {
+	sort.Slice(c, func(i, j int) bool {
+		if c[i].KWh != c[j].KWh {
+			return c[i].KWh > c[j].KWh
+		}
+		return c[i].Key < c[j].Key
+	})
+}
+
+// Save persists GPU and user totals
+func (t *Tracker) Save() error {
+	t.mu.RLock()
+	state := persistedState{
+		Since: t.since,
+		GPUs:  make(map[string]*gpuState, len(t.gpus)),
+		Users: make(map[string]float64, len(t.users)),
+	}
+	for uuid, gpu := range t.gpus {
+		copied := *gpu
+		state.GPUs[uuid] = &copied
+	}
+	for user, kwh := range t.users {
+		state.Users[user] = kwh
+	}
+	t.mu.RUnlock()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, t.path)
+}
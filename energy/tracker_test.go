@@ -0,0 +1,90 @@
+package energy
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRecordUsesEnergyCounter(t *testing.T) {
+	tracker := NewTracker(filepath.Join(t.TempDir(), "energy.json"), Pricing{PricePerKWh: 0.2, CarbonIntensity: 400})
+	now := time.Now()
+
+	sample := func(counterWh float64) map[string]interface{} {
+		return map[string]interface{}{
+			"0": map[string]interface{}{"uuid": "GPU-a", "name": "A100", "energy_consumption_wh": counterWh, "power_draw": 999.0},
+		}
+	}
+	processes := []map[string]interface{}{
+		{"pid": "1", "name": "train", "user": "alice", "gpu_id": "0", "memory": 3000.0},
+		{"pid": "2", "name": "eval", "user": "bob", "gpu_id": "0", "memory": 1000.0},
+	}
+
+	// The first reading only establishes the baseline
+	tracker.Record(sample(5000), processes, time.Minute, now)
+	tracker.Record(sample(7000), processes, time.Minute, now.Add(time.Minute))
+
+	summary := tracker.Summary()
+	node := summary["node"].(Consumption)
+	if !approxEqual(node.KWh, 2) {
+		t.Fatalf("node kWh = %v, want 2", node.KWh)
+	}
+	if !approxEqual(node.Cost, 0.4) || !approxEqual(node.CO2Kg, 0.8) {
+		t.Errorf("node cost/co2 = %v/%v, want 0.4/0.8", node.Cost, node.CO2Kg)
+	}
+
+	users := summary["users"].([]Consumption)
+	if len(users) != 2 || users[0].Key != "alice" || !approxEqual(users[0].KWh, 1.5) {
+		t.Errorf("users = %+v, want alice first with 1.5 kWh", users)
+	}
+
+	// Counter reset (driver reload) must not produce negative energy
+	tracker.Record(sample(10), processes, time.Minute, now.Add(2*time.Minute))
+	if node := tracker.Summary()["node"].(Consumption); !approxEqual(node.KWh, 2) {
+		t.Errorf("node kWh after counter reset = %v, want 2", node.KWh)
+	}
+}
+
+func TestRecordIntegratesPowerDraw(t *testing.T) {
+	tracker := NewTracker(filepath.Join(t.TempDir(), "energy.json"), Pricing{})
+	gpus := map[string]interface{}{
+		"0": map[string]interface{}{"uuid": "GPU-a", "power_draw": 300.0},
+	}
+
+	tracker.Record(gpus, nil, 2*time.Hour, time.Now())
+
+	gpu := map[string]interface{}{"uuid": "GPU-a"}
+	tracker.EnrichGPU(gpu)
+	if kwh, _ := gpu["energy_kwh"].(float64); !approxEqual(kwh, 0.6) {
+		t.Errorf("energy_kwh = %v, want 0.6", gpu["energy_kwh"])
+	}
+}
+
+func TestTrackerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "energy.json")
+	tracker := NewTracker(path, Pricing{})
+	gpus := map[string]interface{}{
+		"0": map[string]interface{}{"uuid": "GPU-a", "power_draw": 1000.0},
+	}
+	processes := []map[string]interface{}{
+		{"pid": "1", "name": "train", "user": "alice", "gpu_id": "0"},
+	}
+	tracker.Record(gpus, processes, time.Hour, time.Now())
+	if err := tracker.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded := NewTracker(path, Pricing{})
+	summary := loaded.Summary()
+	if node := summary["node"].(Consumption); !approxEqual(node.KWh, 1) {
+		t.Errorf("restored node kWh = %v, want 1", node.KWh)
+	}
+	if users := summary["users"].([]Consumption); len(users) != 1 || !approxEqual(users[0].KWh, 1) {
+		t.Errorf("restored users = %+v", users)
+	}
+}
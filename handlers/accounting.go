@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"time"
+
+	"gpu-pro/accounting"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// accountingSaveInterval is how often the accounting ledger is flushed to disk
+const accountingSaveInterval = 5 * time.Minute
+
+// RegisterAccountingHandlers feeds background samples into the ledger and exposes the accounting report
+func RegisterAccountingHandlers(app *fiber.App, sampler *Sampler, ledger *accounting.Ledger) {
+	lastSave := time.Now()
+	sampler.Add(func(gpus map[string]interface{}, processes []map[string]interface{}, interval time.Duration, at time.Time) {
+		ledger.Record(gpus, processes, interval, at)
+
+		if at.Sub(lastSave) >= accountingSaveInterval {
+			lastSave = at
+			if err := ledger.Save(); err != nil {
+				log.Printf("Failed to save accounting ledger: %v", err)
+			}
+		}
+	})
+
+	// API endpoint for the per-user/per-group usage report
+	// Query: from, to (YYYY-MM-DD or RFC3339, default: current month; a date includes the whole day),
+	// group_by (user|group), format (json|csv)
+	app.Get("/api/v1/accounting", func(c *fiber.Ctx) error {
+		now := time.Now()
+		from, err := parseReportTime(c.Query("from"), time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), false)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid 'from' date",
+			})
+		}
+		to, err := parseReportTime(c.Query("to"), now, true)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid 'to' date",
+			})
+		}
+
+		groupBy := c.Query("group_by", "user")
+		if groupBy != "user" && groupBy != "group" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "group_by must be 'user' or 'group'",
+			})
+		}
+
+		entries := ledger.Report(from, to, groupBy)
+
+		if c.Query("format") == "csv" {
+			var buf bytes.Buffer
+			if err := accounting.WriteCSV(&buf, groupBy, entries); err != nil {
+				return c.Status(500).JSON(fiber.Map{
+					"error": "Failed to generate CSV",
+				})
+			}
+			c.Set("Content-Type", "text/csv")
+			c.Set("Content-Disposition", "attachment; filename=gpu-accounting-"+from.Format("2006-01-02")+"-"+to.Add(-time.Second).Format("2006-01-02")+".csv")
+			return c.Send(buf.Bytes())
+		}
+
+		return c.JSON(fiber.Map{
+			"from":     from.Format(time.RFC3339),
+			"to":       to.Format(time.RFC3339),
+			"group_by": groupBy,
+			"entries":  entries,
+		})
+	})
+}
+
+// parseReportTime parses a date (YYYY-MM-DD, local time) or RFC3339 timestamp. The report
+// range is half-open, so a date ending it (end) stands for the start of the following day.
+func parseReportTime(value string, defaultValue time.Time, end bool) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
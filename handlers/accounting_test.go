@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseReportTime(t *testing.T) {
+	from, _ := parseReportTime("2026-10-31", time.Time{}, false)
+	to, _ := parseReportTime("2026-10-31", time.Time{}, true)
+	if want := time.Date(2026, 10, 31, 0, 0, 0, 0, time.Local); !from.Equal(want) {
+		t.Errorf("from = %v, want %v", from, want)
+	}
+	if want := time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local); !to.Equal(want) {
+		t.Errorf("to = %v, want the start of the next day %v", to, want)
+	}
+
+	// Timestamps are taken as given
+	to, err := parseReportTime("2026-10-31T12:00:00Z", time.Time{}, true)
+	if err != nil || !to.Equal(time.Date(2026, 10, 31, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("timestamp to = %v, %v", to, err)
+	}
+	if _, err := parseReportTime("31/10/2026", time.Time{}, true); err == nil {
+		t.Error("invalid date accepted")
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gpu-pro/config"
+	"gpu-pro/monitor"
+
+	gorillaws "github.com/gorilla/websocket"
+)
+
+// agentReconnectDelay is how long the agent waits before reconnecting to the hub
+const agentReconnectDelay = 5 * time.Second
+
+// Agent pushes this node's samples to a hub over an outbound WebSocket,
+// so nodes behind NAT or firewalls can join a hub without inbound ports
+type Agent struct {
+	hubURL    string
+	token     string
+	mon       *monitor.GPUMonitor
+	cfg       *config.Config
+	tlsConfig *tls.Config // Client certificate and CA for https:// hubs
+	instance  string      // Random ID that lets the hub tell this agent from others using the same name
+	stopChan  chan struct{}
+	isRunning bool
+}
+
+// NewAgent creates an agent streaming to the hub at cfg.HubURL
+func NewAgent(mon *monitor.GPUMonitor, cfg *config.Config) *Agent {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return &Agent{
+		hubURL:   cfg.HubURL,
+		token:    cfg.AgentToken,
+		mon:      mon,
+		cfg:      cfg,
+		instance: hex.EncodeToString(b),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// SetTLSConfig sets the TLS configuration used to connect to an https:// hub
+func (a *Agent) SetTLSConfig(cfg *tls.Config) {
+	a.tlsConfig = cfg
+}
+
+// Start connects to the hub in the background, reconnecting until stopped
+func (a *Agent) Start() {
+	if a.isRunning {
+		return
+	}
+	a.isRunning = true
+
+	go func() {
+		for {
+			if err := a.stream(); err != nil {
+				log.Printf("Hub agent connection to %s failed: %v", a.hubURL, err)
+			}
+
+			select {
+			case <-a.stopChan:
+				return
+			case <-time.After(agentReconnectDelay):
+			}
+		}
+	}()
+}
+
+// Stop disconnects from the hub
+func (a *Agent) Stop() {
+	if !a.isRunning {
+		return
+	}
+	a.isRunning = false
+	close(a.stopChan)
+}
+
+// stream sends samples over one hub connection until it fails or the agent is stopped
+func (a *Agent) stream() error {
+	endpoint, err := AgentEndpoint(a.hubURL, a.cfg.NodeName)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+a.token)
+	header.Set("X-Agent-Instance", a.instance)
+
+	dialer := *gorillaws.DefaultDialer
+	dialer.TLSClientConfig = a.tlsConfig
+	conn, resp, err := dialer.Dial(endpoint, header)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			return fmt.Errorf("hub rejected agent (HTTP %d), check AGENT_TOKEN", resp.StatusCode)
+		}
+		if resp != nil && resp.StatusCode == http.StatusBadRequest {
+			return fmt.Errorf("hub rejected node name %q (HTTP 400), set NODE_NAME to letters, digits, '.', '_' or '-'", a.cfg.NodeName)
+		}
+		if resp != nil && resp.StatusCode == http.StatusConflict {
+			return fmt.Errorf("another agent is connected to the hub as %q, set a unique NODE_NAME", a.cfg.NodeName)
+		}
+		return err
+	}
+	defer conn.Close()
+
+	log.Printf("Streaming samples to hub: %s", a.hubURL)
+
+	// The hub never sends data; reading only detects that it closed the connection
+	closed := make(chan error, 1)
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Duration(a.cfg.UpdateInterval * float64(time.Second)))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stopChan:
+			conn.WriteMessage(gorillaws.CloseMessage, gorillaws.FormatCloseMessage(gorillaws.CloseNormalClosure, ""))
+			return nil
+		case err := <-closed:
+			return err
+		case <-ticker.C:
+			data, err := json.Marshal(buildPayload(a.mon, a.cfg))
+			if err != nil {
+				log.Printf("Error marshaling agent payload: %v", err)
+				continue
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(gorillaws.TextMessage, data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// AgentEndpoint converts a hub base URL into its agent WebSocket endpoint
+func AgentEndpoint(hubURL, nodeName string) (string, error) {
+	u, err := url.Parse(strings.TrimRight(hubURL, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid HUB_URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "ws":
+		u.Scheme = "ws"
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("invalid HUB_URL %q: scheme must be http or https", hubURL)
+	}
+
+	u.Path += "/api/v1/agents/connect"
+	u.RawQuery = url.Values{"node": {nodeName}}.Encode()
+	return u.String(), nil
+}
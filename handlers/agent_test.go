@@ -0,0 +1,29 @@
+package handlers
+
+import "testing"
+
+func TestAgentEndpoint(t *testing.T) {
+	tests := []struct {
+		hubURL string
+		want   string
+	}{
+		{"http://hub:1312", "ws://hub:1312/api/v1/agents/connect?node=gpu+01"},
+		{"https://hub.example.com/", "wss://hub.example.com/api/v1/agents/connect?node=gpu+01"},
+		{"https://example.com/gpu-pro", "wss://example.com/gpu-pro/api/v1/agents/connect?node=gpu+01"},
+	}
+
+	for _, tt := range tests {
+		got, err := AgentEndpoint(tt.hubURL, "gpu 01")
+		if err != nil {
+			t.Errorf("AgentEndpoint(%q) error: %v", tt.hubURL, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("AgentEndpoint(%q) = %q, want %q", tt.hubURL, got, tt.want)
+		}
+	}
+
+	if _, err := AgentEndpoint("ftp://hub", "n"); err == nil {
+		t.Error("AgentEndpoint accepted an ftp:// URL")
+	}
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"gpu-pro/analytics"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterAnalyticsHandlers shows the analytics heartbeat settings and exactly what it sends
+func RegisterAnalyticsHandlers(app *fiber.App) {
+	app.Get("/api/v1/analytics", func(c *fiber.Ctx) error {
+		return c.JSON(analytics.CurrentStatus())
+	})
+}
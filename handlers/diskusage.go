@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ML artifact categories disk usage can be filtered by
+const (
+	ArtifactCheckpoints = "checkpoints"
+	ArtifactDatasets    = "datasets"
+	ArtifactHFCache     = "hf-cache"
+)
+
+// ArtifactCategories lists the categories in display order
+var ArtifactCategories = []string{ArtifactCheckpoints, ArtifactDatasets, ArtifactHFCache}
+
+var checkpointExts = map[string]bool{
+	".ckpt": true, ".pt": true, ".pth": true, ".safetensors": true, ".h5": true,
+	".keras": true, ".onnx": true, ".gguf": true, ".distcp": true,
+}
+
+var datasetExts = map[string]bool{
+	".parquet": true, ".arrow": true, ".tfrecord": true, ".tfrecords": true,
+	".npy": true, ".npz": true, ".mds": true, ".lmdb": true, ".webdataset": true,
+}
+
+// Default and maximum drill-down of a disk usage view
+const (
+	defaultUsageDepth = 1
+	maxUsageDepth     = 5
+	defaultUsageTop   = 20
+	maxUsageTop       = 500
+)
+
+// classifyArtifact returns the ML artifact category of a file, or "" if it has none.
+// Files in the Hugging Face cache count as cache even if they are checkpoints.
+func classifyArtifact(path string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	dirs := segments[:len(segments)-1]
+	for i, s := range dirs {
+		if s == "huggingface" && i > 0 && dirs[i-1] == ".cache" {
+			return ArtifactHFCache
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if checkpointExts[ext] {
+		return ArtifactCheckpoints
+	}
+	for _, s := range dirs {
+		if s == "checkpoints" || strings.HasPrefix(s, "checkpoint-") || strings.HasPrefix(s, "global_step") {
+			return ArtifactCheckpoints
+		}
+	}
+	if datasetExts[ext] {
+		return ArtifactDatasets
+	}
+	for _, s := range dirs {
+		if s == "datasets" || s == "dataset" {
+			return ArtifactDatasets
+		}
+	}
+	return ""
+}
+
+// UsageTotals sums the files below a directory
+type UsageTotals struct {
+	Size        int64 `json:"size"`         // Apparent size
+	ActualSize  int64 `json:"actual_size"`  // Actual disk usage
+	Files       int64 `json:"files"`        // Regular files
+	SparseFiles int64 `json:"sparse_files"` // Files using less disk than their apparent size
+}
+
+func (t *UsageTotals) add(size, actualSize int64, sparse bool) {
+	t.Size += size
+	t.ActualSize += actualSize
+	t.Files++
+	if sparse {
+		t.SparseFiles++
+	}
+}
+
+// DirUsage is the aggregated disk usage of a directory and its subdirectories
+type DirUsage struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+	UsageTotals
+	SizeHuman       string                  `json:"size_human"`
+	ActualSizeHuman string                  `json:"actual_size_human"`
+	Categories      map[string]*UsageTotals `json:"categories,omitempty"` // Totals per ML artifact category
+	Children        []*DirUsage             `json:"children,omitempty"`   // Largest first
+	OmittedChildren int                     `json:"omitted_children,omitempty"`
+
+	parent *DirUsage
+}
+
+// find returns the node of a directory below u, or nil if no file was found there
+func (u *DirUsage) find(path string) *DirUsage {
+	rel, err := filepath.Rel(u.Path, path)
+	if err != nil || !isWithin(u.Path, path) {
+		return nil
+	}
+	node := u
+	if rel == "." {
+		return node
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		var next *DirUsage
+		for _, child := range node.Children {
+			if child.Name == name {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// view copies the tree down to depth levels, keeping the top largest children per level.
+// With a category only that category's files are counted.
+func (u *DirUsage) view(depth, top int, category string) *DirUsage {
+	v := &DirUsage{
+		Path:        u.Path,
+		Name:        u.Name,
+		UsageTotals: u.UsageTotals,
+		Categories:  u.Categories,
+	}
+	if category != "" {
+		v.UsageTotals = UsageTotals{}
+		if t := u.Categories[category]; t != nil {
+			v.UsageTotals = *t
+		}
+		v.Categories = nil
+	}
+	v.SizeHuman = formatBytes(v.Size)
+	v.ActualSizeHuman = formatBytes(v.ActualSize)
+
+	if depth <= 0 {
+		v.OmittedChildren = len(u.Children)
+		return v
+	}
+	for _, child := range u.Children {
+		if category != "" && child.Categories[category] == nil {
+			continue
+		}
+		v.Children = append(v.Children, child.view(depth-1, top, category))
+	}
+	if category != "" {
+		sort.Slice(v.Children, func(i, j int) bool {
+			return v.Children[i].ActualSize > v.Children[j].ActualSize
+		})
+	}
+	if len(v.Children) > top {
+		v.OmittedChildren = len(v.Children) - top
+		v.Children = v.Children[:top]
+	}
+	return v
+}
+
+// sortChildren orders every level largest first
+func (u *DirUsage) sortChildren() {
+	sort.Slice(u.Children, func(i, j int) bool {
+		return u.Children[i].ActualSize > u.Children[j].ActualSize
+	})
+	for _, child := range u.Children {
+		child.sortChildren()
+	}
+}
+
+// scanDiskUsage aggregates the sizes of all files below dir into a directory tree,
+// like du. Hidden files count too, so caches such as ~/.cache/huggingface show up.
+func scanDiskUsage(ctx context.Context, dir string, limits ScanLimits, p *scanProgress) (*DirUsage, error) {
+	root := &DirUsage{Path: dir, Name: filepath.Base(dir)}
+	nodes := map[string]*DirUsage{dir: root}
+
+	var nodeFor func(path string) *DirUsage
+	nodeFor = func(path string) *DirUsage {
+		if n, ok := nodes[path]; ok {
+			return n
+		}
+		parent := nodeFor(filepath.Dir(path))
+		n := &DirUsage{Path: path, Name: filepath.Base(path), parent: parent}
+		parent.Children = append(parent.Children, n)
+		nodes[path] = n
+		return n
+	}
+
+	err := walkLimited(ctx, dir, limits, true, p, func(path string, info fs.FileInfo) {
+		size := info.Size()
+		actualSize, sparse := getActualFileSize(info, size)
+		category := classifyArtifact(path)
+
+		for n := nodeFor(filepath.Dir(path)); n != nil; n = n.parent {
+			n.add(size, actualSize, sparse)
+			if category != "" {
+				if n.Categories == nil {
+					n.Categories = make(map[string]*UsageTotals)
+				}
+				if n.Categories[category] == nil {
+					n.Categories[category] = &UsageTotals{}
+				}
+				n.Categories[category].add(size, actualSize, sparse)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	root.sortChildren()
+	root.SizeHuman = formatBytes(root.Size)
+	root.ActualSizeHuman = formatBytes(root.ActualSize)
+	return root, nil
+}
+
+// diskUsageScan is the ScanFunc behind /api/v1/files/usage
+func diskUsageScan(ctx context.Context, dir string, limits ScanLimits, p *scanProgress) (interface{}, error) {
+	return scanDiskUsage(ctx, dir, limits, p)
+}
+
+// registerDiskUsageHandlers serves the disk usage tree
+func registerDiskUsageHandlers(app *fiber.App, scanner *FileScanner) {
+	// Disk usage of a directory: ?directory=/data&depth=1&top=20&category=checkpoints&refresh=false.
+	// Drilling into a subdirectory reuses a cached scan of any directory above it.
+	app.Get("/api/v1/files/usage", func(c *fiber.Ctx) error {
+		depth := c.QueryInt("depth", defaultUsageDepth)
+		top := c.QueryInt("top", defaultUsageTop)
+		category := c.Query("category")
+		if depth < 0 || depth > maxUsageDepth {
+			return c.Status(400).JSON(fiber.Map{
+				"error": fmt.Sprintf("depth must be between 0 and %d", maxUsageDepth),
+			})
+		}
+		if top < 1 || top > maxUsageTop {
+			return c.Status(400).JSON(fiber.Map{
+				"error": fmt.Sprintf("top must be between 1 and %d", maxUsageTop),
+			})
+		}
+		if category != "" && !isArtifactCategory(category) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": fmt.Sprintf("Unknown category %q (expected %s)", category, strings.Join(ArtifactCategories, ", ")),
+			})
+		}
+
+		dir, err := scanner.Sandbox().Resolve(c.Query("directory"))
+		if err != nil {
+			return c.Status(fileScanErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		job, ok := ScanJob{}, false
+		if !c.QueryBool("refresh") {
+			job, ok = scanner.Covering("disk-usage", dir)
+		}
+		var node *DirUsage
+		if ok {
+			node = job.Result.(*DirUsage).find(dir)
+			// A directory missing from a scan cut short may just not have been reached
+			ok = node != nil || job.Truncated == ""
+		}
+		if !ok {
+			job, err = scanner.StartFor(c, "disk-usage", dir, c.QueryBool("refresh"), diskUsageScan)
+			if err != nil {
+				return c.Status(fileScanErrorStatus(err)).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			if job.Status == ScanRunning {
+				return c.Status(202).JSON(fiber.Map{
+					"directory": dir,
+					"job":       job,
+				})
+			}
+			node, _ = job.Result.(*DirUsage)
+		}
+		if node == nil {
+			node = &DirUsage{Path: dir, Name: filepath.Base(dir)}
+		}
+
+		job.Result = nil
+		return c.JSON(fiber.Map{
+			"directory":  dir,
+			"usage":      node.view(depth, top, category),
+			"categories": ArtifactCategories,
+			"job":        job,
+		})
+	})
+}
+
+func isArtifactCategory(name string) bool {
+	for _, category := range ArtifactCategories {
+		if name == category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gpu-pro/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestClassifyArtifact(t *testing.T) {
+	tests := map[string]string{
+		"/home/a/.cache/huggingface/hub/models--gpt2/blobs/abc":  ArtifactHFCache,
+		"/home/a/.cache/huggingface/hub/model.safetensors":       ArtifactHFCache,
+		"/data/run1/model.safetensors":                           ArtifactCheckpoints,
+		"/data/run1/checkpoint-500/optimizer.bin":                ArtifactCheckpoints,
+		"/data/run1/global_step1000/mp_rank_00_model_states.bin": ArtifactCheckpoints,
+		"/data/imagenet/train-00001.parquet":                     ArtifactDatasets,
+		"/data/datasets/coco/annotations.json":                   ArtifactDatasets,
+		"/home/a/notes.txt":                                      "",
+		"/home/a/huggingface/readme.md":                          "",
+	}
+	for path, want := range tests {
+		if got := classifyArtifact(path); got != want {
+			t.Errorf("classifyArtifact(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDiskUsageTree(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel string, size int) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("runs/a/checkpoint-1/model.safetensors", 64<<10)
+	write("runs/a/log.txt", 4<<10)
+	write("runs/b/model.pt", 128<<10)
+	write("datasets/train.parquet", 32<<10)
+	write(".cache/huggingface/hub/blob", 16<<10)
+
+	// A 1 MB sparse file with nothing written uses (almost) no disk
+	sparse, err := os.Create(filepath.Join(root, "runs", "sparse.img"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparse.Truncate(1 << 20)
+	sparse.Close()
+
+	scanner := NewFileScanner(NewFileSandbox([]string{root}), ScanLimits{}, time.Minute)
+	// Only starting a scan spends the budget; every answer below but the last is cached
+	scanner.SetBudget(ratelimit.NewBudget("scans", 1, time.Minute))
+	app := fiber.New()
+	RegisterFileScanHandlers(app, scanner)
+
+	type response struct {
+		Directory string    `json:"directory"`
+		Usage     *DirUsage `json:"usage"`
+		Job       ScanJob   `json:"job"`
+	}
+	get := func(query string) (int, response) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/files/usage?"+query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r response
+		json.NewDecoder(resp.Body).Decode(&r)
+		return resp.StatusCode, r
+	}
+
+	status, r := get("depth=2")
+	if status == 202 {
+		waitForScan(t, scanner, r.Job.ID)
+		status, r = get("depth=2")
+	}
+	if status != 200 || r.Usage == nil {
+		t.Fatalf("usage status = %d, %+v", status, r)
+	}
+	canonicalRoot := scanner.Sandbox().Roots()[0]
+
+	usage := r.Usage
+	if usage.Files != 6 || usage.SparseFiles != 1 || usage.Size != 244<<10+1<<20 {
+		t.Errorf("root totals = %+v", usage.UsageTotals)
+	}
+	if usage.ActualSize >= usage.Size {
+		t.Errorf("actual size %d not below apparent size %d with a sparse file", usage.ActualSize, usage.Size)
+	}
+	if len(usage.Children) != 3 || usage.Children[0].Name != "runs" {
+		t.Fatalf("root children = %+v", usage.Children)
+	}
+	if runs := usage.Children[0]; len(runs.Children) != 2 || runs.Children[0].Name != "b" || runs.Children[0].Children != nil || runs.Children[0].OmittedChildren != 0 {
+		t.Errorf("runs children = %+v", runs.Children)
+	}
+	if cp := usage.Categories[ArtifactCheckpoints]; cp == nil || cp.Files != 2 || cp.Size != 192<<10 {
+		t.Errorf("checkpoint totals = %+v", cp)
+	}
+	if hf := usage.Categories[ArtifactHFCache]; hf == nil || hf.Files != 1 {
+		t.Errorf("hf cache totals = %+v", hf)
+	}
+
+	// Drilling down reuses the root's scan
+	status, r = get("directory=" + filepath.Join(root, "runs", "a") + "&depth=1")
+	if status != 200 || r.Job.Directory != canonicalRoot || r.Usage.Files != 2 || r.Usage.Children[0].Name != "checkpoint-1" {
+		t.Errorf("drill down = %d, %+v", status, r)
+	}
+
+	// Filtering by category and keeping only the largest children
+	status, r = get("category=datasets&depth=1")
+	if status != 200 || r.Usage.Files != 1 || len(r.Usage.Children) != 1 || r.Usage.Children[0].Name != "datasets" {
+		t.Errorf("datasets = %d, %+v", status, r.Usage)
+	}
+	status, r = get("top=1")
+	if status != 200 || len(r.Usage.Children) != 1 || r.Usage.OmittedChildren != 2 {
+		t.Errorf("top=1 = %d, %+v", status, r.Usage)
+	}
+
+	for _, query := range []string{"category=videos", "depth=9", "top=0"} {
+		if status, _ := get(query); status != 400 {
+			t.Errorf("%s status = %d, want 400", query, status)
+		}
+	}
+	if status, _ := get("directory=" + filepath.Dir(root)); status != 403 {
+		t.Errorf("outside the roots status = %d, want 403", status)
+	}
+	if status, _ := get("refresh=true"); status != 429 {
+		t.Errorf("second scan status = %d, want 429", status)
+	}
+}
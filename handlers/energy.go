@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"gpu-pro/energy"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// energySaveInterval is how often energy totals are flushed to disk
+const energySaveInterval = 5 * time.Minute
+
+// RegisterEnergyHandlers feeds background samples into the energy tracker and exposes consumption, cost and CO2
+func RegisterEnergyHandlers(app *fiber.App, sampler *Sampler, tracker *energy.Tracker) {
+	lastSave := time.Now()
+	sampler.Add(func(gpus map[string]interface{}, processes []map[string]interface{}, interval time.Duration, at time.Time) {
+		tracker.Record(gpus, processes, interval, at)
+
+		if at.Sub(lastSave) >= energySaveInterval {
+			lastSave = at
+			if err := tracker.Save(); err != nil {
+				log.Printf("Failed to save energy totals: %v", err)
+			}
+		}
+	})
+
+	// API endpoint for energy consumption per node, GPU, user and process
+	app.Get("/api/v1/energy", func(c *fiber.Ctx) error {
+		return c.JSON(tracker.Summary())
+	})
+}
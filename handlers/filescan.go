@@ -0,0 +1,642 @@
+package handlers
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpu-pro/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	ErrPathNotAllowed = errors.New("path is outside the allowed scan roots")
+	ErrPathNotFound   = errors.New("path not found")
+	ErrNotDirectory   = errors.New("path is not a directory")
+	ErrScanNotFound   = errors.New("scan not found")
+	ErrTooManyScans   = errors.New("too many scans running")
+)
+
+// Scan job states
+const (
+	ScanRunning   = "running"
+	ScanDone      = "done"
+	ScanCancelled = "cancelled"
+	ScanFailed    = "failed"
+)
+
+// maxRunningScans caps concurrent scans so the API cannot saturate the disks
+const maxRunningScans = 4
+
+// largestFilesCount is how many files a largest-files scan returns
+const largestFilesCount = 10
+
+// errFileLimit stops a walk that reached ScanLimits.MaxFiles
+var errFileLimit = errors.New("file limit reached")
+
+// FileSandbox restricts filesystem browsing to a set of root directories
+type FileSandbox struct {
+	roots []string // Canonical (absolute, symlink-free) root directories
+}
+
+// NewFileSandbox canonicalizes the given roots, skipping those that do not exist
+func NewFileSandbox(roots []string) *FileSandbox {
+	s := &FileSandbox{}
+	for _, root := range roots {
+		resolved, err := canonicalPath(root)
+		if err != nil {
+			log.Printf("Skipping file scan root %s: %v", root, err)
+			continue
+		}
+		s.roots = append(s.roots, resolved)
+	}
+	return s
+}
+
+// Roots returns the canonical root directories
+func (s *FileSandbox) Roots() []string {
+	return append([]string(nil), s.roots...)
+}
+
+// Resolve canonicalizes a directory and checks that it lies within a root. Symlinks are
+// resolved first, so neither "..", nor links pointing elsewhere, escape the roots.
+// An empty directory means the first root.
+func (s *FileSandbox) Resolve(dir string) (string, error) {
+	if len(s.roots) == 0 {
+		return "", ErrPathNotAllowed
+	}
+	if dir == "" {
+		return s.roots[0], nil
+	}
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("%w: %s is not an absolute path", ErrPathNotAllowed, dir)
+	}
+
+	resolved, err := canonicalPath(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, root := range s.roots {
+		if isWithin(root, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrPathNotAllowed, dir)
+}
+
+// canonicalPath returns the absolute, symlink-free form of an existing directory
+func canonicalPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrPathNotFound, dir)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrPathNotFound, dir)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%w: %s", ErrNotDirectory, dir)
+	}
+	return resolved, nil
+}
+
+// isWithin reports whether path is root or below it
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ScanLimits bound a filesystem scan. Zero values mean no limit.
+type ScanLimits struct {
+	MaxDepth int           // Directory levels below the scanned directory
+	MaxFiles int64         // Files examined
+	Timeout  time.Duration // Wall time
+}
+
+// scanProgress counts what a walk has seen so far
+type scanProgress struct {
+	files     atomic.Int64
+	dirs      atomic.Int64
+	bytes     atomic.Int64
+	truncated atomic.Value // string: the limit that cut the scan short
+}
+
+// ScanFunc scans a directory, reporting progress, and returns the scan's result
+type ScanFunc func(ctx context.Context, dir string, limits ScanLimits, p *scanProgress) (interface{}, error)
+
+// walkLimited calls visit for every regular file below dir, honoring the limits. Hidden
+// files and directories are skipped unless includeHidden is set; symlinks are not followed.
+// Hitting a limit ends the walk early without an error and records the limit in
+// p.truncated; cancellation returns the context's error.
+func walkLimited(ctx context.Context, dir string, limits ScanLimits, includeHidden bool, p *scanProgress, visit func(path string, info fs.FileInfo)) error {
+	baseDepth := strings.Count(dir, string(filepath.Separator))
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil // Skip entries we can't access
+		}
+
+		hidden := !includeHidden && path != dir && strings.HasPrefix(d.Name(), ".")
+		if d.IsDir() {
+			if hidden {
+				return filepath.SkipDir
+			}
+			if limits.MaxDepth > 0 && strings.Count(path, string(filepath.Separator))-baseDepth > limits.MaxDepth {
+				p.truncated.Store("max_depth")
+				return filepath.SkipDir
+			}
+			p.dirs.Add(1)
+			return nil
+		}
+		if hidden || !d.Type().IsRegular() {
+			return nil
+		}
+
+		if limits.MaxFiles > 0 && p.files.Load() >= limits.MaxFiles {
+			return errFileLimit
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		p.files.Add(1)
+		p.bytes.Add(info.Size())
+		visit(path, info)
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errFileLimit):
+		p.truncated.Store("max_files")
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		p.truncated.Store("timeout")
+		return nil
+	}
+	return err
+}
+
+// fileHeap is a min-heap of files by actual size
+type fileHeap []LargeFile
+
+func (h fileHeap) Len() int            { return len(h) }
+func (h fileHeap) Less(i, j int) bool  { return h[i].ActualSize < h[j].ActualSize }
+func (h fileHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *fileHeap) Push(x interface{}) { *h = append(*h, x.(LargeFile)) }
+func (h *fileHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// scanLargestFiles returns the n files using the most disk space below dir
+func scanLargestFiles(ctx context.Context, dir string, n int, limits ScanLimits, p *scanProgress) ([]LargeFile, error) {
+	top := &fileHeap{}
+	err := walkLimited(ctx, dir, limits, false, p, func(path string, info fs.FileInfo) {
+		apparentSize := info.Size()
+		actualSize, isSparse := getActualFileSize(info, apparentSize)
+		if top.Len() == n && actualSize <= (*top)[0].ActualSize {
+			return
+		}
+		heap.Push(top, LargeFile{
+			Path:            path,
+			Size:            apparentSize,
+			SizeHuman:       formatBytes(apparentSize),
+			ActualSize:      actualSize,
+			ActualSizeHuman: formatBytes(actualSize),
+			IsSparse:        isSparse,
+			ModTime:         info.ModTime().Format("2006-01-02 15:04:05"),
+		})
+		if top.Len() > n {
+			heap.Pop(top)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := []LargeFile(*top)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ActualSize > files[j].ActualSize
+	})
+	return files, nil
+}
+
+// ScanJob is a snapshot of a background filesystem scan
+type ScanJob struct {
+	ID           string      `json:"id"`
+	Kind         string      `json:"kind"`
+	Directory    string      `json:"directory"`
+	Status       string      `json:"status"`
+	FilesScanned int64       `json:"files_scanned"`
+	DirsScanned  int64       `json:"dirs_scanned"`
+	BytesScanned int64       `json:"bytes_scanned"`
+	Truncated    string      `json:"truncated,omitempty"` // Limit that cut the scan short
+	StartedAt    time.Time   `json:"started_at"`
+	FinishedAt   *time.Time  `json:"finished_at,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	Result       interface{} `json:"result,omitempty"`
+}
+
+// scanJob is a scan in progress or finished
+type scanJob struct {
+	id         string
+	kind       string
+	directory  string
+	status     string
+	progress   scanProgress
+	startedAt  time.Time
+	finishedAt time.Time
+	err        error
+	result     interface{}
+	cancel     context.CancelFunc
+}
+
+// FileScanner runs sandboxed filesystem scans in the background and caches their results
+type FileScanner struct {
+	sandbox  *FileSandbox
+	limits   ScanLimits
+	cacheTTL time.Duration
+	jobs     map[string]*scanJob
+	latest   map[string]*scanJob // kind + directory -> most recent job
+	budget   *ratelimit.Budget   // Scans each client may start; nil for no limit
+	mu       sync.Mutex
+}
+
+// NewFileScanner creates a scanner for the sandbox's roots. Finished scans are reused
+// for cacheTTL before the same directory is scanned again.
+func NewFileScanner(sandbox *FileSandbox, limits ScanLimits, cacheTTL time.Duration) *FileScanner {
+	return &FileScanner{
+		sandbox:  sandbox,
+		limits:   limits,
+		cacheTTL: cacheTTL,
+		jobs:     make(map[string]*scanJob),
+		latest:   make(map[string]*scanJob),
+	}
+}
+
+// Sandbox returns the scanner's sandbox
+func (s *FileScanner) Sandbox() *FileSandbox {
+	return s.sandbox
+}
+
+// SetBudget limits how many scans each client may start. Running and cached scans
+// returned instead of a new one are not counted.
+func (s *FileScanner) SetBudget(budget *ratelimit.Budget) {
+	s.mu.Lock()
+	s.budget = budget
+	s.mu.Unlock()
+}
+
+// Start scans a directory in the background. A running scan of the same directory,
+// or a finished one younger than the cache TTL, is returned instead unless refresh is set.
+func (s *FileScanner) Start(kind, dir string, refresh bool, scan ScanFunc) (ScanJob, error) {
+	return s.start(kind, dir, refresh, scan, nil)
+}
+
+// StartFor is Start on behalf of a request, whose client spends its scan budget
+// if a new scan is started
+func (s *FileScanner) StartFor(c *fiber.Ctx, kind, dir string, refresh bool, scan ScanFunc) (ScanJob, error) {
+	return s.start(kind, dir, refresh, scan, c)
+}
+
+func (s *FileScanner) start(kind, dir string, refresh bool, scan ScanFunc, c *fiber.Ctx) (ScanJob, error) {
+	resolved, err := s.sandbox.Resolve(dir)
+	if err != nil {
+		return ScanJob{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneLocked(now)
+
+	key := kind + "\x00" + resolved
+	if job, ok := s.latest[key]; ok {
+		if job.status == ScanRunning || (!refresh && job.status == ScanDone && now.Sub(job.finishedAt) < s.cacheTTL) {
+			return job.snapshot(), nil
+		}
+	}
+
+	running := 0
+	for _, job := range s.jobs {
+		if job.status == ScanRunning {
+			running++
+		}
+	}
+	if running >= maxRunningScans {
+		return ScanJob{}, ErrTooManyScans
+	}
+	if c != nil {
+		if err := s.budget.Spend(c); err != nil {
+			return ScanJob{}, err
+		}
+	}
+
+	id, err := newScanID()
+	if err != nil {
+		return ScanJob{}, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &scanJob{
+		id:        id,
+		kind:      kind,
+		directory: resolved,
+		status:    ScanRunning,
+		startedAt: now,
+		cancel:    cancel,
+	}
+	s.jobs[id] = job
+	s.latest[key] = job
+
+	go s.run(ctx, job, scan)
+	return job.snapshot(), nil
+}
+
+// run executes a scan and records its outcome
+func (s *FileScanner) run(ctx context.Context, job *scanJob, scan ScanFunc) {
+	defer job.cancel()
+	scanCtx := ctx
+	if s.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		scanCtx, cancel = context.WithTimeout(ctx, s.limits.Timeout)
+		defer cancel()
+	}
+
+	result, err := scan(scanCtx, job.directory, s.limits, &job.progress)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.finishedAt = time.Now()
+	switch {
+	case ctx.Err() != nil:
+		job.status = ScanCancelled
+	case err != nil:
+		job.status = ScanFailed
+		job.err = err
+	default:
+		job.status = ScanDone
+		job.result = result
+	}
+}
+
+// Covering returns the most specific finished, still cached scan of a directory or of
+// one above it, so drilling into a scanned tree needs no new scan
+func (s *FileScanner) Covering(kind, dir string) (ScanJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneLocked(now)
+
+	var best *scanJob
+	for _, job := range s.latest {
+		if job.kind != kind || job.status != ScanDone || !isWithin(job.directory, dir) {
+			continue
+		}
+		if best == nil || len(job.directory) > len(best.directory) {
+			best = job
+		}
+	}
+	if best == nil {
+		return ScanJob{}, false
+	}
+	return best.snapshot(), true
+}
+
+// Job returns a scan by ID
+func (s *FileScanner) Job(id string) (ScanJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return ScanJob{}, ErrScanNotFound
+	}
+	return job.snapshot(), nil
+}
+
+// Cancel stops a running scan
+func (s *FileScanner) Cancel(id string) error {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return ErrScanNotFound
+	}
+	job.cancel()
+	return nil
+}
+
+// pruneLocked forgets finished scans older than the cache TTL (s.mu must be held)
+func (s *FileScanner) pruneLocked(now time.Time) {
+	for id, job := range s.jobs {
+		if job.status != ScanRunning && now.Sub(job.finishedAt) >= s.cacheTTL {
+			delete(s.jobs, id)
+			if s.latest[job.kind+"\x00"+job.directory] == job {
+				delete(s.latest, job.kind+"\x00"+job.directory)
+			}
+		}
+	}
+}
+
+// snapshot copies the job's state (the scanner's mutex must be held)
+func (j *scanJob) snapshot() ScanJob {
+	snap := ScanJob{
+		ID:           j.id,
+		Kind:         j.kind,
+		Directory:    j.directory,
+		Status:       j.status,
+		FilesScanned: j.progress.files.Load(),
+		DirsScanned:  j.progress.dirs.Load(),
+		BytesScanned: j.progress.bytes.Load(),
+		StartedAt:    j.startedAt,
+		Result:       j.result,
+	}
+	if truncated, ok := j.progress.truncated.Load().(string); ok {
+		snap.Truncated = truncated
+	}
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		snap.FinishedAt = &finished
+	}
+	if j.err != nil {
+		snap.Error = j.err.Error()
+	}
+	return snap
+}
+
+func newScanID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// largestFilesScan is the ScanFunc behind /api/largest-files
+func largestFilesScan(ctx context.Context, dir string, limits ScanLimits, p *scanProgress) (interface{}, error) {
+	return scanLargestFiles(ctx, dir, largestFilesCount, limits, p)
+}
+
+// fileScanErrorStatus maps scanner errors to HTTP status codes
+func fileScanErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrPathNotAllowed):
+		return 403
+	case errors.Is(err, ErrPathNotFound), errors.Is(err, ErrScanNotFound):
+		return 404
+	case errors.Is(err, ErrNotDirectory):
+		return 400
+	case errors.Is(err, ErrTooManyScans), errors.Is(err, ratelimit.ErrRateLimited):
+		return 429
+	default:
+		return 500
+	}
+}
+
+// RegisterFileScanHandlers exposes sandboxed filesystem scans
+func RegisterFileScanHandlers(app *fiber.App, scanner *FileScanner) {
+	// Directory the dashboard suggests scanning: the home directory if allowed, else the first root
+	app.Get("/api/home-directory", func(c *fiber.Ctx) error {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			home, err = scanner.Sandbox().Resolve(home)
+		}
+		if err != nil {
+			home, _ = scanner.Sandbox().Resolve("")
+		}
+		return c.JSON(fiber.Map{
+			"home":  home,
+			"roots": scanner.Sandbox().Roots(),
+		})
+	})
+
+	// Largest files of a directory. Answers 202 with the scan while it is still running;
+	// poll /api/v1/files/scans/:id or call again. ?refresh=true ignores cached results.
+	app.Get("/api/largest-files", func(c *fiber.Ctx) error {
+		job, err := scanner.StartFor(c, "largest-files", c.Query("directory"), c.QueryBool("refresh"), largestFilesScan)
+		if err != nil {
+			return c.Status(fileScanErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if job.Status == ScanRunning {
+			return c.Status(202).JSON(fiber.Map{
+				"directory": job.Directory,
+				"job":       job,
+			})
+		}
+
+		files, _ := job.Result.([]LargeFile)
+		if files == nil {
+			files = []LargeFile{}
+		}
+		job.Result = nil
+		return c.JSON(fiber.Map{
+			"directory": job.Directory,
+			"files":     files,
+			"job":       job,
+		})
+	})
+
+	// Allowed scan roots
+	app.Get("/api/v1/files/roots", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"roots": scanner.Sandbox().Roots(),
+		})
+	})
+
+	// Start a scan: {"directory": "/data", "kind": "largest-files", "refresh": false}
+	app.Post("/api/v1/files/scans", func(c *fiber.Ctx) error {
+		var req struct {
+			Directory string `json:"directory"`
+			Kind      string `json:"kind"`
+			Refresh   bool   `json:"refresh"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+		if req.Kind == "" {
+			req.Kind = "largest-files"
+		}
+		scan, ok := scanKinds[req.Kind]
+		if !ok {
+			return c.Status(400).JSON(fiber.Map{
+				"error": fmt.Sprintf("Unknown scan kind %q", req.Kind),
+			})
+		}
+
+		job, err := scanner.StartFor(c, req.Kind, req.Directory, req.Refresh, scan)
+		if err != nil {
+			return c.Status(fileScanErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if job.Status == ScanRunning {
+			return c.Status(202).JSON(job)
+		}
+		return c.JSON(jobView(job))
+	})
+
+	// Scan progress, and its result once done
+	app.Get("/api/v1/files/scans/:id", func(c *fiber.Ctx) error {
+		job, err := scanner.Job(c.Params("id"))
+		if err != nil {
+			return c.Status(fileScanErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(jobView(job))
+	})
+
+	// Cancel a running scan
+	app.Delete("/api/v1/files/scans/:id", func(c *fiber.Ctx) error {
+		if err := scanner.Cancel(c.Params("id")); err != nil {
+			return c.Status(fileScanErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.SendStatus(204)
+	})
+
+	registerDiskUsageHandlers(app, scanner)
+}
+
+// jobView trims results too large to return whole; /api/v1/files/usage drills into them
+func jobView(job ScanJob) ScanJob {
+	if usage, ok := job.Result.(*DirUsage); ok {
+		job.Result = usage.view(defaultUsageDepth, defaultUsageTop, "")
+	}
+	return job
+}
+
+// scanKinds are the scans available through /api/v1/files/scans
+var scanKinds = map[string]ScanFunc{
+	"largest-files": largestFilesScan,
+	"disk-usage":    diskUsageScan,
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForScan polls a scan until it is no longer running
+func waitForScan(t *testing.T, s *FileScanner, id string) ScanJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := s.Job(id)
+		if err != nil {
+			t.Fatalf("Job(%s): %v", id, err)
+		}
+		if job.Status != ScanRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("scan %s still running", id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFileSandbox(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "data", "models"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "data", "weights.bin"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	sandbox := NewFileSandbox([]string{root, filepath.Join(root, "missing")})
+	if roots := sandbox.Roots(); len(roots) != 1 {
+		t.Fatalf("roots = %v, want only the existing root", roots)
+	}
+	canonicalRoot := sandbox.Roots()[0]
+
+	tests := []struct {
+		dir  string
+		want string
+		err  error
+	}{
+		{"", canonicalRoot, nil},
+		{filepath.Join(root, "data", "models"), filepath.Join(canonicalRoot, "data", "models"), nil},
+		{filepath.Join(root, "data", "..", "data"), filepath.Join(canonicalRoot, "data"), nil},
+		{filepath.Join(root, ".."), "", ErrPathNotAllowed},
+		{filepath.Join(root, "data", "..", "..", filepath.Base(outside)), "", ErrPathNotAllowed},
+		{filepath.Join(root, "escape"), "", ErrPathNotAllowed},
+		{outside, "", ErrPathNotAllowed},
+		{"data", "", ErrPathNotAllowed},
+		{filepath.Join(root, "nope"), "", ErrPathNotFound},
+		{filepath.Join(root, "data", "weights.bin"), "", ErrNotDirectory},
+	}
+	for _, tt := range tests {
+		got, err := sandbox.Resolve(tt.dir)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Resolve(%q) error = %v, want %v", tt.dir, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", tt.dir, got, err, tt.want)
+		}
+	}
+
+	if _, err := NewFileSandbox(nil).Resolve(""); !errors.Is(err, ErrPathNotAllowed) {
+		t.Errorf("Resolve without roots error = %v, want ErrPathNotAllowed", err)
+	}
+}
+
+func TestFileScanner(t *testing.T) {
+	root := t.TempDir()
+	for i, size := range []int{16 << 10, 64 << 10, 32 << 10, 128 << 10} {
+		dir := filepath.Join(root, "sub", string(rune('a'+i)))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "file"), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".hidden"), make([]byte, 9000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := NewFileScanner(NewFileSandbox([]string{root}), ScanLimits{}, time.Minute)
+	job, err := scanner.Start("largest-files", root, false, largestFilesScan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := waitForScan(t, scanner, job.ID)
+	files, _ := done.Result.([]LargeFile)
+	if done.Status != ScanDone || done.FilesScanned != 4 || len(files) != 4 {
+		t.Fatalf("scan = %+v, want 4 files done", done)
+	}
+	if files[0].Size != 128<<10 || files[3].Size != 16<<10 {
+		t.Errorf("files not sorted largest first: %+v", files)
+	}
+
+	// Finished scans are reused until refreshed
+	cached, err := scanner.Start("largest-files", root, false, largestFilesScan)
+	if err != nil || cached.ID != job.ID {
+		t.Errorf("cached scan = %s, %v; want %s", cached.ID, err, job.ID)
+	}
+	fresh, err := scanner.Start("largest-files", root, true, largestFilesScan)
+	if err != nil || fresh.ID == job.ID {
+		t.Errorf("refreshed scan = %s, %v; want a new scan", fresh.ID, err)
+	}
+	waitForScan(t, scanner, fresh.ID)
+
+	// Limits stop the walk early and are reported
+	limited := NewFileScanner(NewFileSandbox([]string{root}), ScanLimits{MaxFiles: 2}, time.Minute)
+	job, err = limited.Start("largest-files", root, false, largestFilesScan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done := waitForScan(t, limited, job.ID); done.Status != ScanDone || done.FilesScanned != 2 || done.Truncated != "max_files" {
+		t.Errorf("limited scan = %+v, want 2 files truncated by max_files", done)
+	}
+
+	shallow := NewFileScanner(NewFileSandbox([]string{root}), ScanLimits{MaxDepth: 1}, time.Minute)
+	job, err = shallow.Start("largest-files", root, false, largestFilesScan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done := waitForScan(t, shallow, job.ID); done.FilesScanned != 0 || done.Truncated != "max_depth" {
+		t.Errorf("shallow scan = %+v, want no files truncated by max_depth", done)
+	}
+
+	// Cancelling a scan stops it
+	started := make(chan struct{})
+	blocking := func(ctx context.Context, dir string, limits ScanLimits, p *scanProgress) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	job, err = scanner.Start("blocking", root, false, blocking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := scanner.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if done := waitForScan(t, scanner, job.ID); done.Status != ScanCancelled {
+		t.Errorf("cancelled scan status = %s", done.Status)
+	}
+	if err := scanner.Cancel("unknown"); !errors.Is(err, ErrScanNotFound) {
+		t.Errorf("Cancel(unknown) error = %v", err)
+	}
+
+	if _, err := scanner.Start("largest-files", filepath.Dir(root), false, largestFilesScan); !errors.Is(err, ErrPathNotAllowed) {
+		t.Errorf("scan outside the roots error = %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"time"
+
+	"gpu-pro/mounts"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// filesystemMonitor feeds per-mount usage into the system metrics payload (nil if disabled)
+var filesystemMonitor *mounts.Monitor
+
+// FilesystemRules returns the filesystem alert rules from the saved alert thresholds
+func FilesystemRules() mounts.Rules {
+	thresholds := getDefaultThresholds()
+	if saved, err := loadAlertThresholds(); err == nil {
+		for k, v := range saved {
+			thresholds[k] = v
+		}
+	}
+
+	value := func(key string) float64 {
+		switch v := thresholds[key].(type) {
+		case float64:
+			return v
+		case int:
+			return float64(v)
+		}
+		return 0
+	}
+	hours := func(key string) time.Duration {
+		return time.Duration(value(key) * float64(time.Hour))
+	}
+	return mounts.Rules{
+		DiskWarning:    value("disk_warning"),
+		DiskCritical:   value("disk_critical"),
+		InodesWarning:  value("inodes_warning"),
+		InodesCritical: value("inodes_critical"),
+		FullWarning:    hours("disk_full_hours_warning"),
+		FullCritical:   hours("disk_full_hours_critical"),
+	}
+}
+
+// RegisterFilesystemHandlers serves per-mount usage, fill predictions and alerts
+func RegisterFilesystemHandlers(app *fiber.App, m *mounts.Monitor) {
+	filesystemMonitor = m
+
+	app.Get("/api/v1/filesystems", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"filesystems": m.Filesystems(),
+			"alerts":      m.Alerts(),
+		})
+	})
+}
+
+// getFilesystems returns the monitored filesystems for the system metrics payload
+func getFilesystems() []mounts.Filesystem {
+	if filesystemMonitor == nil {
+		return []mounts.Filesystem{}
+	}
+	return filesystemMonitor.Filesystems()
+}
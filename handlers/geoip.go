@@ -2,11 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
+
+	"gpu-pro/config"
 )
 
 // GeoLocation represents geographical location data
@@ -21,6 +25,64 @@ type GeoLocation struct {
 	ISP         string  `json:"isp"`
 }
 
+// GeoIPProvider resolves IP addresses to locations
+type GeoIPProvider interface {
+	Name() string
+	Lookup(ip string) (*GeoLocation, error) // nil, nil if the address is not in the provider's data
+}
+
+// ErrGeoIPRateLimited means the provider refuses more lookups for now
+var ErrGeoIPRateLimited = errors.New("geoip lookups rate limited")
+
+var (
+	geoProvider   GeoIPProvider
+	geoProviderMu sync.RWMutex
+)
+
+// SetGeoIPProvider sets the provider used to locate connections; nil disables geolocation
+func SetGeoIPProvider(p GeoIPProvider) {
+	geoProviderMu.Lock()
+	geoProvider = p
+	geoProviderMu.Unlock()
+}
+
+// GeoIPProviderName returns the name of the active provider, or "none" if disabled
+func GeoIPProviderName() string {
+	geoProviderMu.RLock()
+	defer geoProviderMu.RUnlock()
+	if geoProvider == nil {
+		return "none"
+	}
+	return geoProvider.Name()
+}
+
+// NewGeoIPProvider creates the provider selected by GEOIP_PROVIDER: "mmdb" reads local
+// databases, "ip-api" looks addresses up online and "none" disables geolocation. "auto"
+// uses the local database if one is configured and otherwise disables geolocation.
+// It returns nil, nil when geolocation is disabled.
+func NewGeoIPProvider(cfg *config.Config) (GeoIPProvider, error) {
+	mode := strings.ToLower(cfg.GeoIPProvider)
+	if mode == "auto" || mode == "" {
+		mode = "none"
+		if cfg.GeoIPDatabase != "" {
+			mode = "mmdb"
+		}
+	}
+
+	switch mode {
+	case "mmdb":
+		if cfg.GeoIPDatabase == "" {
+			return nil, errors.New("GEOIP_DATABASE is required for the mmdb provider")
+		}
+		return NewMMDBProvider(cfg.GeoIPDatabase, cfg.GeoIPASNDatabase)
+	case "ip-api":
+		return NewIPAPIProvider(cfg.GeoIPURL, ipAPIRequestsPerMinute), nil
+	case "none":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown GeoIP provider %q (want auto, mmdb, ip-api or none)", cfg.GeoIPProvider)
+}
+
 // IPAPIResponse is the response from ip-api.com
 type IPAPIResponse struct {
 	Query       string  `json:"query"`
@@ -37,14 +99,6 @@ type IPAPIResponse struct {
 	AS          string  `json:"as"`
 }
 
-var (
-	geoCache   = make(map[string]*GeoLocation)
-	geoCacheMu sync.RWMutex
-	httpClient = &http.Client{
-		Timeout: 5 * time.Second,
-	}
-)
-
 // IsPrivateIP checks if an IP is private/local
 func IsPrivateIP(ip string) bool {
 	// Remove port if present
@@ -100,87 +154,184 @@ func ExtractIP(addr string) string {
 	return host
 }
 
-// LookupGeoLocation performs IP geolocation lookup
-func LookupGeoLocation(ip string) (*GeoLocation, error) {
-	// Check cache first
-	geoCacheMu.RLock()
-	if cached, ok := geoCache[ip]; ok {
-		geoCacheMu.RUnlock()
-		return cached, nil
+// IPAPIProvider looks addresses up online at ip-api.com. Every lookup sends the address
+// to a third party, so it is only used when configured explicitly.
+type IPAPIProvider struct {
+	url       string // Base URL the address is appended to
+	perMinute int    // Requests allowed per minute
+	client    *http.Client
+
+	cache     map[string]ipAPICacheEntry
+	cacheSize int           // Most addresses kept in the cache
+	cacheTTL  time.Duration // How long a found location is reused
+	missTTL   time.Duration // How long an unknown address is not asked for again
+	requests  []time.Time   // Requests made in the last minute
+	mu        sync.Mutex
+}
+
+// ipAPICacheEntry is a cached answer; location is nil for unknown addresses
+type ipAPICacheEntry struct {
+	location *GeoLocation
+	expires  time.Time
+}
+
+// DefaultIPAPIURL is the free ip-api.com endpoint, which only serves plain HTTP
+const DefaultIPAPIURL = "http://ip-api.com/json/"
+
+// ipAPIRequestsPerMinute stays below the free endpoint's limit of 45 requests a minute
+const ipAPIRequestsPerMinute = 40
+
+// Bounds of the ip-api answer cache, so a long-running hub with many peers does not grow forever
+const (
+	ipAPICacheSize = 10000
+	ipAPICacheTTL  = 24 * time.Hour
+	ipAPIMissTTL   = time.Hour
+)
+
+// NewIPAPIProvider creates an online provider making at most perMinute requests a minute
+// (0 for no limit). An empty url uses DefaultIPAPIURL.
+func NewIPAPIProvider(url string, perMinute int) *IPAPIProvider {
+	if url == "" {
+		url = DefaultIPAPIURL
 	}
-	geoCacheMu.RUnlock()
+	return &IPAPIProvider{
+		url:       url,
+		perMinute: perMinute,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		cache:     make(map[string]ipAPICacheEntry),
+		cacheSize: ipAPICacheSize,
+		cacheTTL:  ipAPICacheTTL,
+		missTTL:   ipAPIMissTTL,
+	}
+}
 
-	// Use ip-api.com (free, no API key needed, 45 req/min limit)
-	url := "http://ip-api.com/json/" + ip + "?fields=status,country,countryCode,region,regionName,city,lat,lon,isp,org,as,query"
+// Name implements GeoIPProvider
+func (p *IPAPIProvider) Name() string {
+	return "ip-api"
+}
 
-	resp, err := httpClient.Get(url)
+// Lookup implements GeoIPProvider. Answers are cached for cacheTTL, unknown addresses for missTTL.
+func (p *IPAPIProvider) Lookup(ip string) (*GeoLocation, error) {
+	now := time.Now()
+	p.mu.Lock()
+	if cached, ok := p.cache[ip]; ok && now.Before(cached.expires) {
+		p.mu.Unlock()
+		return cached.location, nil
+	}
+	for len(p.requests) > 0 && now.Sub(p.requests[0]) >= time.Minute {
+		p.requests = p.requests[1:]
+	}
+	if p.perMinute > 0 && len(p.requests) >= p.perMinute {
+		p.mu.Unlock()
+		return nil, ErrGeoIPRateLimited
+	}
+	p.requests = append(p.requests, now)
+	p.mu.Unlock()
+
+	url := p.url + ip + "?fields=status,country,countryCode,region,regionName,city,lat,lon,isp,org,as,query"
+	resp, err := p.client.Get(url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrGeoIPRateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ip-api returned %s", resp.Status)
+	}
+
 	var apiResp IPAPIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
 		return nil, err
 	}
 
-	if apiResp.Status != "success" {
-		return nil, nil
+	var geoLoc *GeoLocation
+	if apiResp.Status == "success" {
+		geoLoc = &GeoLocation{
+			IP:          ip,
+			Country:     apiResp.Country,
+			CountryCode: apiResp.CountryCode,
+			Region:      apiResp.RegionName,
+			City:        apiResp.City,
+			Latitude:    apiResp.Lat,
+			Longitude:   apiResp.Lon,
+			ISP:         apiResp.ISP,
+		}
 	}
 
-	geoLoc := &GeoLocation{
-		IP:          ip,
-		Country:     apiResp.Country,
-		CountryCode: apiResp.CountryCode,
-		Region:      apiResp.RegionName,
-		City:        apiResp.City,
-		Latitude:    apiResp.Lat,
-		Longitude:   apiResp.Lon,
-		ISP:         apiResp.ISP,
+	// Cache unknown addresses too, so they are not asked for again right away
+	ttl := p.cacheTTL
+	if geoLoc == nil {
+		ttl = p.missTTL
 	}
-
-	// Cache the result
-	geoCacheMu.Lock()
-	geoCache[ip] = geoLoc
-	geoCacheMu.Unlock()
+	p.mu.Lock()
+	p.storeLocked(ip, ipAPICacheEntry{location: geoLoc, expires: time.Now().Add(ttl)})
+	p.mu.Unlock()
 
 	return geoLoc, nil
 }
 
+// storeLocked caches an answer. When the cache is full, expired answers are dropped
+// first and then the ones closest to expiring.
+func (p *IPAPIProvider) storeLocked(ip string, entry ipAPICacheEntry) {
+	if _, ok := p.cache[ip]; !ok && len(p.cache) >= p.cacheSize {
+		now := time.Now()
+		for key, cached := range p.cache {
+			if !now.Before(cached.expires) {
+				delete(p.cache, key)
+			}
+		}
+		for len(p.cache) >= p.cacheSize {
+			var oldest string
+			for key, cached := range p.cache {
+				if oldest == "" || cached.expires.Before(p.cache[oldest].expires) {
+					oldest = key
+				}
+			}
+			delete(p.cache, oldest)
+		}
+	}
+	p.cache[ip] = entry
+}
+
+// LookupGeoLocation locates an IP address with the configured provider (nil, nil if disabled)
+func LookupGeoLocation(ip string) (*GeoLocation, error) {
+	geoProviderMu.RLock()
+	p := geoProvider
+	geoProviderMu.RUnlock()
+	if p == nil {
+		return nil, nil
+	}
+	return p.Lookup(ip)
+}
+
 // GetConnectionGeoLocations gets geolocation for all external IPs in connections
 func GetConnectionGeoLocations(connections []NetworkConnection) map[string]*GeoLocation {
 	locations := make(map[string]*GeoLocation)
-	uniqueIPs := make(map[string]bool)
+	if GeoIPProviderName() == "none" {
+		return locations
+	}
 
 	// Extract unique external IPs
+	uniqueIPs := make(map[string]bool)
 	for _, conn := range connections {
 		foreignIP := ExtractIP(conn.ForeignAddr)
-		
+
 		// Skip private/local IPs and already processed IPs
-		if !IsPrivateIP(foreignIP) && !uniqueIPs[foreignIP] && foreignIP != "" && foreignIP != "*" {
+		if !IsPrivateIP(foreignIP) && foreignIP != "" && foreignIP != "*" {
 			uniqueIPs[foreignIP] = true
 		}
 	}
 
-	// Lookup geolocation for each unique IP (with rate limiting)
-	// ip-api.com allows 45 requests per minute for free
-	count := 0
-	maxPerBatch := 15 // Conservative limit
-
 	for ip := range uniqueIPs {
-		if count >= maxPerBatch {
-			break // Avoid rate limiting
-		}
-
 		geoLoc, err := LookupGeoLocation(ip)
+		if errors.Is(err, ErrGeoIPRateLimited) {
+			break // The rest are looked up on a later refresh
+		}
 		if err == nil && geoLoc != nil {
 			locations[ip] = geoLoc
-			count++
-		}
-
-		// Small delay to avoid rate limiting
-		if count < maxPerBatch {
-			time.Sleep(100 * time.Millisecond)
 		}
 	}
 
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// mmdbReloadInterval is how often the database files are checked for updates
+var mmdbReloadInterval = time.Minute
+
+// mmdbCityRecord holds the fields read from a City (or Country) database such as
+// GeoLite2-City or DB-IP City Lite
+type mmdbCityRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// mmdbASNRecord holds the fields read from an ASN or ISP database such as GeoLite2-ASN
+type mmdbASNRecord struct {
+	ISP          string `maxminddb:"isp"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// mmdbFile is an open database that is reopened when the file on disk changes
+type mmdbFile struct {
+	path    string
+	reader  *maxminddb.Reader
+	modTime time.Time
+}
+
+func openMMDBFile(path string) (*mmdbFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &mmdbFile{path: path, reader: reader, modTime: info.ModTime()}, nil
+}
+
+// MMDBProvider looks addresses up in local MaxMind-format databases, so no address
+// leaves the host. Updated database files are picked up without a restart.
+type MMDBProvider struct {
+	city    *mmdbFile
+	asn     *mmdbFile // Optional, for the ISP
+	checked time.Time
+	mu      sync.RWMutex
+}
+
+// NewMMDBProvider opens a City database and, if asnPath is set, an ASN database
+func NewMMDBProvider(cityPath, asnPath string) (*MMDBProvider, error) {
+	city, err := openMMDBFile(cityPath)
+	if err != nil {
+		return nil, err
+	}
+	p := &MMDBProvider{city: city, checked: time.Now()}
+	if asnPath != "" {
+		if p.asn, err = openMMDBFile(asnPath); err != nil {
+			city.reader.Close()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Name implements GeoIPProvider
+func (p *MMDBProvider) Name() string {
+	return "mmdb"
+}
+
+// Lookup implements GeoIPProvider
+func (p *MMDBProvider) Lookup(ip string) (*GeoLocation, error) {
+	p.reloadIfChanged()
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil, nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var city mmdbCityRecord
+	_, found, err := p.city.reader.LookupNetwork(parsed, &city)
+	if err != nil || !found {
+		// An IPv6 address in an IPv4-only database is simply not found
+		return nil, nil
+	}
+
+	geoLoc := &GeoLocation{
+		IP:          ip,
+		Country:     city.Country.Names["en"],
+		CountryCode: city.Country.ISOCode,
+		City:        city.City.Names["en"],
+		Latitude:    city.Location.Latitude,
+		Longitude:   city.Location.Longitude,
+	}
+	if len(city.Subdivisions) > 0 {
+		geoLoc.Region = city.Subdivisions[0].Names["en"]
+	}
+
+	if p.asn != nil {
+		var asn mmdbASNRecord
+		if _, found, err := p.asn.reader.LookupNetwork(parsed, &asn); err == nil && found {
+			geoLoc.ISP = asn.ISP
+			if geoLoc.ISP == "" {
+				geoLoc.ISP = asn.Organization
+			}
+		}
+	}
+	return geoLoc, nil
+}
+
+// Close closes the databases
+func (p *MMDBProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.asn != nil {
+		p.asn.reader.Close()
+	}
+	return p.city.reader.Close()
+}
+
+// reloadIfChanged reopens database files whose modification time changed, at most
+// once per mmdbReloadInterval. A file that fails to open keeps the previous version.
+func (p *MMDBProvider) reloadIfChanged() {
+	p.mu.RLock()
+	due := time.Since(p.checked) >= mmdbReloadInterval
+	p.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.checked) < mmdbReloadInterval {
+		return
+	}
+	p.checked = time.Now()
+
+	for _, f := range []*mmdbFile{p.city, p.asn} {
+		if f == nil {
+			continue
+		}
+		info, err := os.Stat(f.path)
+		if err != nil || info.ModTime().Equal(f.modTime) {
+			continue
+		}
+		reader, err := maxminddb.Open(f.path)
+		if err != nil {
+			log.Printf("Failed to reload GeoIP database %s: %v", f.path, err)
+			continue
+		}
+		f.reader.Close()
+		f.reader, f.modTime = reader, info.ModTime()
+		log.Printf("Reloaded GeoIP database %s", f.path)
+	}
+}
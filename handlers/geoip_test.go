@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gpu-pro/config"
+)
+
+// encodeMMDB encodes a value in the MaxMind DB data section format
+func encodeMMDB(v interface{}) []byte {
+	control := func(typ, size int) []byte {
+		var b []byte
+		if typ > 7 {
+			b = []byte{0, byte(typ - 7)}
+		} else {
+			b = []byte{byte(typ << 5)}
+		}
+		if size < 29 {
+			b[0] |= byte(size)
+		} else {
+			b[0] |= 29
+			b = append(b, byte(size-29))
+		}
+		return b
+	}
+	switch v := v.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case float64:
+		b := binary.BigEndian.AppendUint64(nil, math.Float64bits(v))
+		return append(control(3, 8), b...)
+	case uint16:
+		return append(control(5, 2), byte(v>>8), byte(v))
+	case uint32:
+		return append(control(6, 4), binary.BigEndian.AppendUint32(nil, v)...)
+	case uint64:
+		return append(control(9, 8), binary.BigEndian.AppendUint64(nil, v)...)
+	case []interface{}:
+		b := control(11, len(v))
+		for _, item := range v {
+			b = append(b, encodeMMDB(item)...)
+		}
+		return b
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b := control(7, len(v))
+		for _, k := range keys {
+			b = append(b, encodeMMDB(k)...)
+			b = append(b, encodeMMDB(v[k])...)
+		}
+		return b
+	}
+	panic(fmt.Sprintf("cannot encode %T", v))
+}
+
+// writeMMDB writes an IPv4 database with a single node: addresses below 128.0.0.0
+// resolve to record and the rest are not found
+func writeMMDB(t *testing.T, path string, record map[string]interface{}) {
+	t.Helper()
+	const nodeCount = 1
+	data := encodeMMDB(record)
+
+	// 24-bit records: left points at the data section, right is "not found"
+	pointer := nodeCount + 16
+	b := []byte{byte(pointer >> 16), byte(pointer >> 8), byte(pointer), 0, 0, nodeCount}
+	b = append(b, make([]byte, 16)...)
+	b = append(b, data...)
+	b = append(b, "\xAB\xCD\xEFMaxMind.com"...)
+	b = append(b, encodeMMDB(map[string]interface{}{
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(4),
+		"database_type":               "Test-City",
+		"languages":                   []interface{}{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"description":                 map[string]interface{}{"en": "test"},
+	})...)
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMMDBProvider(t *testing.T) {
+	dir := t.TempDir()
+	cityPath := filepath.Join(dir, "city.mmdb")
+	asnPath := filepath.Join(dir, "asn.mmdb")
+	writeMMDB(t, cityPath, map[string]interface{}{
+		"city":         map[string]interface{}{"names": map[string]interface{}{"en": "Frankfurt am Main", "de": "Frankfurt"}},
+		"country":      map[string]interface{}{"iso_code": "DE", "names": map[string]interface{}{"en": "Germany"}},
+		"subdivisions": []interface{}{map[string]interface{}{"names": map[string]interface{}{"en": "Hesse"}}},
+		"location":     map[string]interface{}{"latitude": 50.1188, "longitude": 8.6843},
+	})
+	writeMMDB(t, asnPath, map[string]interface{}{"autonomous_system_organization": "Example Networks"})
+
+	p, err := NewMMDBProvider(cityPath, asnPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	loc, err := p.Lookup("81.2.69.142")
+	if err != nil || loc == nil {
+		t.Fatalf("Lookup = %v, %v", loc, err)
+	}
+	want := GeoLocation{IP: "81.2.69.142", Country: "Germany", CountryCode: "DE", Region: "Hesse",
+		City: "Frankfurt am Main", Latitude: 50.1188, Longitude: 8.6843, ISP: "Example Networks"}
+	if *loc != want {
+		t.Errorf("Lookup = %+v, want %+v", *loc, want)
+	}
+	for _, ip := range []string{"203.0.113.9", "2001:db8::1", "not-an-ip"} {
+		if loc, err := p.Lookup(ip); loc != nil || err != nil {
+			t.Errorf("Lookup(%s) = %+v, %v; want not found", ip, loc, err)
+		}
+	}
+
+	// A replaced database is picked up on the next check
+	mmdbReloadInterval = 0
+	defer func() { mmdbReloadInterval = time.Minute }()
+	writeMMDB(t, cityPath, map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "FR", "names": map[string]interface{}{"en": "France"}},
+	})
+	os.Chtimes(cityPath, time.Now(), time.Now().Add(time.Minute))
+	if loc, _ := p.Lookup("81.2.69.142"); loc == nil || loc.CountryCode != "FR" || loc.City != "" {
+		t.Errorf("after reload = %+v", loc)
+	}
+
+	if _, err := NewMMDBProvider(filepath.Join(dir, "missing.mmdb"), ""); err == nil {
+		t.Error("missing database opened")
+	}
+}
+
+func TestIPAPIProvider(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/json/192.0.2.200" {
+			fmt.Fprint(w, `{"status":"fail","query":"192.0.2.200"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"success","country":"Japan","countryCode":"JP","regionName":"Tokyo","city":"Tokyo","lat":35.68,"lon":139.69,"isp":"Example"}`)
+	}))
+	defer server.Close()
+
+	p := NewIPAPIProvider(server.URL+"/json/", 2)
+	loc, err := p.Lookup("8.8.8.8")
+	if err != nil || loc == nil || loc.City != "Tokyo" || loc.IP != "8.8.8.8" {
+		t.Fatalf("Lookup = %+v, %v", loc, err)
+	}
+	if loc, err := p.Lookup("192.0.2.200"); loc != nil || err != nil {
+		t.Errorf("failed lookup = %+v, %v; want not found", loc, err)
+	}
+
+	// Cached answers, found or not, cost no request
+	p.Lookup("8.8.8.8")
+	p.Lookup("192.0.2.200")
+	if n := requests.Load(); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+	if _, err := p.Lookup("1.1.1.1"); err != ErrGeoIPRateLimited {
+		t.Errorf("third request err = %v, want rate limited", err)
+	}
+}
+
+func TestIPAPIProviderCacheBounds(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/json/192.0.2.200" {
+			fmt.Fprint(w, `{"status":"fail","query":"192.0.2.200"}`)
+			return
+		}
+		fmt.Fprint(w, `{"status":"success","country":"Japan","countryCode":"JP","city":"Tokyo"}`)
+	}))
+	defer server.Close()
+
+	p := NewIPAPIProvider(server.URL+"/json/", 0)
+	p.cacheSize = 2
+	for _, ip := range []string{"8.8.8.8", "8.8.4.4", "1.1.1.1"} {
+		if _, err := p.Lookup(ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(p.cache); n != 2 {
+		t.Errorf("cache holds %d addresses, want at most 2", n)
+	}
+	if _, ok := p.cache["8.8.8.8"]; ok {
+		t.Error("oldest answer not evicted from a full cache")
+	}
+
+	// Unknown addresses are asked for again once their shorter TTL expires
+	p.missTTL = 0
+	p.Lookup("192.0.2.200")
+	p.Lookup("192.0.2.200")
+	if n := requests.Load(); n != 5 {
+		t.Errorf("requests = %d, want 5 with the miss expired", n)
+	}
+}
+
+func TestGeoIPProviderSelection(t *testing.T) {
+	defer SetGeoIPProvider(nil)
+
+	for _, mode := range []string{"auto", "none"} {
+		p, err := NewGeoIPProvider(&config.Config{GeoIPProvider: mode})
+		if p != nil || err != nil {
+			t.Errorf("%s without a database = %v, %v; want disabled", mode, p, err)
+		}
+	}
+	if _, err := NewGeoIPProvider(&config.Config{GeoIPProvider: "mmdb"}); err == nil {
+		t.Error("mmdb without a database accepted")
+	}
+	if _, err := NewGeoIPProvider(&config.Config{GeoIPProvider: "maxmind"}); err == nil {
+		t.Error("unknown provider accepted")
+	}
+	if p, err := NewGeoIPProvider(&config.Config{GeoIPProvider: "ip-api"}); err != nil || p.Name() != "ip-api" {
+		t.Errorf("ip-api = %v, %v", p, err)
+	}
+
+	// Disabled geolocation never looks anything up
+	SetGeoIPProvider(nil)
+	connections := []NetworkConnection{{ForeignAddr: "8.8.8.8:443"}}
+	if locs := GetConnectionGeoLocations(connections); len(locs) != 0 || GeoIPProviderName() != "none" {
+		t.Errorf("disabled = %v", locs)
+	}
+
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeMMDB(t, path, map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "DE", "names": map[string]interface{}{"en": "Germany"}},
+	})
+	p, err := NewGeoIPProvider(&config.Config{GeoIPProvider: "auto", GeoIPDatabase: path})
+	if err != nil || p.Name() != "mmdb" {
+		t.Fatalf("auto with a database = %v, %v", p, err)
+	}
+	defer p.(*MMDBProvider).Close()
+	SetGeoIPProvider(p)
+	connections = append(connections, NetworkConnection{ForeignAddr: "10.0.0.5:22"}, NetworkConnection{ForeignAddr: "8.8.8.8:53"})
+	if locs := GetConnectionGeoLocations(connections); len(locs) != 1 || locs["8.8.8.8"].CountryCode != "DE" {
+		t.Errorf("locations = %v", locs)
+	}
+}
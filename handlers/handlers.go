@@ -10,6 +10,8 @@ import (
 
 	"gpu-pro/config"
 	"gpu-pro/monitor"
+	"gpu-pro/mounts"
+	"gpu-pro/ratelimit"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
@@ -77,27 +79,6 @@ func RegisterHandlers(app *fiber.App, mon *monitor.GPUMonitor, cfg *config.Confi
 	monitorRunning := false
 	var monitorMu sync.Mutex
 
-	// API endpoint to get user's home directory
-	app.Get("/api/home-directory", func(c *fiber.Ctx) error {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			homeDir = "/" // Fallback to root if home directory cannot be determined
-		}
-		return c.JSON(fiber.Map{
-			"home": homeDir,
-		})
-	})
-
-	// API endpoint for fetching largest files from a specific directory
-	app.Get("/api/largest-files", func(c *fiber.Ctx) error {
-		directory := c.Query("directory", "/")
-		files := GetTopLargestFiles(10, directory)
-		return c.JSON(fiber.Map{
-			"directory": directory,
-			"files":     files,
-		})
-	})
-
 	// API endpoint to get alert thresholds
 	app.Get("/api/alert-thresholds", func(c *fiber.Ctx) error {
 		thresholds, err := loadAlertThresholds()
@@ -143,8 +124,9 @@ func RegisterHandlers(app *fiber.App, mon *monitor.GPUMonitor, cfg *config.Confi
 		})
 	})
 
-	// WebSocket endpoint
-	app.Get("/socket.io/", websocket.New(func(c *websocket.Conn) {
+	// WebSocket endpoint, capped at WS_MAX_CLIENTS concurrent clients
+	wsLimit := ratelimit.NewConnLimiter(cfg.WSMaxClients)
+	app.Get("/socket.io/", wsLimit.Upgrade(), websocket.New(wsLimit.Handler(func(c *websocket.Conn) {
 		wsClients.Add(c)
 		log.Println("Dashboard client connected")
 
@@ -170,22 +152,13 @@ func RegisterHandlers(app *fiber.App, mon *monitor.GPUMonitor, cfg *config.Confi
 		}
 
 		wsClients.Remove(c)
-	}))
+	})))
 }
 
 // sendInitialData sends immediate data to a newly connected client to clear loading state
 func sendInitialData(mon *monitor.GPUMonitor, conn *websocket.Conn, cfg *config.Config) {
 	// Collect initial data (will be empty if no GPU)
-	gpuData, _ := mon.GetGPUData()
-	processes, _ := mon.GetProcesses()
-
-	// Ensure we have valid empty structures if nil
-	if gpuData == nil {
-		gpuData = make(map[string]interface{})
-	}
-	if processes == nil {
-		processes = []map[string]interface{}{}
-	}
+	gpuData, processes := collectGPUState(mon)
 
 	// Get system info
 	// Use 1s interval for CPU to get reliable reading on macOS
@@ -228,6 +201,7 @@ func sendInitialData(mon *monitor.GPUMonitor, conn *websocket.Conn, cfg *config.
 	response := map[string]interface{}{
 		"mode":           cfg.Mode,
 		"node_name":      cfg.NodeName,
+		"labels":         cfg.NodeLabels,
 		"gpus":           gpuData,
 		"processes":      processes,
 		"system":         systemInfo,
@@ -261,98 +235,94 @@ func monitorLoop(mon *monitor.GPUMonitor, wsClients *WebSocketClients, cfg *conf
 			continue
 		}
 
-		// Collect GPU data and processes (will return empty if not initialized)
-		var gpuData map[string]interface{}
-		var processes []map[string]interface{}
+		response := buildPayload(mon, cfg)
 
-		gpuData, _ = mon.GetGPUData()
-		processes, _ = mon.GetProcesses()
-
-		// Ensure we have valid empty structures if nil
-		if gpuData == nil {
-			gpuData = make(map[string]interface{})
-		}
-		if processes == nil {
-			processes = []map[string]interface{}{}
+		// Send to all connected clients
+		data, err := json.Marshal(response)
+		if err != nil {
+			log.Printf("Error marshaling response: %v", err)
+			continue
 		}
 
-		// Get system info
-		// Use 1s interval for CPU to get reliable reading on macOS
-		// First call initializes baseline, subsequent calls return actual values
-		cpuPercent, err := cpu.Percent(1*time.Second, false)
-		if err != nil || len(cpuPercent) == 0 {
-			// Fallback: try again with 500ms
-			cpuPercent, _ = cpu.Percent(500*time.Millisecond, false)
-		}
+		wsClients.Broadcast(data)
+	}
+}
 
-		memInfo, _ := mem.VirtualMemory()
+// buildPayload collects GPU, process and system data into the payload streamed to dashboards and hubs
+func buildPayload(mon *monitor.GPUMonitor, cfg *config.Config) map[string]interface{} {
+	// Collect GPU data and processes (will return empty if not initialized)
+	gpuData, processes := collectGPUState(mon)
 
-		systemInfo := map[string]interface{}{
-			"cpu_percent":    0.0,
-			"memory_percent": 0.0,
-			"disk_percent":   0.0,
-			"disk_read_rate": 0.0,
-			"disk_write_rate": 0.0,
-			"timestamp":      time.Now().Format(time.RFC3339),
-		}
+	// Get system info
+	// Use 1s interval for CPU to get reliable reading on macOS
+	// First call initializes baseline, subsequent calls return actual values
+	cpuPercent, err := cpu.Percent(1*time.Second, false)
+	if err != nil || len(cpuPercent) == 0 {
+		// Fallback: try again with 500ms
+		cpuPercent, _ = cpu.Percent(500*time.Millisecond, false)
+	}
 
-		if len(cpuPercent) > 0 && cpuPercent[0] > 0 {
-			systemInfo["cpu_percent"] = cpuPercent[0]
-		}
-		if memInfo != nil {
-			systemInfo["memory_percent"] = memInfo.UsedPercent
-		}
+	memInfo, _ := mem.VirtualMemory()
 
-		// Get disk usage for root partition
-		// Use platform-appropriate path (/ for Unix, C:\ for Windows)
-		diskPath := "/"
-		if runtime.GOOS == "windows" {
-			diskPath = "C:\\"
-		}
-		diskUsage, err := disk.Usage(diskPath)
-		if err == nil {
-			systemInfo["disk_percent"] = diskUsage.UsedPercent
-			systemInfo["disk_used"] = float64(diskUsage.Used) / (1024 * 1024 * 1024)  // GB
-			systemInfo["disk_total"] = float64(diskUsage.Total) / (1024 * 1024 * 1024) // GB
-
-		// Get system fan speeds (Linux only)
-		fans := getSystemFanSpeeds()
-		if len(fans) > 0 {
-			systemInfo["system_fans"] = fans
-			avgRPM := getAverageFanSpeed(fans)
-			maxRPM := getMaxFanSpeed(fans)
-			// Calculate percentage (assuming max RPM of 3000 or actual max seen)
-			maxReference := maxRPM
-			if maxReference < 3000 {
-				maxReference = 3000
-			}
-			systemInfo["system_fan_speed"] = float64(avgRPM)
-			systemInfo["system_fan_percent"] = (float64(avgRPM) / float64(maxReference)) * 100
-		}
-		}
+	systemInfo := map[string]interface{}{
+		"cpu_percent":    0.0,
+		"memory_percent": 0.0,
+		"disk_percent":   0.0,
+		"disk_read_rate": 0.0,
+		"disk_write_rate": 0.0,
+		"timestamp":      time.Now().Format(time.RFC3339),
+	}
 
-		// Get extended system metrics (network I/O, disk I/O, connections, large files)
-		systemMetrics := GetSystemMetrics()
-
-		// Build response
-		response := map[string]interface{}{
-			"mode":           cfg.Mode,
-			"node_name":      cfg.NodeName,
-			"gpus":           gpuData,
-			"processes":      processes,
-			"system":         systemInfo,
-			"system_metrics": systemMetrics,
-		}
+	if len(cpuPercent) > 0 && cpuPercent[0] > 0 {
+		systemInfo["cpu_percent"] = cpuPercent[0]
+	}
+	if memInfo != nil {
+		systemInfo["memory_percent"] = memInfo.UsedPercent
+	}
 
-		// Send to all connected clients
-		data, err := json.Marshal(response)
-		if err != nil {
-			log.Printf("Error marshaling response: %v", err)
-			continue
+	// Get disk usage for root partition
+	// Use platform-appropriate path (/ for Unix, C:\ for Windows)
+	diskPath := "/"
+	if runtime.GOOS == "windows" {
+		diskPath = "C:\\"
+	}
+	diskUsage, err := disk.Usage(diskPath)
+	if err == nil {
+		systemInfo["disk_percent"] = diskUsage.UsedPercent
+		systemInfo["disk_used"] = float64(diskUsage.Used) / (1024 * 1024 * 1024)  // GB
+		systemInfo["disk_total"] = float64(diskUsage.Total) / (1024 * 1024 * 1024) // GB
+
+	// Get system fan speeds (Linux only)
+	fans := getSystemFanSpeeds()
+	if len(fans) > 0 {
+		systemInfo["system_fans"] = fans
+		avgRPM := getAverageFanSpeed(fans)
+		maxRPM := getMaxFanSpeed(fans)
+		// Calculate percentage (assuming max RPM of 3000 or actual max seen)
+		maxReference := maxRPM
+		if maxReference < 3000 {
+			maxReference = 3000
 		}
+		systemInfo["system_fan_speed"] = float64(avgRPM)
+		systemInfo["system_fan_percent"] = (float64(avgRPM) / float64(maxReference)) * 100
+	}
+	}
 
-		wsClients.Broadcast(data)
+	// Get extended system metrics (network I/O, disk I/O, connections, large files)
+	systemMetrics := GetSystemMetrics()
+
+	// Build response
+	response := map[string]interface{}{
+		"mode":           cfg.Mode,
+		"node_name":      cfg.NodeName,
+		"labels":         cfg.NodeLabels,
+		"gpus":           gpuData,
+		"processes":      processes,
+		"system":         systemInfo,
+		"system_metrics": systemMetrics,
 	}
+
+	return response
 }
 
 // Alert Management Functions
@@ -366,6 +336,12 @@ func getDefaultThresholds() map[string]interface{} {
 		"memory_critical":  95,
 		"power_warning":    90,
 		"power_critical":   98,
+		"disk_warning":     mounts.DefaultRules.DiskWarning,
+		"disk_critical":    mounts.DefaultRules.DiskCritical,
+		"inodes_warning":   mounts.DefaultRules.InodesWarning,
+		"inodes_critical":  mounts.DefaultRules.InodesCritical,
+		"disk_full_hours_warning":  mounts.DefaultRules.FullWarning.Hours(),
+		"disk_full_hours_critical": mounts.DefaultRules.FullCritical.Hours(),
 	}
 }
 
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"gpu-pro/monitor"
+	"gpu-pro/placement"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterPlacementHandlers exposes the GPU finder for this node's GPUs
+func RegisterPlacementHandlers(app *fiber.App, mon *monitor.GPUMonitor, nodeName string) {
+	// GPUs ranked by availability with a recommended placement
+	// Query: count, memory (MiB or e.g. "24G"), same_node, nvlink
+	app.Get("/api/v1/free-gpus", func(c *fiber.Ctx) error {
+		req, err := placement.ParseRequest(c.Queries())
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		gpus, _ := collectGPUState(mon)
+		return c.JSON(placement.Rank([]placement.Node{{Name: nodeName, GPUs: gpus}}, req))
+	})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gpu-pro/auth"
+	"gpu-pro/monitor"
+	"gpu-pro/reservation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterReservationHandlers exposes GPU leases and their violations.
+// nodeName is the default node of new leases (empty on a hub, where the node is required).
+func RegisterReservationHandlers(app *fiber.App, store *reservation.Store, watcher *reservation.Watcher, nodeName string) {
+	// Current and upcoming leases with processes of other users on reserved GPUs
+	app.Get("/api/v1/reservations", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"reservations": store.List(),
+			"violations":   watcher.Violations(),
+		})
+	})
+
+	// Reserve GPUs: {"user", "node", "gpus": [uuid], "note", "start", "end" or "duration": "4h"}.
+	// "user" is the Unix account allowed on the GPUs. With authentication the lease is
+	// owned by the caller, whose name is also the default user.
+	app.Post("/api/v1/reservations", func(c *fiber.Ctx) error {
+		var req struct {
+			User     string    `json:"user"`
+			Node     string    `json:"node"`
+			GPUs     []string  `json:"gpus"`
+			Note     string    `json:"note"`
+			Start    time.Time `json:"start"`
+			End      time.Time `json:"end"`
+			Duration string    `json:"duration"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if req.Node == "" {
+			req.Node = nodeName
+		}
+		var owner string
+		if identity, ok := auth.IdentityFrom(c); ok {
+			owner = identity.Name
+			if req.User == "" {
+				req.User = identity.Name
+			}
+		}
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid duration",
+				})
+			}
+			start := req.Start
+			if start.IsZero() {
+				start = time.Now()
+			}
+			req.End = start.Add(d)
+		}
+
+		lease, err := store.Create(reservation.Lease{
+			User:  req.User,
+			Owner: owner,
+			Node:  req.Node,
+			GPUs:  req.GPUs,
+			Note:  req.Note,
+			Start: req.Start,
+			End:   req.End,
+		})
+		if err != nil {
+			return c.Status(reservationErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(201).JSON(lease)
+	})
+
+	// Extend a lease: {"end": RFC3339} or {"extend_by": "2h"}. Only its owner or an admin may.
+	app.Patch("/api/v1/reservations/:id", func(c *fiber.Ctx) error {
+		var req struct {
+			End      time.Time `json:"end"`
+			ExtendBy string    `json:"extend_by"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if err := authorizeLeaseChange(c, store, c.Params("id")); err != nil {
+			return c.Status(reservationErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		var lease reservation.Lease
+		var err error
+		if req.ExtendBy != "" {
+			d, parseErr := time.ParseDuration(req.ExtendBy)
+			if parseErr != nil {
+				return c.Status(400).JSON(fiber.Map{
+					"error": "Invalid extend_by duration",
+				})
+			}
+			lease, err = store.ExtendBy(c.Params("id"), d)
+		} else {
+			lease, err = store.Extend(c.Params("id"), req.End)
+		}
+		if err != nil {
+			return c.Status(reservationErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(lease)
+	})
+
+	// Release a lease. Only its owner or an admin may.
+	app.Delete("/api/v1/reservations/:id", func(c *fiber.Ctx) error {
+		if err := authorizeLeaseChange(c, store, c.Params("id")); err != nil {
+			return c.Status(reservationErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err := store.Release(c.Params("id")); err != nil {
+			return c.Status(reservationErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"success": true,
+		})
+	})
+}
+
+// errNotLeaseOwner is returned when a caller changes someone else's lease
+var errNotLeaseOwner = errors.New("only the owner of a lease or an admin can change it")
+
+// authorizeLeaseChange returns an error unless the authenticated caller owns the lease
+// or is an admin. Without authentication everyone may change every lease.
+func authorizeLeaseChange(c *fiber.Ctx, store *reservation.Store, id string) error {
+	identity, ok := auth.IdentityFrom(c)
+	if !ok {
+		return nil
+	}
+	lease, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+	if identity.Role < auth.RoleAdmin && !lease.OwnedBy(identity.Name) {
+		owner := lease.Owner
+		if owner == "" {
+			owner = lease.User
+		}
+		return fmt.Errorf("%w (lease %s belongs to %s)", errNotLeaseOwner, id, owner)
+	}
+	return nil
+}
+
+// LocalProcessSource reports this node's GPU processes to a reservation watcher
+func LocalProcessSource(mon *monitor.GPUMonitor, nodeName string) reservation.ProcessSource {
+	return func() map[string][]map[string]interface{} {
+		_, processes := collectGPUState(mon)
+		return map[string][]map[string]interface{}{nodeName: processes}
+	}
+}
+
+// reservationErrorStatus maps reservation errors to HTTP status codes
+func reservationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errNotLeaseOwner):
+		return 403
+	case errors.Is(err, reservation.ErrLeaseNotFound):
+		return 404
+	case errors.Is(err, reservation.ErrConflict):
+		return 409
+	case errors.Is(err, reservation.ErrInvalidLease):
+		return 400
+	default:
+		return 500
+	}
+}
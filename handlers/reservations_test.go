@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gpu-pro/auth"
+	"gpu-pro/reservation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestReservationOwnership(t *testing.T) {
+	a, err := auth.NewAuthenticator([]string{"alice:alice-token", "bob:bob-token", "root:root-token"}, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetRoles(map[string]string{"root": "admin"}, "operator"); err != nil {
+		t.Fatal(err)
+	}
+	store := reservation.NewStore(filepath.Join(t.TempDir(), "gpu-reservations.json"))
+	watcher := reservation.NewWatcher(store, "", func() map[string][]map[string]interface{} { return nil })
+	app := fiber.New()
+	app.Use(auth.Middleware(a))
+	RegisterReservationHandlers(app, store, watcher, "node1")
+
+	do := func(method, path, token, body string) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&r)
+		return resp.StatusCode, r
+	}
+
+	// The lease belongs to the caller, whatever user the request names
+	status, lease := do("POST", "/api/v1/reservations", "alice-token", `{"user":"bob","gpus":["GPU-aaaa"],"duration":"1h"}`)
+	if status != 201 || lease["owner"] != "alice" || lease["user"] != "bob" {
+		t.Fatalf("create = %d %v, want 201 owned by alice for user bob", status, lease)
+	}
+	path := "/api/v1/reservations/" + lease["id"].(string)
+
+	// Other operators can neither extend nor release it
+	if status, r := do("PATCH", path, "bob-token", `{"extend_by":"1h"}`); status != 403 {
+		t.Errorf("extend by another user = %d %v, want 403", status, r)
+	}
+	if status, r := do("DELETE", path, "bob-token", ""); status != 403 {
+		t.Errorf("release by another user = %d %v, want 403", status, r)
+	}
+	if status, _ := do("DELETE", "/api/v1/reservations/missing", "bob-token", ""); status != 404 {
+		t.Errorf("release of a missing lease = %d, want 404", status)
+	}
+
+	// The owner and admins can
+	if status, r := do("PATCH", path, "alice-token", `{"extend_by":"1h"}`); status != 200 {
+		t.Errorf("extend by the owner = %d %v, want 200", status, r)
+	}
+	if status, r := do("DELETE", path, "root-token", ""); status != 200 {
+		t.Errorf("release by an admin = %d %v, want 200", status, r)
+	}
+	if got := len(store.List()); got != 0 {
+		t.Errorf("%d leases left after release, want 0", got)
+	}
+}
+
+func TestReservationUserForTokenCaller(t *testing.T) {
+	a, err := auth.NewAuthenticator([]string{"ci-runner:ci-token"}, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetRoles(nil, "operator"); err != nil {
+		t.Fatal(err)
+	}
+	store := reservation.NewStore(filepath.Join(t.TempDir(), "gpu-reservations.json"))
+	watcher := reservation.NewWatcher(store, "", func() map[string][]map[string]interface{} { return nil })
+	app := fiber.New()
+	app.Use(auth.Middleware(a))
+	RegisterReservationHandlers(app, store, watcher, "node1")
+
+	// A token caller reserves GPUs for the Unix account its jobs run as
+	req := httptest.NewRequest("POST", "/api/v1/reservations", strings.NewReader(`{"user":"alice","gpus":["GPU-aaaa"],"duration":"1h"}`))
+	req.Header.Set("Authorization", "Bearer ci-token")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lease reservation.Lease
+	json.NewDecoder(resp.Body).Decode(&lease)
+	if resp.StatusCode != 201 || lease.Owner != "ci-runner" || lease.User != "alice" {
+		t.Fatalf("create = %d %+v, want 201 owned by ci-runner for user alice", resp.StatusCode, lease)
+	}
+
+	processes := []map[string]interface{}{
+		{"gpu_uuid": "GPU-aaaa", "user": "alice", "pid": 100},
+		{"gpu_uuid": "GPU-aaaa", "user": "bob", "pid": 200},
+	}
+	violations := store.Check("node1", processes, time.Now())
+	if len(violations) != 1 || violations[0].User != "bob" {
+		t.Errorf("violations = %+v, want only bob's process", violations)
+	}
+}
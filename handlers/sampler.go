@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"gpu-pro/monitor"
+)
+
+// collectMu serializes GPU collection between the dashboard loop and the background sampler,
+// since the monitor annotates its cached GPU maps from GetProcesses
+var collectMu sync.Mutex
+
+// collectGPUState collects GPU data and processes, returning copies safe to use from other goroutines
+func collectGPUState(mon *monitor.GPUMonitor) (map[string]interface{}, []map[string]interface{}) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+
+	gpuData, _ := mon.GetGPUData()
+	processes, _ := mon.GetProcesses()
+
+	gpus := make(map[string]interface{}, len(gpuData))
+	for gpuID, gpu := range gpuData {
+		if data, ok := gpu.(map[string]interface{}); ok {
+			copied := make(map[string]interface{}, len(data))
+			for k, v := range data {
+				copied[k] = v
+			}
+			gpus[gpuID] = copied
+		} else {
+			gpus[gpuID] = gpu
+		}
+	}
+
+	if processes == nil {
+		processes = []map[string]interface{}{}
+	}
+	return gpus, processes
+}
+
+// SampleFunc consumes one background sample of GPU state covering the given interval
+type SampleFunc func(gpus map[string]interface{}, processes []map[string]interface{}, interval time.Duration, at time.Time)
+
+// Sampler collects GPU state at a fixed interval regardless of connected dashboards
+// and hands every sample to its consumers (accounting, energy tracking, ...)
+type Sampler struct {
+	mon       *monitor.GPUMonitor
+	interval  time.Duration
+	consumers []SampleFunc
+	mu        sync.RWMutex
+	stopChan  chan bool
+	isRunning bool
+}
+
+// NewSampler creates a background sampler
+func NewSampler(mon *monitor.GPUMonitor, interval time.Duration) *Sampler {
+	return &Sampler{
+		mon:      mon,
+		interval: interval,
+		stopChan: make(chan bool),
+	}
+}
+
+// Add registers a sample consumer
+func (s *Sampler) Add(fn SampleFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.consumers = append(s.consumers, fn)
+}
+
+// Start begins sampling in the background
+func (s *Sampler) Start() {
+	if s.isRunning {
+		return
+	}
+	s.isRunning = true
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		for {
+			select {
+			case now := <-ticker.C:
+				// Never credit more than two intervals, e.g. after the host was suspended
+				elapsed := now.Sub(last)
+				if elapsed > 2*s.interval {
+					elapsed = 2 * s.interval
+				}
+				last = now
+
+				gpus, processes := collectGPUState(s.mon)
+
+				s.mu.RLock()
+				consumers := s.consumers
+				s.mu.RUnlock()
+				for _, fn := range consumers {
+					fn(gpus, processes, elapsed, now)
+				}
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops sampling
+func (s *Sampler) Stop() {
+	if !s.isRunning {
+		return
+	}
+	s.isRunning = false
+	close(s.stopChan)
+}
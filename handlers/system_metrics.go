@@ -2,13 +2,12 @@ package handlers
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"os/user"
-	"path/filepath"
 	"runtime"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -570,67 +569,24 @@ func getConnectionsWithSS() ([]NetworkConnection, ConnectionStats) {
 	return connections, stats
 }
 
-// GetTopLargestFiles finds the top N largest files in a specified directory
+// GetTopLargestFiles finds the top N largest files in a specified directory.
+// It walks the whole tree synchronously; the API uses sandboxed background scans instead.
 func GetTopLargestFiles(n int, directory string) []LargeFile {
-	files := []LargeFile{}
-
 	// If no directory specified, use user's home directory
 	if directory == "" {
 		usr, err := user.Current()
 		if err != nil {
-			return files
+			return []LargeFile{}
 		}
 		directory = usr.HomeDir
 	}
 
-	// Collect all files with their sizes
-	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip files we can't access
-		}
-
-		// Skip directories and hidden files/directories
-		if info.IsDir() || strings.HasPrefix(filepath.Base(path), ".") {
-			if info.IsDir() && strings.HasPrefix(filepath.Base(path), ".") {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		// Get apparent size
-		apparentSize := info.Size()
-
-		// Get actual disk usage (platform-specific)
-		actualSize, isSparse := getActualFileSize(info, apparentSize)
-
-		files = append(files, LargeFile{
-			Path:           path,
-			Size:           apparentSize,
-			SizeHuman:      formatBytes(apparentSize),
-			ActualSize:     actualSize,
-			ActualSizeHuman: formatBytes(actualSize),
-			IsSparse:       isSparse,
-			ModTime:        info.ModTime().Format("2006-01-02 15:04:05"),
-		})
-
-		return nil
-	})
-	
+	// Sort by actual disk usage descending (not apparent size)
+	// This gives a more accurate view of what's consuming disk space
+	files, err := scanLargestFiles(context.Background(), directory, n, ScanLimits{}, &scanProgress{})
 	if err != nil {
 		return []LargeFile{}
 	}
-	
-	// Sort by actual disk usage descending (not apparent size)
-	// This gives a more accurate view of what's consuming disk space
-	sort.Slice(files, func(i, j int) bool {
-		return files[i].ActualSize > files[j].ActualSize
-	})
-	
-	// Return top N
-	if len(files) > n {
-		files = files[:n]
-	}
-	
 	return files
 }
 
@@ -787,9 +743,11 @@ func GetSystemMetrics() map[string]interface{} {
 	return map[string]interface{}{
 		"network_io":       GetNetworkIO(),
 		"disk_io":          GetDiskIO(),
+		"filesystems":      getFilesystems(),
 		"connections":      connections,
 		"connection_stats": connStats,
 		"geo_locations":    geoLocs,
+		"geo_provider":     GeoIPProviderName(),
 		"open_files":       0,                // Skip for now - can be slow on macOS
 		"largest_files":    []interface{}{}, // Skip for now - scanning / is very slow
 		"timestamp":        time.Now().Format(time.RFC3339),
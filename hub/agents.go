@@ -0,0 +1,83 @@
+package hub
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// agentScheme prefixes the pseudo URL push-mode agents are registered under
+const agentScheme = "agent://"
+
+// validAgentName matches the node names agents may register under. They are shown on
+// every hub dashboard, so only plain host-like names are accepted.
+var validAgentName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,63}$`)
+
+// ErrAgentNameInUse is returned when another agent already has a live session under the node name
+var ErrAgentNameInUse = errors.New("another agent is connected under this node name")
+
+func agentURL(nodeName string) string {
+	return agentScheme + nodeName
+}
+
+func isAgentURL(url string) bool {
+	return strings.HasPrefix(url, agentScheme)
+}
+
+// attachAgent registers a push-mode agent session. A live session of the node is only
+// replaced by the same agent instance reconnecting; other agents get ErrAgentNameInUse.
+// The returned channel is closed when the session should be torn down (replaced or node removed).
+func (h *Hub) attachAgent(nodeName, instance string) (string, chan struct{}, error) {
+	url := agentURL(nodeName)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if stop, ok := h.stopChans[url]; ok {
+		if instance == "" || h.agentInstances[url] != instance {
+			return "", nil, ErrAgentNameInUse
+		}
+		log.Printf("Agent %s reconnected, closing previous session", nodeName)
+		close(stop)
+	} else if _, exists := h.findURLLocked(url); !exists {
+		h.addNodeLocked(url, "")
+	}
+
+	stop := make(chan struct{})
+	h.stopChans[url] = stop
+	h.agentInstances[url] = instance
+	if node, ok := h.nodes[h.urlToNode[url]]; ok {
+		node.mu.Lock()
+		node.connectedAt = time.Now()
+		node.mu.Unlock()
+	}
+	return url, stop, nil
+}
+
+// agentNameTaken reports whether another agent instance has a live session under the node name
+func (h *Hub) agentNameTaken(nodeName, instance string) bool {
+	url := agentURL(nodeName)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, live := h.stopChans[url]
+	return live && (instance == "" || h.agentInstances[url] != instance)
+}
+
+// detachAgent ends an agent session and marks the node offline, unless a newer session replaced it
+func (h *Hub) detachAgent(url string, stop chan struct{}, cause error) {
+	h.mu.Lock()
+	current, ok := h.stopChans[url]
+	if !ok || current != stop {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.stopChans, url)
+	delete(h.agentInstances, url)
+	close(stop)
+	h.mu.Unlock()
+
+	h.markNodeOffline(url, cause)
+}
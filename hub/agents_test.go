@@ -0,0 +1,142 @@
+package hub
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gpu-pro/config"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gorilla/websocket"
+)
+
+// startHubServer serves the hub handlers on a random local port and returns its ws:// base URL
+func startHubServer(t *testing.T, h *Hub, token string) string {
+	t.Helper()
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	RegisterHubHandlers(app, h, &config.Config{AgentToken: token})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+
+	return "ws://" + ln.Addr().String()
+}
+
+func dialAgent(base, node, token string) (*websocket.Conn, *http.Response, error) {
+	return dialAgentInstance(base, node, token, "")
+}
+
+func dialAgentInstance(base, node, token, instance string) (*websocket.Conn, *http.Response, error) {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	if instance != "" {
+		header.Set("X-Agent-Instance", instance)
+	}
+	return websocket.DefaultDialer.Dial(base+"/api/v1/agents/connect?node="+url.QueryEscape(node), header)
+}
+
+func TestAgentAuthentication(t *testing.T) {
+	base := startHubServer(t, newHub(nil, ""), "s3cret")
+
+	if _, resp, err := dialAgent(base, "worker-1", "wrong"); err == nil || resp == nil || resp.StatusCode != 401 {
+		t.Errorf("wrong token: err=%v resp=%v, want HTTP 401", err, resp)
+	}
+	if _, resp, err := dialAgent(base, "worker-1", ""); err == nil || resp == nil || resp.StatusCode != 401 {
+		t.Errorf("missing token: err=%v resp=%v, want HTTP 401", err, resp)
+	}
+
+	disabled := startHubServer(t, newHub(nil, ""), "")
+	if _, resp, err := dialAgent(disabled, "worker-1", "anything"); err == nil || resp == nil || resp.StatusCode != 403 {
+		t.Errorf("hub without token: err=%v resp=%v, want HTTP 403", err, resp)
+	}
+}
+
+func TestAgentStreamsSamples(t *testing.T) {
+	h := newHub(nil, "")
+	base := startHubServer(t, h, "s3cret")
+
+	conn, _, err := dialAgent(base, "worker-1", "s3cret")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+
+	payload := `{"mode":"default","node_name":"worker-1","gpus":{"0":{"name":"A100","uuid":"GPU-1"}},"processes":[]}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(payload)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		nodes := h.ListNodes()
+		return len(nodes) == 1 && nodes[0]["name"] == "worker-1" && nodes[0]["status"] == "online" && nodes[0]["agent"] == true
+	})
+
+	stats := h.GetClusterData()["cluster_stats"].(map[string]interface{})
+	if stats["total_gpus"] != 1 {
+		t.Errorf("total_gpus = %v, want 1", stats["total_gpus"])
+	}
+
+	conn.Close()
+	waitFor(t, func() bool {
+		nodes := h.ListNodes()
+		return len(nodes) == 1 && nodes[0]["status"] == "offline"
+	})
+}
+
+func TestAgentNodeNames(t *testing.T) {
+	h := newHub(nil, "")
+	base := startHubServer(t, h, "s3cret")
+
+	for _, name := range []string{`"><img src=x onerror=alert(1)>`, "gpu 01", strings.Repeat("a", 64)} {
+		if _, resp, err := dialAgent(base, name, "s3cret"); err == nil || resp == nil || resp.StatusCode != 400 {
+			t.Errorf("node %q: err=%v resp=%v, want HTTP 400", name, err, resp)
+		}
+	}
+
+	first, _, err := dialAgentInstance(base, "worker-1", "s3cret", "aaaa")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer first.Close()
+
+	// A payload name that is not a plain node name is not shown
+	payload := `{"mode":"default","node_name":"<b>x</b>","gpus":{},"processes":[]}`
+	if err := first.WriteMessage(websocket.TextMessage, []byte(payload)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	waitFor(t, func() bool {
+		nodes := h.ListNodes()
+		return len(nodes) == 1 && nodes[0]["status"] == "online"
+	})
+	if name := h.ListNodes()[0]["name"]; name == "<b>x</b>" {
+		t.Errorf("node shown as %q", name)
+	}
+
+	// Other agents cannot take over the live session
+	for _, instance := range []string{"", "bbbb"} {
+		if _, resp, err := dialAgentInstance(base, "worker-1", "s3cret", instance); err == nil || resp == nil || resp.StatusCode != 409 {
+			t.Errorf("instance %q: err=%v resp=%v, want HTTP 409", instance, err, resp)
+		}
+	}
+
+	// The same agent reconnecting replaces it
+	second, _, err := dialAgentInstance(base, "worker-1", "s3cret", "aaaa")
+	if err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+	defer second.Close()
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := first.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("previous session read = %v, want it closed after the agent reconnected", err)
+	}
+}
@@ -0,0 +1,164 @@
+package hub
+
+import "math"
+
+// idleUtilization is the utilization (%) below which a GPU without compute processes counts as idle
+const idleUtilization = 5.0
+
+// aggregate accumulates statistics over a set of GPUs
+type aggregate struct {
+	gpus        int
+	idleGPUs    int
+	memoryUsed  float64 // MiB
+	memoryTotal float64 // MiB
+	utilSum     float64
+	utilMax     float64
+	utilCount   int
+	tempSum     float64
+	tempMax     float64
+	tempCount   int
+	powerDraw   float64
+}
+
+// add accounts one GPU entry of a node payload
+func (a *aggregate) add(gpu map[string]interface{}) {
+	a.gpus++
+
+	if v, ok := gpu["memory_used"].(float64); ok {
+		a.memoryUsed += v
+	}
+	if v, ok := gpu["memory_total"].(float64); ok {
+		a.memoryTotal += v
+	}
+
+	util, hasUtil := gpu["utilization"].(float64)
+	if hasUtil {
+		a.utilSum += util
+		a.utilMax = math.Max(a.utilMax, util)
+		a.utilCount++
+	}
+
+	if v, ok := gpu["temperature"].(float64); ok {
+		a.tempSum += v
+		a.tempMax = math.Max(a.tempMax, v)
+		a.tempCount++
+	}
+
+	if v, ok := gpu["power_draw"].(float64); ok {
+		a.powerDraw += v
+	}
+
+	processes, _ := gpu["compute_processes_count"].(float64)
+	if hasUtil && util < idleUtilization && processes == 0 {
+		a.idleGPUs++
+	}
+}
+
+// result returns the aggregated statistics
+func (a *aggregate) result() map[string]interface{} {
+	result := map[string]interface{}{
+		"gpus":             a.gpus,
+		"idle_gpus":        a.idleGPUs,
+		"memory_used":      a.memoryUsed,
+		"memory_total":     a.memoryTotal,
+		"memory_percent":   0.0,
+		"utilization_avg":  0.0,
+		"utilization_max":  a.utilMax,
+		"temperature_avg":  0.0,
+		"temperature_max":  a.tempMax,
+		"power_draw_total": a.powerDraw,
+	}
+	if a.memoryTotal > 0 {
+		result["memory_percent"] = a.memoryUsed / a.memoryTotal * 100
+	}
+	if a.utilCount > 0 {
+		result["utilization_avg"] = a.utilSum / float64(a.utilCount)
+	}
+	if a.tempCount > 0 {
+		result["temperature_avg"] = a.tempSum / float64(a.tempCount)
+	}
+	return result
+}
+
+// clusterAggregates accumulates cluster totals plus breakdowns by GPU model and node label
+type clusterAggregates struct {
+	total   aggregate
+	byModel map[string]*aggregate
+	byLabel map[string]map[string]*aggregate // label key -> label value -> aggregate
+}
+
+func newClusterAggregates() *clusterAggregates {
+	return &clusterAggregates{
+		byModel: make(map[string]*aggregate),
+		byLabel: make(map[string]map[string]*aggregate),
+	}
+}
+
+// addNode accounts all GPUs of one node payload
+func (c *clusterAggregates) addNode(gpus map[string]interface{}, labels map[string]string) {
+	for _, gpu := range gpus {
+		data, ok := gpu.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		c.total.add(data)
+
+		model, _ := data["name"].(string)
+		if model == "" {
+			model = "Unknown"
+		}
+		if c.byModel[model] == nil {
+			c.byModel[model] = &aggregate{}
+		}
+		c.byModel[model].add(data)
+
+		for key, value := range labels {
+			if c.byLabel[key] == nil {
+				c.byLabel[key] = make(map[string]*aggregate)
+			}
+			if c.byLabel[key][value] == nil {
+				c.byLabel[key][value] = &aggregate{}
+			}
+			c.byLabel[key][value].add(data)
+		}
+	}
+}
+
+// result returns totals with "by_model" and "by_label" breakdowns
+func (c *clusterAggregates) result() map[string]interface{} {
+	result := c.total.result()
+
+	byModel := make(map[string]interface{}, len(c.byModel))
+	for model, agg := range c.byModel {
+		byModel[model] = agg.result()
+	}
+	result["by_model"] = byModel
+
+	byLabel := make(map[string]interface{}, len(c.byLabel))
+	for key, values := range c.byLabel {
+		byValue := make(map[string]interface{}, len(values))
+		for value, agg := range values {
+			byValue[value] = agg.result()
+		}
+		byLabel[key] = byValue
+	}
+	result["by_label"] = byLabel
+
+	return result
+}
+
+// nodeLabels extracts the labels a node advertises in its payload
+func nodeLabels(data map[string]interface{}) map[string]string {
+	labels := make(map[string]string)
+	raw, ok := data["labels"].(map[string]interface{})
+	if !ok {
+		return labels
+	}
+	for key, value := range raw {
+		if s, ok := value.(string); ok {
+			labels[key] = s
+		}
+	}
+	return labels
+}
@@ -0,0 +1,64 @@
+package hub
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClusterAggregates(t *testing.T) {
+	h := newHub(nil, "")
+
+	payloads := map[string]map[string]interface{}{
+		"node-a": {
+			"node_name": "node-a",
+			"labels":    map[string]interface{}{"rack": "r1"},
+			"gpus": map[string]interface{}{
+				"0": map[string]interface{}{"name": "A100", "utilization": 90.0, "temperature": 70.0, "memory_used": 30000.0, "memory_total": 40000.0, "power_draw": 300.0, "compute_processes_count": 1.0},
+				"1": map[string]interface{}{"name": "A100", "utilization": 0.0, "temperature": 40.0, "memory_used": 0.0, "memory_total": 40000.0, "power_draw": 50.0, "compute_processes_count": 0.0},
+			},
+		},
+		"node-b": {
+			"node_name": "node-b",
+			"labels":    map[string]interface{}{"rack": "r2"},
+			"gpus": map[string]interface{}{
+				"0": map[string]interface{}{"name": "H100", "utilization": 30.0, "temperature": 60.0, "memory_used": 10000.0, "memory_total": 80000.0, "power_draw": 250.0, "compute_processes_count": 2.0},
+			},
+		},
+	}
+	for name, payload := range payloads {
+		url := agentURL(name)
+		h.attachAgent(name, "")
+		h.updateNode(url, payload)
+	}
+
+	agg := h.GetClusterData()["cluster_stats"].(map[string]interface{})["aggregates"].(map[string]interface{})
+
+	checks := map[string]float64{
+		"memory_used":      40000,
+		"memory_total":     160000,
+		"memory_percent":   25,
+		"utilization_avg":  40,
+		"utilization_max":  90,
+		"temperature_avg":  170.0 / 3,
+		"temperature_max":  70,
+		"power_draw_total": 600,
+	}
+	for key, want := range checks {
+		if got, _ := agg[key].(float64); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", key, agg[key], want)
+		}
+	}
+	if agg["gpus"] != 3 || agg["idle_gpus"] != 1 {
+		t.Errorf("gpus/idle_gpus = %v/%v, want 3/1", agg["gpus"], agg["idle_gpus"])
+	}
+
+	a100 := agg["by_model"].(map[string]interface{})["A100"].(map[string]interface{})
+	if a100["gpus"] != 2 || a100["power_draw_total"] != 350.0 {
+		t.Errorf("A100 breakdown = %v", a100)
+	}
+
+	r2 := agg["by_label"].(map[string]interface{})["rack"].(map[string]interface{})["r2"].(map[string]interface{})
+	if r2["gpus"] != 1 || r2["utilization_avg"] != 30.0 {
+		t.Errorf("rack=r2 breakdown = %v", r2)
+	}
+}
@@ -0,0 +1,163 @@
+package hub
+
+import (
+	"crypto/tls"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+// Connection tuning for node sessions
+const (
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 60 * time.Second
+	pingInterval      = 10 * time.Second
+	readTimeout       = 3 * pingInterval // No data or pong for this long drops the connection
+	writeTimeout      = 10 * time.Second
+
+	// DefaultStaleAfter marks an online node stale when it sent no data for this long
+	DefaultStaleAfter = 10 * time.Second
+)
+
+// WebSocket control message types (identical in gorilla and fasthttp websocket)
+const pingMessage = 9
+
+// backoff computes jittered exponential reconnect delays
+type backoff struct {
+	min, max time.Duration
+	attempt  int
+}
+
+func newBackoff(min, max time.Duration) *backoff {
+	return &backoff{min: min, max: max}
+}
+
+// Next returns the delay before the next attempt: a random duration between half and all of
+// min*2^attempt (capped at max), so many nodes failing together do not reconnect in lockstep
+func (b *backoff) Next() time.Duration {
+	ceiling := b.max
+	if b.attempt < 32 {
+		if d := b.min << b.attempt; d > 0 && d < b.max {
+			ceiling = d
+		}
+	}
+	b.attempt++
+
+	half := ceiling / 2
+	return half + rand.N(ceiling-half+1)
+}
+
+// Reset starts over from the minimum delay after a healthy session
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
+
+// pinger is the subset of gorilla and fasthttp websocket connections used for keepalives
+type pinger interface {
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetPongHandler(h func(appData string) error)
+	SetReadDeadline(t time.Time) error
+}
+
+// setPongHandler extends the read deadline on every pong and reports the round-trip time
+// of pings sent by pingLoop. It must be called before the connection is read from.
+func setPongHandler(conn pinger, onLatency func(time.Duration)) {
+	conn.SetPongHandler(func(appData string) error {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		if sent, err := strconv.ParseInt(appData, 10, 64); err == nil {
+			onLatency(time.Since(time.Unix(0, sent)))
+		}
+		return nil
+	})
+}
+
+// pingLoop pings the peer until done is closed or a ping cannot be written
+func pingLoop(conn pinger, done <-chan struct{}) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			payload := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+			if err := conn.WriteControl(pingMessage, payload, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// statusAt returns the node's status, reporting online nodes that stopped sending data as "stale" (n.mu must be held)
+func (n *NodeInfo) statusAt(now time.Time, staleAfter time.Duration) string {
+	if n.Status != "online" || staleAfter <= 0 {
+		return n.Status
+	}
+	last := n.lastData
+	if last.IsZero() {
+		last = n.connectedAt
+	}
+	if !last.IsZero() && now.Sub(last) > staleAfter {
+		return "stale"
+	}
+	return n.Status
+}
+
+// connectionStats returns per-node connection statistics (n.mu must be held)
+func (n *NodeInfo) connectionStats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"reconnects":      n.reconnects,
+		"last_error":      n.lastError,
+		"last_error_time": "",
+		"connected_since": "",
+		"latency_ms":      nil,
+	}
+	if !n.lastErrorAt.IsZero() {
+		stats["last_error_time"] = n.lastErrorAt.Format(time.RFC3339)
+	}
+	if !n.connectedAt.IsZero() && n.Status == "online" {
+		stats["connected_since"] = n.connectedAt.Format(time.RFC3339)
+	}
+	if n.latency > 0 {
+		stats["latency_ms"] = float64(n.latency.Microseconds()) / 1000
+	}
+	return stats
+}
+
+// nodeByURL returns the node registered under url
+func (h *Hub) nodeByURL(url string) *NodeInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.nodes[h.urlToNode[url]]
+}
+
+// recordLatency stores the latest ping round-trip time of a node
+func (h *Hub) recordLatency(url string, latency time.Duration) {
+	if node := h.nodeByURL(url); node != nil {
+		node.mu.Lock()
+		node.latency = latency
+		node.mu.Unlock()
+	}
+}
+
+// SetStaleAfter sets how long an online node may go without sending data before it is reported stale
+func (h *Hub) SetStaleAfter(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.staleAfter = d
+}
+
+// SetNodeToken sets the bearer token sent when dialing nodes and child hubs that require authentication
+func (h *Hub) SetNodeToken(token string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nodeToken = token
+}
+
+// SetTLSConfig sets the TLS configuration (client certificate, CA) used to dial https:// nodes
+func (h *Hub) SetTLSConfig(cfg *tls.Config) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tlsConfig = cfg
+}
@@ -0,0 +1,116 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestBackoffGrowsWithJitterAndResets(t *testing.T) {
+	b := newBackoff(time.Second, 8*time.Second)
+
+	ceilings := []time.Duration{1, 2, 4, 8, 8, 8}
+	for i, ceiling := range ceilings {
+		ceiling *= time.Second
+		d := b.Next()
+		if d < ceiling/2 || d > ceiling {
+			t.Errorf("attempt %d: delay %v outside [%v, %v]", i, d, ceiling/2, ceiling)
+		}
+	}
+
+	b.Reset()
+	if d := b.Next(); d > time.Second {
+		t.Errorf("delay after Reset = %v, want <= 1s", d)
+	}
+}
+
+func TestNodeStatusStale(t *testing.T) {
+	h := newHub(nil, "")
+	h.SetStaleAfter(time.Second)
+
+	url := agentURL("worker-1")
+	_, stop, _ := h.attachAgent("worker-1", "")
+	h.updateNode(url, map[string]interface{}{"node_name": "worker-1", "gpus": map[string]interface{}{}})
+
+	if status := h.ListNodes()[0]["status"]; status != "online" {
+		t.Fatalf("status = %v, want online", status)
+	}
+
+	// Pretend the last payload arrived long ago
+	node := h.nodeByURL(url)
+	node.mu.Lock()
+	node.lastData = time.Now().Add(-time.Minute)
+	node.mu.Unlock()
+
+	cluster := h.GetClusterData()
+	nodeData := cluster["nodes"].(map[string]interface{})["worker-1"].(map[string]interface{})
+	if nodeData["status"] != "stale" {
+		t.Errorf("cluster status = %v, want stale", nodeData["status"])
+	}
+	stats := cluster["cluster_stats"].(map[string]interface{})
+	if stats["stale_nodes"] != 1 || stats["online_nodes"] != 0 {
+		t.Errorf("cluster_stats = %v, want 1 stale and 0 online", stats)
+	}
+
+	h.detachAgent(url, stop, nil)
+	if status := h.ListNodes()[0]["status"]; status != "offline" {
+		t.Errorf("status after detach = %v, want offline", status)
+	}
+}
+
+func TestReconnectRecordsStatistics(t *testing.T) {
+	var sessions atomic.Int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// The first session drops right after one payload; later ones stay up
+		first := sessions.Add(1) == 1
+		for {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"node_name":"worker-1","gpus":{}}`)); err != nil {
+				return
+			}
+			if first {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	h := newHub(nil, "")
+	h.running = true
+	h.connecting = true
+	defer h.Shutdown()
+
+	if err := h.AddNode(server.URL, ""); err != nil {
+		t.Fatalf("AddNode: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		if sessions.Load() < 2 {
+			return false
+		}
+		nodes := h.ListNodes()
+		return len(nodes) == 1 && nodes[0]["status"] == "online"
+	})
+
+	stats := h.ListNodes()[0]["connection"].(map[string]interface{})
+	if stats["reconnects"].(int) < 1 {
+		t.Errorf("reconnects = %v, want >= 1", stats["reconnects"])
+	}
+	if stats["last_error"] == "" {
+		t.Error("last_error is empty after a dropped connection")
+	}
+	if stats["connected_since"] == "" {
+		t.Error("connected_since is empty for an online node")
+	}
+}
@@ -0,0 +1,346 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpu-pro/config"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// discoveryTimeout bounds a single discovery refresh
+const discoveryTimeout = 10 * time.Second
+
+// Discoverer finds node URLs from an external inventory
+type Discoverer interface {
+	// Source identifies the discoverer, e.g. "dns-srv:_gpupro._tcp.example.com"
+	Source() string
+	// Discover returns the current set of node URLs
+	Discover(ctx context.Context) ([]string, error)
+}
+
+// watcher is implemented by discoverers that can signal changes between periodic refreshes
+type watcher interface {
+	Watch(stop <-chan struct{}) <-chan struct{}
+}
+
+// Discovery periodically refreshes the hub's node set from its discoverers
+type Discovery struct {
+	hub         *Hub
+	discoverers []Discoverer
+	interval    time.Duration
+	stopChan    chan struct{}
+	isRunning   bool
+}
+
+// NewDiscovery creates a discovery loop for the hub
+func NewDiscovery(h *Hub, interval time.Duration, discoverers ...Discoverer) *Discovery {
+	return &Discovery{
+		hub:         h,
+		discoverers: discoverers,
+		interval:    interval,
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start refreshes every discoverer immediately and then periodically (or when its source changes)
+func (d *Discovery) Start() {
+	if d.isRunning {
+		return
+	}
+	d.isRunning = true
+
+	for _, discoverer := range d.discoverers {
+		go d.run(discoverer)
+	}
+}
+
+// Stop ends discovery; nodes found so far stay registered
+func (d *Discovery) Stop() {
+	if !d.isRunning {
+		return
+	}
+	d.isRunning = false
+	close(d.stopChan)
+}
+
+func (d *Discovery) run(discoverer Discoverer) {
+	var changes <-chan struct{}
+	if w, ok := discoverer.(watcher); ok {
+		changes = w.Watch(d.stopChan)
+	}
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.refresh(discoverer)
+
+		select {
+		case <-d.stopChan:
+			return
+		case <-ticker.C:
+		case <-changes:
+		}
+	}
+}
+
+func (d *Discovery) refresh(discoverer Discoverer) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+
+	urls, err := discoverer.Discover(ctx)
+	if err != nil {
+		// Keep the previous node set rather than dropping nodes on a transient failure
+		log.Printf("Node discovery via %s failed: %v", discoverer.Source(), err)
+		return
+	}
+
+	added, removed := d.hub.SyncDiscovered(discoverer.Source(), urls)
+	if added > 0 || removed > 0 {
+		log.Printf("Node discovery via %s: %d added, %d removed", discoverer.Source(), added, removed)
+	}
+}
+
+// SyncDiscovered makes the nodes found by a discovery source match urls,
+// returning how many nodes were added and removed. A node is removed once no source
+// lists it anymore; nodes registered otherwise are left alone.
+func (h *Hub) SyncDiscovered(source string, urls []string) (added, removed int) {
+	want := make(map[string]bool, len(urls))
+	for _, raw := range urls {
+		nodeURL, err := NormalizeNodeURL(raw)
+		if err != nil {
+			log.Printf("Ignoring target from %s: %v", source, err)
+			continue
+		}
+		want[nodeURL] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for nodeURL, sources := range h.discovered {
+		if sources[source] && !want[nodeURL] {
+			delete(sources, source)
+			if len(sources) == 0 {
+				h.removeNodeLocked(nodeURL)
+				removed++
+			}
+		}
+	}
+
+	for nodeURL := range want {
+		if sources, ok := h.discovered[nodeURL]; ok {
+			sources[source] = true
+			continue
+		}
+		if _, exists := h.findURLLocked(nodeURL); exists {
+			continue
+		}
+		h.discovered[nodeURL] = map[string]bool{source: true}
+		h.addNodeLocked(nodeURL, "")
+		added++
+	}
+	return added, removed
+}
+
+// DNSDiscoverer resolves node URLs from DNS SRV records or A/AAAA records
+type DNSDiscoverer struct {
+	name   string
+	srv    bool
+	port   int
+	scheme string
+
+	lookupSRV  func(ctx context.Context, name string) ([]*net.SRV, error)
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+// NewSRVDiscoverer discovers nodes from the SRV records of name (e.g. _gpupro._tcp.example.com)
+func NewSRVDiscoverer(name, scheme string) *DNSDiscoverer {
+	return &DNSDiscoverer{
+		name:   name,
+		srv:    true,
+		scheme: scheme,
+		lookupSRV: func(ctx context.Context, name string) ([]*net.SRV, error) {
+			_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+			return records, err
+		},
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+// NewADiscoverer discovers one node per address of host (host or host:port, default port config.DefaultPort)
+func NewADiscoverer(hostPort, scheme string) (*DNSDiscoverer, error) {
+	host, port := hostPort, config.DefaultPort
+	if h, p, err := net.SplitHostPort(hostPort); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in %q", hostPort)
+		}
+		host, port = h, n
+	}
+
+	return &DNSDiscoverer{
+		name:       host,
+		port:       port,
+		scheme:     scheme,
+		lookupHost: net.DefaultResolver.LookupHost,
+	}, nil
+}
+
+// Source implements Discoverer
+func (d *DNSDiscoverer) Source() string {
+	if d.srv {
+		return "dns-srv:" + d.name
+	}
+	return "dns-a:" + d.name
+}
+
+// Discover implements Discoverer
+func (d *DNSDiscoverer) Discover(ctx context.Context) ([]string, error) {
+	if d.srv {
+		records, err := d.lookupSRV(ctx, d.name)
+		if err != nil {
+			return nil, err
+		}
+		urls := make([]string, 0, len(records))
+		for _, record := range records {
+			target := strings.TrimSuffix(record.Target, ".")
+			urls = append(urls, nodeURL(d.scheme, target, int(record.Port)))
+		}
+		return urls, nil
+	}
+
+	addrs, err := d.lookupHost(ctx, d.name)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		urls = append(urls, nodeURL(d.scheme, addr, d.port))
+	}
+	return urls, nil
+}
+
+func nodeURL(scheme, host string, port int) string {
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// TargetGroup is one entry of a Prometheus file_sd style targets file
+type TargetGroup struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// FileDiscoverer reads node targets from a JSON or YAML file in Prometheus file_sd format.
+// Targets are host:port or full URLs; the "__scheme__" label overrides the default scheme.
+type FileDiscoverer struct {
+	path   string
+	scheme string
+}
+
+// NewFileDiscoverer creates a discoverer for the given targets file
+func NewFileDiscoverer(path, scheme string) *FileDiscoverer {
+	return &FileDiscoverer{path: path, scheme: scheme}
+}
+
+// Source implements Discoverer
+func (f *FileDiscoverer) Source() string {
+	return "file:" + f.path
+}
+
+// Discover implements Discoverer
+func (f *FileDiscoverer) Discover(ctx context.Context) ([]string, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []TargetGroup
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &groups)
+	default:
+		err = json.Unmarshal(data, &groups)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", f.path, err)
+	}
+
+	var urls []string
+	for _, group := range groups {
+		scheme := f.scheme
+		if s := group.Labels["__scheme__"]; s != "" {
+			scheme = s
+		}
+		for _, target := range group.Targets {
+			target = strings.TrimSpace(target)
+			if target == "" {
+				continue
+			}
+			if strings.Contains(target, "://") {
+				urls = append(urls, target)
+				continue
+			}
+			if _, _, err := net.SplitHostPort(target); err != nil {
+				target = net.JoinHostPort(target, strconv.Itoa(config.DefaultPort))
+			}
+			urls = append(urls, scheme+"://"+target)
+		}
+	}
+	return urls, nil
+}
+
+// Watch signals when the targets file changes. The directory is watched so that
+// files replaced atomically (as most editors and config management tools do) are noticed.
+func (f *FileDiscoverer) Watch(stop <-chan struct{}) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Cannot watch %s, relying on periodic refresh: %v", f.path, err)
+		return changes
+	}
+	if err := w.Add(filepath.Dir(f.path)); err != nil {
+		log.Printf("Cannot watch %s, relying on periodic refresh: %v", f.path, err)
+		w.Close()
+		return changes
+	}
+
+	go func() {
+		defer w.Close()
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != filepath.Clean(f.path) {
+					continue
+				}
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Error watching %s: %v", f.path, err)
+			}
+		}
+	}()
+	return changes
+}
@@ -0,0 +1,165 @@
+package hub
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestDNSDiscoverer(t *testing.T) {
+	srv := NewSRVDiscoverer("_gpupro._tcp.example.com", "http")
+	srv.lookupSRV = func(ctx context.Context, name string) ([]*net.SRV, error) {
+		return []*net.SRV{
+			{Target: "gpu01.example.com.", Port: 1312},
+			{Target: "gpu02.example.com.", Port: 8889},
+		}, nil
+	}
+	urls, err := srv.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("SRV Discover: %v", err)
+	}
+	want := []string{"http://gpu01.example.com:1312", "http://gpu02.example.com:8889"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("SRV urls = %v, want %v", urls, want)
+	}
+
+	a, err := NewADiscoverer("gpu-nodes.example.com", "https")
+	if err != nil {
+		t.Fatalf("NewADiscoverer: %v", err)
+	}
+	a.lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		return []string{"10.0.0.5", "fd00::5"}, nil
+	}
+	urls, err = a.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("A Discover: %v", err)
+	}
+	want = []string{"https://10.0.0.5:8889", "https://[fd00::5]:8889"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("A urls = %v, want %v", urls, want)
+	}
+
+	if _, err := NewADiscoverer("gpu-nodes:http", "http"); err == nil {
+		t.Error("NewADiscoverer accepted a non-numeric port")
+	}
+}
+
+func TestFileDiscovererFormats(t *testing.T) {
+	dir := t.TempDir()
+	want := []string{"http://gpu01:8889", "http://gpu02:9000", "https://gpu03:8889", "https://lab.example.com:8443"}
+
+	files := map[string]string{
+		"targets.json": `[
+			{"targets": ["gpu01", "gpu02:9000"]},
+			{"targets": ["gpu03", "https://lab.example.com:8443"], "labels": {"__scheme__": "https"}}
+		]`,
+		"targets.yaml": `
+- targets: [gpu01, "gpu02:9000"]
+- targets: [gpu03, "https://lab.example.com:8443"]
+  labels:
+    __scheme__: https
+`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		urls, err := NewFileDiscoverer(path, "http").Discover(context.Background())
+		if err != nil {
+			t.Fatalf("%s: Discover: %v", name, err)
+		}
+		sort.Strings(urls)
+		if !reflect.DeepEqual(urls, want) {
+			t.Errorf("%s: urls = %v, want %v", name, urls, want)
+		}
+	}
+}
+
+func TestSyncDiscovered(t *testing.T) {
+	h := newHub([]string{"http://static:1312"}, filepath.Join(t.TempDir(), "hub-nodes.json"))
+
+	added, removed := h.SyncDiscovered("file:targets.json", []string{"http://a:1312", "http://b:1312", "http://static:1312"})
+	if added != 2 || removed != 0 {
+		t.Errorf("first sync added/removed = %d/%d, want 2/0", added, removed)
+	}
+
+	added, removed = h.SyncDiscovered("file:targets.json", []string{"http://b:1312"})
+	if added != 0 || removed != 1 {
+		t.Errorf("second sync added/removed = %d/%d, want 0/1", added, removed)
+	}
+
+	// Statically registered nodes are never removed by discovery
+	added, removed = h.SyncDiscovered("file:targets.json", nil)
+	if added != 0 || removed != 1 {
+		t.Errorf("third sync added/removed = %d/%d, want 0/1", added, removed)
+	}
+	nodes := h.ListNodes()
+	if len(nodes) != 1 || nodes[0]["url"] != "http://static:1312" || nodes[0]["source"] != "registry" {
+		t.Errorf("nodes = %v, want only the static node", nodes)
+	}
+}
+
+func TestSyncDiscoveredSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hub-nodes.json")
+	h := newHub(nil, path)
+
+	h.SyncDiscovered("dns-srv:_gpupro._tcp", []string{"http://a:1312"})
+	h.SyncDiscovered("file:targets.json", []string{"http://a:1312"})
+	if nodes := h.ListNodes(); len(nodes) != 1 || nodes[0]["source"] != "dns-srv:_gpupro._tcp,file:targets.json" {
+		t.Fatalf("nodes = %v, want one node from both sources", nodes)
+	}
+
+	// A node stays while any source still lists it
+	if _, removed := h.SyncDiscovered("dns-srv:_gpupro._tcp", nil); removed != 0 || len(h.ListNodes()) != 1 {
+		t.Errorf("node removed while the file still lists it")
+	}
+
+	// Its display name is kept across restarts and rediscovery
+	if err := h.RenameNode("http://a:1312", "trainer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, removed := h.SyncDiscovered("file:targets.json", nil); removed != 1 || len(h.ListNodes()) != 0 {
+		t.Errorf("node kept after the last source dropped it")
+	}
+	restored := newHub(nil, path)
+	if nodes := restored.ListNodes(); len(nodes) != 0 {
+		t.Errorf("discovered node registered on restart: %v", nodes)
+	}
+	restored.SyncDiscovered("file:targets.json", []string{"http://a:1312"})
+	if nodes := restored.ListNodes(); len(nodes) != 1 || nodes[0]["name"] != "trainer" {
+		t.Errorf("rediscovered nodes = %v, want trainer", nodes)
+	}
+}
+
+func TestDiscoveryFollowsFileChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets.json")
+	if err := os.WriteFile(path, []byte(`[{"targets": ["gpu01"]}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newHub(nil, "")
+	discovery := NewDiscovery(h, time.Hour, NewFileDiscoverer(path, "http"))
+	discovery.Start()
+	defer discovery.Stop()
+
+	waitFor(t, func() bool { return len(h.ListNodes()) == 1 })
+
+	// Replace the file atomically, as config management tools do
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(`[{"targets": ["gpu01", "gpu02"]}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(h.ListNodes()) == 2 })
+}
@@ -0,0 +1,114 @@
+package hub
+
+import "time"
+
+const (
+	// hubLabel is the label federated nodes carry with the name of the child hub they came through
+	hubLabel = "hub"
+	// federationSeparator joins a child hub's name and its node names
+	federationSeparator = "/"
+	// maxFederationDepth bounds hub nesting so a hub configured as its own upstream cannot grow without limit
+	maxFederationDepth = 8
+)
+
+// clusterNode is one node of the cluster view, either connected directly or federated through a child hub
+type clusterNode struct {
+	name       string
+	status     string
+	lastUpdate string
+	data       map[string]interface{}
+	labels     map[string]string
+	connection map[string]interface{}
+	depth      int // Number of hubs between this hub and the node
+}
+
+// clusterNode describes a directly connected node for the cluster view (n.mu must be held)
+func (n *NodeInfo) clusterNode(name, status string) clusterNode {
+	return clusterNode{
+		name:       name,
+		status:     status,
+		lastUpdate: n.LastUpdate,
+		data:       n.Data,
+		labels:     nodeLabels(n.Data),
+		connection: n.connectionStats(),
+	}
+}
+
+// isHubPayload reports whether a node's payload comes from another hub
+func isHubPayload(data map[string]interface{}) bool {
+	mode, _ := data["mode"].(string)
+	return mode == "hub"
+}
+
+// clusterNodesLocked lists every node of the cluster view, expanding child hubs
+// into the nodes they aggregate (h.mu must be held)
+func (h *Hub) clusterNodesLocked(now time.Time) []clusterNode {
+	nodes := make([]clusterNode, 0, len(h.nodes))
+	for nodeName, nodeInfo := range h.nodes {
+		nodeInfo.mu.RLock()
+		status := nodeInfo.statusAt(now, h.staleAfter)
+		if isHubPayload(nodeInfo.Data) {
+			nodes = append(nodes, federatedNodes(nodeName, status, nodeInfo.Data)...)
+		} else {
+			nodes = append(nodes, nodeInfo.clusterNode(nodeName, status))
+		}
+		nodeInfo.mu.RUnlock()
+	}
+	return nodes
+}
+
+// federatedNodes expands a child hub's cluster payload into its nodes. Node names are
+// prefixed with the child hub's name and labeled with it; the child hub's own
+// status caps theirs, so nodes behind an unreachable hub show up offline or stale.
+func federatedNodes(hubName, hubStatus string, data map[string]interface{}) []clusterNode {
+	children, _ := data["nodes"].(map[string]interface{})
+	nodes := make([]clusterNode, 0, len(children))
+	for childName, raw := range children {
+		child, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		depth := 1
+		if d, ok := child["federation_depth"].(float64); ok {
+			depth += int(d)
+		}
+		if depth > maxFederationDepth {
+			continue
+		}
+
+		status, _ := child["status"].(string)
+		switch {
+		case hubStatus != "online" && hubStatus != "stale":
+			status = "offline"
+		case hubStatus == "stale" && status == "online":
+			status = "stale"
+		}
+
+		labels := nodeLabels(child)
+		labels[hubLabel] = hubName
+
+		lastUpdate, _ := child["last_update"].(string)
+		connection, _ := child["connection"].(map[string]interface{})
+
+		nodes = append(nodes, clusterNode{
+			name:       hubName + federationSeparator + childName,
+			status:     status,
+			lastUpdate: lastUpdate,
+			data:       child,
+			labels:     labels,
+			connection: connection,
+			depth:      depth,
+		})
+	}
+	return nodes
+}
+
+// federatedNodeCount returns how many nodes a child hub reports, or 0 for regular nodes
+func federatedNodeCount(data map[string]interface{}) int {
+	if !isHubPayload(data) {
+		return 0
+	}
+	children, _ := data["nodes"].(map[string]interface{})
+	return len(children)
+}
@@ -0,0 +1,111 @@
+package hub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// childHubPayload is what a datacenter hub broadcasts to its upstream hub
+const childHubPayload = `{
+	"mode": "hub",
+	"node_name": "dc-eu",
+	"nodes": {
+		"gpu01": {"status": "online", "labels": {"rack": "r1"}, "last_update": "2026-01-01T00:00:00Z",
+			"gpus": {"0": {"name": "A100", "utilization": 80, "slurm_job_id": "42", "slurm_job_user": "alice"}},
+			"processes": [], "system": {}},
+		"gpu02": {"status": "offline", "labels": {}, "gpus": {}, "processes": [], "system": {}}
+	},
+	"cluster_stats": {"total_nodes": 2}
+}`
+
+func TestFederatedHubNodes(t *testing.T) {
+	h := newHub(nil, "")
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(childHubPayload), &payload); err != nil {
+		t.Fatal(err)
+	}
+	url := agentURL("dc-eu")
+	_, stop, _ := h.attachAgent("dc-eu", "")
+	h.updateNode(url, payload)
+
+	h.attachAgent("local", "")
+	h.updateNode(agentURL("local"), map[string]interface{}{
+		"node_name": "local",
+		"gpus": map[string]interface{}{
+			"0": map[string]interface{}{"name": "H100", "utilization": 20.0},
+		},
+	})
+
+	cluster := h.GetClusterData()
+	nodes := cluster["nodes"].(map[string]interface{})
+	if len(nodes) != 3 {
+		t.Fatalf("cluster has %d nodes, want 3: %v", len(nodes), nodes)
+	}
+	gpu01, ok := nodes["dc-eu/gpu01"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("missing federated node dc-eu/gpu01 in %v", nodes)
+	}
+	labels := gpu01["labels"].(map[string]string)
+	if labels[hubLabel] != "dc-eu" || labels["rack"] != "r1" {
+		t.Errorf("federated labels = %v", labels)
+	}
+	if nodes["dc-eu/gpu02"].(map[string]interface{})["status"] != "offline" {
+		t.Error("child hub's offline node is not offline")
+	}
+
+	stats := cluster["cluster_stats"].(map[string]interface{})
+	if stats["online_nodes"] != 2 || stats["total_gpus"] != 2 {
+		t.Errorf("cluster_stats = %v, want 2 online nodes and 2 GPUs", stats)
+	}
+	if job := cluster["slurm_jobs"].(map[string]map[string]interface{})["42"]; job == nil || job["nodes"].([]string)[0] != "dc-eu/gpu01" {
+		t.Errorf("slurm job 42 = %v", job)
+	}
+
+	selector, _ := ParseLabelSelector("hub=dc-eu")
+	if got := len(h.QueryCluster(ClusterQuery{Selector: selector})["nodes"].(map[string]interface{})); got != 2 {
+		t.Errorf("hub=dc-eu matched %d nodes, want 2", got)
+	}
+
+	if listed := h.ListNodes(); listed[0]["federated_nodes"] != 2 {
+		t.Errorf("ListNodes()[0] = %v, want 2 federated nodes", listed[0])
+	}
+
+	// Nodes behind a stale child hub are stale, behind a disconnected one offline
+	h.SetStaleAfter(time.Second)
+	node := h.nodeByURL(url)
+	node.mu.Lock()
+	node.lastData = time.Now().Add(-time.Minute)
+	node.mu.Unlock()
+	if status := h.GetClusterData()["nodes"].(map[string]interface{})["dc-eu/gpu01"].(map[string]interface{})["status"]; status != "stale" {
+		t.Errorf("status behind stale hub = %v, want stale", status)
+	}
+
+	h.detachAgent(url, stop, nil)
+	nodes = h.GetClusterData()["nodes"].(map[string]interface{})
+	if status := nodes["dc-eu/gpu01"].(map[string]interface{})["status"]; status != "offline" {
+		t.Errorf("status behind offline hub = %v, want offline", status)
+	}
+}
+
+func TestFederationDepthLimit(t *testing.T) {
+	h := newHub(nil, "")
+	h.attachAgent("loop", "")
+	h.updateNode(agentURL("loop"), map[string]interface{}{
+		"mode":      "hub",
+		"node_name": "loop",
+		"nodes": map[string]interface{}{
+			"deep": map[string]interface{}{"status": "online", "federation_depth": float64(maxFederationDepth)},
+			"ok":   map[string]interface{}{"status": "online", "federation_depth": float64(maxFederationDepth - 1)},
+		},
+	})
+
+	nodes := h.GetClusterData()["nodes"].(map[string]interface{})
+	if _, ok := nodes["loop/deep"]; ok {
+		t.Error("node beyond the federation depth limit was included")
+	}
+	if entry, ok := nodes["loop/ok"].(map[string]interface{}); !ok || entry["federation_depth"] != maxFederationDepth {
+		t.Errorf("loop/ok = %v, want depth %d", nodes["loop/ok"], maxFederationDepth)
+	}
+}
@@ -1,11 +1,19 @@
 package hub
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"log"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
+	"gpu-pro/config"
+	"gpu-pro/placement"
+	"gpu-pro/ratelimit"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
 )
@@ -63,14 +71,18 @@ func (wsc *WebSocketClients) Count() int {
 	return len(wsc.clients)
 }
 
-// RegisterHubHandlers registers WebSocket handlers for hub mode
-func RegisterHubHandlers(app *fiber.App, h *Hub) {
+// RegisterHubHandlers registers WebSocket handlers for hub mode and starts connecting to the nodes
+func RegisterHubHandlers(app *fiber.App, h *Hub, cfg *config.Config) {
+	// Connect to the nodes right away so the REST API has data without a dashboard open
+	h.Start()
+
 	wsClients := NewWebSocketClients()
 	hubRunning := false
 	var hubMu sync.Mutex
 
-	// WebSocket endpoint
-	app.Get("/socket.io/", websocket.New(func(c *websocket.Conn) {
+	// WebSocket endpoint, capped at WS_MAX_CLIENTS concurrent clients
+	wsLimit := ratelimit.NewConnLimiter(cfg.WSMaxClients)
+	app.Get("/socket.io/", wsLimit.Upgrade(), websocket.New(wsLimit.Handler(func(c *websocket.Conn) {
 		wsClients.Add(c)
 		log.Println("Dashboard client connected")
 
@@ -78,13 +90,10 @@ func RegisterHubHandlers(app *fiber.App, h *Hub) {
 		hubMu.Lock()
 		if !hubRunning {
 			hubRunning = true
-			go hubLoop(h, wsClients)
+			go hubLoop(h, wsClients, cfg.NodeName)
 		}
 		hubMu.Unlock()
 
-		// Start node connections if not already started
-		h.Start()
-
 		// Keep connection alive
 		for {
 			if _, _, err := c.ReadMessage(); err != nil {
@@ -94,11 +103,361 @@ func RegisterHubHandlers(app *fiber.App, h *Hub) {
 		}
 
 		wsClients.Remove(c)
+	})))
+
+	// Cluster-wide aggregate statistics
+	// Query: labels (selector, e.g. "rack=r1,team=ml"), group_by (label key)
+	app.Get("/api/v1/cluster", func(c *fiber.Ctx) error {
+		q, err := parseClusterQuery(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		data := h.QueryCluster(q)
+		stats := data["cluster_stats"].(map[string]interface{})
+		if groups, ok := data["groups"]; ok {
+			stats["group_by"] = q.GroupBy
+			stats["groups"] = groups
+		}
+		return c.JSON(stats)
+	})
+
+	registerNodeHandlers(app, h)
+	registerClusterDataHandlers(app, h)
+	registerAgentHandlers(app, h, cfg.AgentToken)
+}
+
+// registerClusterDataHandlers exposes the cluster view over REST for scripts
+// that do not speak WebSocket
+func registerClusterDataHandlers(app *fiber.App, h *Hub) {
+	// All GPUs with current data, keyed "<node>-<gpu id>"
+	app.Get("/api/gpu-data", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"gpus":      h.GPUData(),
+			"timestamp": time.Now().Format(time.RFC3339),
+		})
+	})
+
+	// Full cluster snapshot, the same data dashboards receive
+	// Query: labels (selector, e.g. "rack=r1,team=ml"), group_by (label key)
+	app.Get("/api/v1/cluster/snapshot", func(c *fiber.Ctx) error {
+		q, err := parseClusterQuery(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(h.QueryCluster(q))
+	})
+
+	// GPUs ranked by availability across the cluster with a recommended placement
+	// Query: count, memory (MiB or e.g. "24G"), same_node, nvlink, labels
+	app.Get("/api/v1/free-gpus", func(c *fiber.Ctx) error {
+		req, err := placement.ParseRequest(c.Queries())
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		selector, err := ParseLabelSelector(c.Query("labels"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(placement.Rank(h.PlacementNodes(selector), req))
+	})
+
+	// A single GPU anywhere in the cluster
+	app.Get("/api/v1/gpus/:uuid", func(c *fiber.Ctx) error {
+		gpu, err := h.FindGPU(c.Params("uuid"))
+		if err != nil {
+			return c.Status(nodeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(gpu)
+	})
+
+	// Latest payload of a node (by name or URL)
+	app.Get("/api/v1/nodes/:node", func(c *fiber.Ctx) error {
+		key, err := url.PathUnescape(c.Params("node"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid node",
+			})
+		}
+
+		data, err := h.NodeData(key)
+		if err != nil {
+			return c.Status(nodeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(data)
+	})
+
+	// GPU processes of a node (by name or URL)
+	app.Get("/api/v1/nodes/:node/processes", func(c *fiber.Ctx) error {
+		key, err := url.PathUnescape(c.Params("node"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid node",
+			})
+		}
+
+		processes, err := h.NodeProcesses(key)
+		if err != nil {
+			return c.Status(nodeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(processes)
+	})
+}
+
+// agentReadLimit caps the size of a single agent message
+const agentReadLimit = 16 << 20
+
+// registerAgentHandlers accepts push-mode agents connecting outbound from their nodes
+func registerAgentHandlers(app *fiber.App, h *Hub, token string) {
+	// Authenticate before upgrading so rejected agents get a plain HTTP error
+	app.Use("/api/v1/agents/connect", func(c *fiber.Ctx) error {
+		if token == "" {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "Agent connections are disabled (AGENT_TOKEN not set on the hub)",
+			})
+		}
+
+		provided := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Invalid agent token",
+			})
+		}
+
+		if c.Query("node") == "" {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Missing node name",
+			})
+		}
+		if !validAgentName.MatchString(c.Query("node")) {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid node name: use up to 63 letters, digits, '.', '_' or '-'",
+			})
+		}
+
+		// Agents share the token, so a live session can only be resumed by the agent
+		// instance that opened it
+		instance := c.Get("X-Agent-Instance")
+		if h.agentNameTaken(c.Query("node"), instance) {
+			return c.Status(409).JSON(fiber.Map{
+				"error": ErrAgentNameInUse.Error(),
+			})
+		}
+
+		if !websocket.IsWebSocketUpgrade(c) {
+			return fiber.ErrUpgradeRequired
+		}
+		c.Locals("node", c.Query("node"))
+		c.Locals("instance", instance)
+		return c.Next()
+	})
+
+	app.Get("/api/v1/agents/connect", websocket.New(func(c *websocket.Conn) {
+		nodeName, _ := c.Locals("node").(string)
+		instance, _ := c.Locals("instance").(string)
+		nodeURL, stop, err := h.attachAgent(nodeName, instance)
+		if err != nil {
+			// Another agent took the name between the check and the upgrade
+			log.Printf("Refused agent %s (%s): %v", nodeName, c.RemoteAddr(), err)
+			c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()))
+			return
+		}
+		log.Printf("Agent connected: %s (%s)", nodeName, c.RemoteAddr())
+
+		// Close the connection when the session is replaced, removed or the hub shuts down,
+		// and ping the agent to detect hung connections. Both goroutines must finish before
+		// the handler returns, since the connection is released afterwards.
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-stop:
+				// Close on a hijacked connection is a no-op until the handler returns,
+				// so end the session by failing the pending read instead
+				c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "session closed"), time.Now().Add(time.Second))
+				c.SetReadDeadline(time.Now())
+			case <-done:
+			}
+		}()
+		setPongHandler(c, func(latency time.Duration) { h.recordLatency(nodeURL, latency) })
+		go func() {
+			defer wg.Done()
+			pingLoop(c, done)
+		}()
+
+		c.SetReadLimit(agentReadLimit)
+		var readErr error
+	read:
+		for {
+			select {
+			case <-stop:
+				break read
+			default:
+			}
+
+			c.SetReadDeadline(time.Now().Add(readTimeout))
+			_, message, err := c.ReadMessage()
+			if err != nil {
+				log.Printf("Agent disconnected: %s - %v", nodeName, err)
+				readErr = err
+				break
+			}
+
+			var data map[string]interface{}
+			if err := json.Unmarshal(message, &data); err != nil {
+				log.Printf("Failed to parse message from agent %s: %v", nodeName, err)
+				continue
+			}
+
+			h.updateNode(nodeURL, data)
+		}
+
+		close(done)
+		wg.Wait()
+		h.detachAgent(nodeURL, stop, readErr)
 	}))
 }
 
-// hubLoop is the background loop that emits aggregated cluster data
-func hubLoop(h *Hub, wsClients *WebSocketClients) {
+// registerNodeHandlers exposes the runtime node registry
+func registerNodeHandlers(app *fiber.App, h *Hub) {
+	// List registered nodes with their connection status
+	// Query: labels (selector, e.g. "rack=r1,team=ml")
+	app.Get("/api/v1/nodes", func(c *fiber.Ctx) error {
+		selector, err := ParseLabelSelector(c.Query("labels"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		nodes := []map[string]interface{}{}
+		for _, node := range h.ListNodes() {
+			if labels, _ := node["labels"].(map[string]string); selector.Matches(labels) {
+				nodes = append(nodes, node)
+			}
+		}
+		return c.JSON(fiber.Map{
+			"nodes": nodes,
+		})
+	})
+
+	// Register a node: {"url": "http://node:1312", "name": "optional display name"}
+	app.Post("/api/v1/nodes", func(c *fiber.Ctx) error {
+		var req struct {
+			URL  string `json:"url"`
+			Name string `json:"name"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if err := h.AddNode(req.URL, strings.TrimSpace(req.Name)); err != nil {
+			return c.Status(nodeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Registered node %s", req.URL)
+		return c.Status(201).JSON(fiber.Map{
+			"nodes": h.ListNodes(),
+		})
+	})
+
+	// Rename a node (by name or URL): {"name": "new name"}, empty name reverts to the reported name
+	app.Patch("/api/v1/nodes/:node", func(c *fiber.Ctx) error {
+		key, err := url.PathUnescape(c.Params("node"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid node",
+			})
+		}
+
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if err := h.RenameNode(key, strings.TrimSpace(req.Name)); err != nil {
+			return c.Status(nodeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(fiber.Map{
+			"nodes": h.ListNodes(),
+		})
+	})
+
+	// Remove a node (by name or URL) and close its connection
+	app.Delete("/api/v1/nodes/:node", func(c *fiber.Ctx) error {
+		key, err := url.PathUnescape(c.Params("node"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid node",
+			})
+		}
+
+		if err := h.RemoveNode(key); err != nil {
+			return c.Status(nodeErrorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Printf("Removed node %s", key)
+		return c.JSON(fiber.Map{
+			"nodes": h.ListNodes(),
+		})
+	})
+}
+
+// parseClusterQuery reads the label selector and grouping from query parameters
+func parseClusterQuery(c *fiber.Ctx) (ClusterQuery, error) {
+	selector, err := ParseLabelSelector(c.Query("labels"))
+	if err != nil {
+		return ClusterQuery{}, err
+	}
+	return ClusterQuery{
+		Selector: selector,
+		GroupBy:  strings.TrimSpace(c.Query("group_by")),
+	}, nil
+}
+
+// nodeErrorStatus maps registry errors to HTTP status codes
+func nodeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNodeNotFound), errors.Is(err, ErrGPUNotFound):
+		return 404
+	case errors.Is(err, ErrNodeExists), errors.Is(err, ErrNameInUse):
+		return 409
+	case errors.Is(err, ErrInvalidNodeURL):
+		return 400
+	default:
+		return 500
+	}
+}
+
+// hubLoop is the background loop that emits aggregated cluster data to
+// dashboards and upstream hubs, which identify this hub by nodeName
+func hubLoop(h *Hub, wsClients *WebSocketClients, nodeName string) {
 	log.Println("Hub monitoring loop started")
 
 	ticker := time.NewTicker(500 * time.Millisecond) // 0.5s to match node update rate
@@ -112,6 +471,7 @@ func hubLoop(h *Hub, wsClients *WebSocketClients) {
 
 		// Get cluster data
 		clusterData := h.GetClusterData()
+		clusterData["node_name"] = nodeName
 
 		// Send to all connected clients
 		data, err := json.Marshal(clusterData)
@@ -1,8 +1,10 @@
 package hub
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"log"
+	"net/http"
 	"sync"
 	"time"
 
@@ -19,13 +21,30 @@ type NodeInfo struct {
 	LastUpdate string                 `json:"last_update"`
 	conn       *websocket.Conn
 	mu         sync.RWMutex
+
+	// Connection statistics
+	lastData    time.Time
+	connectedAt time.Time
+	reconnects  int
+	lastError   string
+	lastErrorAt time.Time
+	latency     time.Duration
 }
 
 // Hub aggregates GPU data from multiple nodes
 type Hub struct {
-	nodeURLs        []string
+	nodeURLs        []string // Registered node URLs, in registration order
 	nodes           map[string]*NodeInfo
 	urlToNode       map[string]string
+	aliases         map[string]string          // URL -> display name set through the nodes API
+	stopChans       map[string]chan struct{}   // URL -> stops that node's connection goroutine
+	agentInstances  map[string]string          // Agent URL -> instance ID of its live session
+	discovered      map[string]map[string]bool // URL -> discovery sources listing the node
+	registryPath    string
+	staleAfter      time.Duration // Online nodes without data for this long are reported stale
+	nodeToken       string        // Bearer token sent when dialing nodes that require authentication
+	tlsConfig       *tls.Config   // Client certificate and CA for https:// nodes
+	connecting      bool          // Connection goroutines are being started for registered nodes
 	running         bool
 	mu              sync.RWMutex
 	connMu          sync.Mutex
@@ -33,32 +52,101 @@ type Hub struct {
 	heartbeatClient *analytics.HeartbeatClient
 }
 
-// NewHub creates a new hub instance
-func NewHub(nodeURLs []string) *Hub {
+// NewHub creates a new hub instance.
+// If registryPath holds a saved node registry its nodes and names are restored, and
+// nodeURLs missing from it are added alongside them.
+func NewHub(nodeURLs []string, registryPath string) *Hub {
+	hub := newHub(nodeURLs, registryPath)
+
+	// Start analytics heartbeat
+	hub.heartbeatClient = analytics.NewHeartbeatClient("v2.0-hub", "webui") // GPU Pro hub version, WebUI mode
+	hub.heartbeatClient.Start()
+
+	return hub
+}
+
+func newHub(nodeURLs []string, registryPath string) *Hub {
 	hub := &Hub{
-		nodeURLs:        nodeURLs,
-		nodes:           make(map[string]*NodeInfo),
-		urlToNode:       make(map[string]string),
-		heartbeatClient: analytics.NewHeartbeatClient("v2.0-hub", "webui"), // GPU Pro hub version, WebUI mode
+		nodes:          make(map[string]*NodeInfo),
+		urlToNode:      make(map[string]string),
+		aliases:        make(map[string]string),
+		stopChans:      make(map[string]chan struct{}),
+		agentInstances: make(map[string]string),
+		discovered:     make(map[string]map[string]bool),
+		registryPath:   registryPath,
+		staleAfter:     DefaultStaleAfter,
 	}
 
-	// Initialize nodes as offline
-	for _, url := range nodeURLs {
-		hub.nodes[url] = &NodeInfo{
-			URL:        url,
-			Data:       nil,
-			Status:     "offline",
-			LastUpdate: "",
+	entries, aliases, err := loadRegistry(registryPath)
+	if err != nil {
+		log.Printf("Failed to load node registry %s: %v", registryPath, err)
+	}
+	// Names of discovered nodes and agents, applied whenever they (re)appear
+	for url, name := range aliases {
+		hub.aliases[url] = name
+	}
+	if entries == nil {
+		for _, url := range nodeURLs {
+			entries = append(entries, RegistryEntry{URL: url})
+		}
+	} else {
+		// NODE_URLS added since the registry was saved join the saved nodes
+		saved := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			saved[entry.URL] = true
+		}
+		for _, url := range nodeURLs {
+			if !saved[url] {
+				log.Printf("Adding %s from NODE_URLS to the nodes in registry %s", url, registryPath)
+				entries = append(entries, RegistryEntry{URL: url})
+				saved[url] = true
+			}
 		}
-		hub.urlToNode[url] = url
 	}
 
-	// Start analytics heartbeat
-	hub.heartbeatClient.Start()
+	// Initialize nodes as offline
+	for _, entry := range entries {
+		hub.addNodeLocked(entry.URL, entry.Name)
+	}
 
 	return hub
 }
 
+// addNodeLocked registers a node as offline (h.mu must be held)
+func (h *Hub) addNodeLocked(url, name string) {
+	h.nodeURLs = append(h.nodeURLs, url)
+	nodeName := url
+	if name == "" {
+		name = h.aliases[url]
+	}
+	if name != "" && h.nameFreeLocked(url, name) {
+		nodeName = name
+		h.aliases[url] = name
+	}
+	h.nodes[nodeName] = &NodeInfo{
+		URL:        url,
+		Data:       nil,
+		Status:     "offline",
+		LastUpdate: "",
+	}
+	h.urlToNode[url] = nodeName
+
+	// Agents connect to the hub themselves
+	if h.connecting && !isAgentURL(url) {
+		h.startNodeLocked(url)
+	}
+}
+
+// startNodeLocked spins up the connection goroutine for a node (h.mu must be held)
+func (h *Hub) startNodeLocked(url string) {
+	if _, ok := h.stopChans[url]; ok {
+		return
+	}
+	stop := make(chan struct{})
+	h.stopChans[url] = stop
+	go h.connectNode(url, stop)
+}
+
 // Start begins connecting to all nodes
 func (h *Hub) Start() {
 	h.connMu.Lock()
@@ -69,7 +157,9 @@ func (h *Hub) Start() {
 	}
 
 	h.connStarted = true
+	h.mu.Lock()
 	h.running = true
+	h.mu.Unlock()
 
 	// Start connections in background
 	go h.connectAllNodes()
@@ -79,216 +169,376 @@ func (h *Hub) connectAllNodes() {
 	// Wait a bit for initialization
 	time.Sleep(2 * time.Second)
 
-	// Connect to all nodes concurrently
-	var wg sync.WaitGroup
+	// Connect to all nodes concurrently; nodes registered later are started as they are added
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.connecting = true
 	for _, url := range h.nodeURLs {
-		wg.Add(1)
-		go func(nodeURL string) {
-			defer wg.Done()
-			h.connectNodeWithRetry(nodeURL)
-		}(url)
+		if !isAgentURL(url) {
+			h.startNodeLocked(url)
+		}
+	}
+}
+
+// sleepOrStop waits for d and reports false if the node was stopped in the meantime
+func sleepOrStop(stop chan struct{}, d time.Duration) bool {
+	select {
+	case <-stop:
+		return false
+	case <-time.After(d):
+		return true
 	}
-	wg.Wait()
 }
 
-func (h *Hub) connectNodeWithRetry(url string) {
-	maxRetries := 5
-	retryDelay := 2 * time.Second
+// connectNode keeps a node connected until it is removed or the hub shuts down,
+// reconnecting with jittered exponential backoff
+func (h *Hub) connectNode(url string, stop chan struct{}) {
+	retry := newBackoff(minReconnectDelay, maxReconnectDelay)
 
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		err := h.connectNode(url)
-		if err == nil {
-			return // Success
+	for h.isRunning() {
+		received, err := h.runSession(url, stop)
+
+		select {
+		case <-stop:
+			return
+		default:
 		}
 
-		if attempt < maxRetries-1 {
-			log.Printf("Connection attempt %d/%d failed for %s: %v, retrying in %v...",
-				attempt+1, maxRetries, url, err, retryDelay)
-			time.Sleep(retryDelay)
-		} else {
-			log.Printf("Failed to connect to node %s after %d attempts: %v", url, maxRetries, err)
+		// A session that delivered data was healthy; start over from the minimum delay
+		if received {
+			retry.Reset()
+		}
+		h.markNodeOffline(url, err)
+
+		delay := retry.Next()
+		log.Printf("Connection to node %s lost: %v, reconnecting in %v", url, err, delay.Round(time.Millisecond))
+		if !sleepOrStop(stop, delay) {
+			return
 		}
 	}
 }
 
-func (h *Hub) connectNode(url string) error {
-	for h.running {
-		// Convert HTTP URL to WebSocket URL
-		wsURL := url
-		if len(wsURL) > 7 && wsURL[:7] == "http://" {
-			wsURL = "ws://" + wsURL[7:]
-		} else if len(wsURL) > 8 && wsURL[:8] == "https://" {
-			wsURL = "wss://" + wsURL[8:]
-		}
-		wsURL += "/socket.io/"
+// runSession dials a node and reads its payloads until the connection fails,
+// reporting whether any data was received
+func (h *Hub) runSession(url string, stop chan struct{}) (bool, error) {
+	// Convert HTTP URL to WebSocket URL
+	wsURL := url
+	if len(wsURL) > 7 && wsURL[:7] == "http://" {
+		wsURL = "ws://" + wsURL[7:]
+	} else if len(wsURL) > 8 && wsURL[:8] == "https://" {
+		wsURL = "wss://" + wsURL[8:]
+	}
+	wsURL += "/socket.io/"
 
-		log.Printf("Connecting to node WebSocket: %s", wsURL)
+	log.Printf("Connecting to node WebSocket: %s", wsURL)
+
+	h.mu.RLock()
+	token := h.nodeToken
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = h.tlsConfig
+	h.mu.RUnlock()
+	var header http.Header
+	if token != "" {
+		header = http.Header{"Authorization": {"Bearer " + token}}
+	}
 
-		// Connect to WebSocket
-		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	// Connect to WebSocket
+	conn, _, err := dialer.Dial(wsURL, header)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// Store connection, unless the node was removed while dialing
+	h.mu.Lock()
+	select {
+	case <-stop:
+		h.mu.Unlock()
+		return false, nil
+	default:
+	}
+	if node, ok := h.nodes[h.urlToNode[url]]; ok {
+		node.mu.Lock()
+		node.conn = conn
+		node.Status = "online"
+		node.connectedAt = time.Now()
+		node.LastUpdate = time.Now().Format(time.RFC3339)
+		node.mu.Unlock()
+	}
+	h.mu.Unlock()
+
+	log.Printf("Connected to node: %s", url)
+
+	// Ping the node so hung connections are detected and latency is measured
+	done := make(chan struct{})
+	defer close(done)
+	setPongHandler(conn, func(latency time.Duration) { h.recordLatency(url, latency) })
+	go pingLoop(conn, done)
+
+	// Listen for messages
+	received := false
+	for {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		_, message, err := conn.ReadMessage()
 		if err != nil {
-			h.markNodeOffline(url)
-			time.Sleep(5 * time.Second)
-			continue
+			return received, err
 		}
 
-		log.Printf("Connected to node: %s", url)
-
-		// Store connection
-		nodeName := h.getNodeName(url)
-		h.mu.Lock()
-		if node, ok := h.nodes[nodeName]; ok {
-			node.mu.Lock()
-			node.conn = conn
-			node.Status = "online"
-			node.LastUpdate = time.Now().Format(time.RFC3339)
-			node.mu.Unlock()
+		// Parse message
+		var data map[string]interface{}
+		if err := json.Unmarshal(message, &data); err != nil {
+			log.Printf("Failed to parse message from %s: %v", url, err)
+			continue
 		}
-		h.mu.Unlock()
 
-		// Listen for messages
-		for {
-			_, message, err := conn.ReadMessage()
-			if err != nil {
-				log.Printf("WebSocket connection closed for node: %s - %v", url, err)
-				h.markNodeOffline(url)
-				break
-			}
+		h.updateNode(url, data)
+		received = true
+	}
+}
 
-			// Parse message
-			var data map[string]interface{}
-			if err := json.Unmarshal(message, &data); err != nil {
-				log.Printf("Failed to parse message from %s: %v", url, err)
-				continue
-			}
+func (h *Hub) isRunning() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.running
+}
 
-			// Extract node name from data or use URL
-			nodeName := url
-			if name, ok := data["node_name"].(string); ok && name != "" {
-				nodeName = name
-				h.mu.Lock()
-				h.urlToNode[url] = nodeName
-				h.mu.Unlock()
-			}
+// updateNode stores the latest payload of a node, keyed by its display name
+func (h *Hub) updateNode(url string, data map[string]interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-			// Update node data
-			h.mu.Lock()
-			if _, exists := h.nodes[nodeName]; !exists {
-				h.nodes[nodeName] = &NodeInfo{}
-			}
-			node := h.nodes[nodeName]
-			node.mu.Lock()
-			node.URL = url
-			node.Data = data
-			node.Status = "online"
-			node.LastUpdate = time.Now().Format(time.RFC3339)
-			node.mu.Unlock()
-			h.mu.Unlock()
-		}
+	// Removed nodes may still deliver a final message
+	if _, ok := h.stopChans[url]; !ok {
+		return
+	}
+
+	// Prefer the name set through the API, then the node's reported name, then the URL
+	nodeName := url
+	if alias := h.aliases[url]; alias != "" {
+		nodeName = alias
+	} else if name, ok := data["node_name"].(string); ok && name != "" && (!isAgentURL(url) || validAgentName.MatchString(name)) {
+		nodeName = name
+	}
+	nodeName = h.renameLocked(url, nodeName)
+
+	if _, exists := h.nodes[nodeName]; !exists {
+		h.nodes[nodeName] = &NodeInfo{}
+	}
+	node := h.nodes[nodeName]
+	node.mu.Lock()
+	node.URL = url
+	node.Data = data
+	node.Status = "online"
+	node.lastData = time.Now()
+	node.LastUpdate = node.lastData.Format(time.RFC3339)
+	node.mu.Unlock()
+}
 
-		// Connection closed, retry after delay
-		if h.running {
-			time.Sleep(5 * time.Second)
+// renameLocked re-keys a node's entry under a new display name and returns the name used:
+// the node's URL if another node already goes by that name (h.mu must be held)
+func (h *Hub) renameLocked(url, nodeName string) string {
+	oldName, ok := h.urlToNode[url]
+	if !h.nameFreeLocked(url, nodeName) {
+		if oldName != url {
+			log.Printf("Node %s reports name %s, which another node uses; showing it by URL", url, nodeName)
 		}
+		nodeName = url
 	}
+	if ok && oldName != nodeName {
+		if node, exists := h.nodes[oldName]; exists && node.URL == url {
+			delete(h.nodes, oldName)
+			h.nodes[nodeName] = node
+		}
+	}
+	h.urlToNode[url] = nodeName
+	return nodeName
+}
 
-	return nil
+// nameFreeLocked reports whether a node may use a display name: no other node uses it (h.mu must be held)
+func (h *Hub) nameFreeLocked(url, nodeName string) bool {
+	node, exists := h.nodes[nodeName]
+	return !exists || node.URL == url
 }
 
-func (h *Hub) markNodeOffline(url string) {
+// markNodeOffline marks a node offline after its connection ended, recording the cause
+func (h *Hub) markNodeOffline(url string, cause error) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	nodeName := h.urlToNode[url]
 	if node, ok := h.nodes[nodeName]; ok {
 		node.mu.Lock()
+		if node.Status == "online" {
+			log.Printf("Marked node %s as offline", nodeName)
+		}
 		node.Status = "offline"
+		node.conn = nil
+		node.reconnects++
+		if cause != nil {
+			node.lastError = cause.Error()
+			node.lastErrorAt = time.Now()
+		}
 		node.mu.Unlock()
-		log.Printf("Marked node %s as offline", nodeName)
 	}
 }
 
-func (h *Hub) getNodeName(url string) string {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	if name, ok := h.urlToNode[url]; ok {
-		return name
-	}
-	return url
-}
-
 // GetClusterData gets aggregated data from all nodes
 func (h *Hub) GetClusterData() map[string]interface{} {
+	return h.QueryCluster(ClusterQuery{})
+}
+
+// QueryCluster gets aggregated data from the nodes matching the query's label selector,
+// adding per-group node lists and aggregates when grouping by a label
+func (h *Hub) QueryCluster(q ClusterQuery) map[string]interface{} {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	nodes := make(map[string]interface{})
+	slurmJobs := make(map[string]map[string]interface{})
 	totalGPUs := 0
 	onlineNodes := 0
+	staleNodes := 0
+	aggregates := newClusterAggregates()
+	groups := newLabelGroups()
+	now := time.Now()
+
+	for _, node := range h.clusterNodesLocked(now) {
+		if !q.Selector.Matches(node.labels) {
+			continue
+		}
 
-	for nodeName, nodeInfo := range h.nodes {
-		nodeInfo.mu.RLock()
-		if nodeInfo.Status == "online" && nodeInfo.Data != nil {
+		var entry map[string]interface{}
+		if (node.status == "online" || node.status == "stale") && node.data != nil {
 			gpus := make(map[string]interface{})
-			if gpusData, ok := nodeInfo.Data["gpus"].(map[string]interface{}); ok {
+			if gpusData, ok := node.data["gpus"].(map[string]interface{}); ok {
 				gpus = gpusData
 			}
 
 			processes := []interface{}{}
-			if procsData, ok := nodeInfo.Data["processes"].([]interface{}); ok {
+			if procsData, ok := node.data["processes"].([]interface{}); ok {
 				processes = procsData
 			}
 
 			system := make(map[string]interface{})
-			if sysData, ok := nodeInfo.Data["system"].(map[string]interface{}); ok {
+			if sysData, ok := node.data["system"].(map[string]interface{}); ok {
 				system = sysData
 			}
 
-			nodes[nodeName] = map[string]interface{}{
-				"status":      "online",
+			// Stale nodes keep their last known data so dashboards can show it as outdated
+			entry = map[string]interface{}{
+				"status":      node.status,
 				"gpus":        gpus,
 				"processes":   processes,
 				"system":      system,
-				"last_update": nodeInfo.LastUpdate,
+				"last_update": node.lastUpdate,
+				"connection":  node.connection,
+				"labels":      node.labels,
+			}
+
+			for gpuID, gpu := range gpus {
+				if gpuData, ok := gpu.(map[string]interface{}); ok {
+					addSlurmJobGPU(slurmJobs, node.name, gpuID, gpuData)
+				}
 			}
 
 			totalGPUs += len(gpus)
-			onlineNodes++
+			if node.status == "online" {
+				// Aggregates only reflect live data
+				aggregates.addNode(gpus, node.labels)
+				onlineNodes++
+			} else {
+				staleNodes++
+			}
+			if q.GroupBy != "" {
+				groups.addNode(node.labels[q.GroupBy], node.name, gpus, node.labels, node.status == "online")
+			}
 		} else {
-			nodes[nodeName] = map[string]interface{}{
+			entry = map[string]interface{}{
 				"status":      "offline",
 				"gpus":        map[string]interface{}{},
 				"processes":   []interface{}{},
 				"system":      map[string]interface{}{},
-				"last_update": nodeInfo.LastUpdate,
+				"last_update": node.lastUpdate,
+				"connection":  node.connection,
+				"labels":      node.labels,
 			}
+			if q.GroupBy != "" {
+				groups.addNode(node.labels[q.GroupBy], node.name, nil, node.labels, false)
+			}
+		}
+		if node.depth > 0 {
+			entry["federation_depth"] = node.depth
 		}
-		nodeInfo.mu.RUnlock()
+		nodes[node.name] = entry
 	}
 
-	return map[string]interface{}{
-		"mode":  "hub",
-		"nodes": nodes,
+	result := map[string]interface{}{
+		"mode":       "hub",
+		"nodes":      nodes,
+		"slurm_jobs": slurmJobs,
 		"cluster_stats": map[string]interface{}{
-			"total_nodes":  len(h.nodes),
+			"total_nodes":  len(nodes),
 			"online_nodes": onlineNodes,
+			"stale_nodes":  staleNodes,
 			"total_gpus":   totalGPUs,
+			"aggregates":   aggregates.result(),
 		},
 	}
+	if q.GroupBy != "" {
+		result["group_by"] = q.GroupBy
+		result["groups"] = groups.result()
+	}
+	return result
+}
+
+// addSlurmJobGPU groups a GPU under the Slurm job it is tagged with, so jobs spanning nodes show up together
+func addSlurmJobGPU(jobs map[string]map[string]interface{}, nodeName, gpuID string, gpu map[string]interface{}) {
+	jobID, ok := gpu["slurm_job_id"].(string)
+	if !ok || jobID == "" {
+		return
+	}
+
+	job, exists := jobs[jobID]
+	if !exists {
+		job = map[string]interface{}{
+			"job_id":    jobID,
+			"user":      gpu["slurm_job_user"],
+			"name":      gpu["slurm_job_name"],
+			"partition": gpu["slurm_partition"],
+			"nodes":     []string{},
+			"gpus":      []map[string]interface{}{},
+		}
+		jobs[jobID] = job
+	}
+
+	jobNodes := job["nodes"].([]string)
+	if len(jobNodes) == 0 || jobNodes[len(jobNodes)-1] != nodeName {
+		job["nodes"] = append(jobNodes, nodeName)
+	}
+	job["gpus"] = append(job["gpus"].([]map[string]interface{}), map[string]interface{}{
+		"node":   nodeName,
+		"gpu_id": gpuID,
+		"uuid":   gpu["uuid"],
+	})
 }
 
 // Shutdown disconnects from all nodes
 func (h *Hub) Shutdown() {
-	h.running = false
-
 	// Stop heartbeat client
 	if h.heartbeatClient != nil {
 		h.heartbeatClient.Stop()
 	}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
+	h.running = false
+
+	h.connecting = false
+	for url, stop := range h.stopChans {
+		close(stop)
+		delete(h.stopChans, url)
+	}
 	for _, nodeInfo := range h.nodes {
 		nodeInfo.mu.Lock()
 		if nodeInfo.conn != nil {
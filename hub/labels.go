@@ -0,0 +1,88 @@
+package hub
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// noLabelGroup collects nodes without the label used for grouping
+const noLabelGroup = "(none)"
+
+// LabelSelector matches node labels. Every requirement must hold;
+// an empty value only requires the label to be present.
+type LabelSelector map[string]string
+
+// ParseLabelSelector parses "key=value,key2=value2,key3" into a selector
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	selector := make(LabelSelector)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid label selector %q: missing key", part)
+		}
+		selector[key] = strings.TrimSpace(value)
+	}
+	return selector, nil
+}
+
+// Matches reports whether labels satisfy the selector
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for key, want := range s {
+		got, ok := labels[key]
+		if !ok || (want != "" && got != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// ClusterQuery narrows and groups the cluster view
+type ClusterQuery struct {
+	Selector LabelSelector // Only include nodes matching these labels
+	GroupBy  string        // Group nodes and aggregates by this label key
+}
+
+// labelGroups collects node names and aggregates per value of the grouping label
+type labelGroups struct {
+	nodes      map[string][]string
+	aggregates map[string]*clusterAggregates
+}
+
+func newLabelGroups() *labelGroups {
+	return &labelGroups{
+		nodes:      make(map[string][]string),
+		aggregates: make(map[string]*clusterAggregates),
+	}
+}
+
+// addNode places a node in its group; live nodes also contribute to the group's aggregates
+func (g *labelGroups) addNode(group, nodeName string, gpus map[string]interface{}, labels map[string]string, live bool) {
+	if group == "" {
+		group = noLabelGroup
+	}
+	g.nodes[group] = append(g.nodes[group], nodeName)
+	if g.aggregates[group] == nil {
+		g.aggregates[group] = newClusterAggregates()
+	}
+	if live {
+		g.aggregates[group].addNode(gpus, labels)
+	}
+}
+
+func (g *labelGroups) result() map[string]interface{} {
+	result := make(map[string]interface{}, len(g.nodes))
+	for group, nodes := range g.nodes {
+		sort.Strings(nodes)
+		result[group] = map[string]interface{}{
+			"nodes":      nodes,
+			"aggregates": g.aggregates[group].result(),
+		}
+	}
+	return result
+}
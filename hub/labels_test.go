@@ -0,0 +1,90 @@
+package hub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabelSelector(t *testing.T) {
+	selector, err := ParseLabelSelector("rack=r1, team=ml ,gpu")
+	if err != nil {
+		t.Fatalf("ParseLabelSelector: %v", err)
+	}
+	want := LabelSelector{"rack": "r1", "team": "ml", "gpu": ""}
+	if !reflect.DeepEqual(selector, want) {
+		t.Fatalf("selector = %v, want %v", selector, want)
+	}
+
+	tests := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{map[string]string{"rack": "r1", "team": "ml", "gpu": "hopper"}, true},
+		{map[string]string{"rack": "r1", "team": "ml"}, false},
+		{map[string]string{"rack": "r2", "team": "ml", "gpu": "hopper"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := selector.Matches(tt.labels); got != tt.want {
+			t.Errorf("Matches(%v) = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+
+	if !(LabelSelector{}).Matches(nil) {
+		t.Error("empty selector should match everything")
+	}
+	if _, err := ParseLabelSelector("=r1"); err == nil {
+		t.Error("ParseLabelSelector accepted a selector without key")
+	}
+}
+
+func TestQueryClusterFilterAndGroup(t *testing.T) {
+	h := newHub(nil, "")
+
+	nodes := map[string]map[string]interface{}{
+		"a": {"rack": "r1", "team": "ml"},
+		"b": {"rack": "r1", "team": "infra"},
+		"c": {"rack": "r2", "team": "ml"},
+		"d": {},
+	}
+	for name, labels := range nodes {
+		h.attachAgent(name, "")
+		h.updateNode(agentURL(name), map[string]interface{}{
+			"node_name": name,
+			"labels":    labels,
+			"gpus": map[string]interface{}{
+				"0": map[string]interface{}{"name": "A100", "utilization": 50.0},
+			},
+		})
+	}
+
+	selector, _ := ParseLabelSelector("team=ml")
+	filtered := h.QueryCluster(ClusterQuery{Selector: selector})
+	if got := len(filtered["nodes"].(map[string]interface{})); got != 2 {
+		t.Errorf("team=ml matched %d nodes, want 2", got)
+	}
+	if stats := filtered["cluster_stats"].(map[string]interface{}); stats["total_nodes"] != 2 || stats["total_gpus"] != 2 {
+		t.Errorf("filtered cluster_stats = %v", stats)
+	}
+
+	grouped := h.QueryCluster(ClusterQuery{GroupBy: "rack"})
+	groups := grouped["groups"].(map[string]interface{})
+	wantNodes := map[string][]string{
+		"r1":         {"a", "b"},
+		"r2":         {"c"},
+		noLabelGroup: {"d"},
+	}
+	for group, want := range wantNodes {
+		g, ok := groups[group].(map[string]interface{})
+		if !ok {
+			t.Errorf("missing group %q", group)
+			continue
+		}
+		if !reflect.DeepEqual(g["nodes"], want) {
+			t.Errorf("group %q nodes = %v, want %v", group, g["nodes"], want)
+		}
+		if gpus := g["aggregates"].(map[string]interface{})["gpus"]; gpus != len(want) {
+			t.Errorf("group %q gpus = %v, want %d", group, gpus, len(want))
+		}
+	}
+}
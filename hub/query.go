@@ -0,0 +1,182 @@
+package hub
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gpu-pro/placement"
+)
+
+// ErrGPUNotFound is returned when no node reports a GPU with the requested UUID
+var ErrGPUNotFound = errors.New("GPU not found")
+
+// findClusterNodeLocked looks up a node of the cluster view by name or URL;
+// nodes behind child hubs are found by their prefixed name (h.mu must be held)
+func (h *Hub) findClusterNodeLocked(key string, now time.Time) (clusterNode, bool) {
+	if u, ok := h.findURLLocked(key); ok {
+		key = h.urlToNode[u]
+	}
+	if info, ok := h.nodes[key]; ok {
+		info.mu.RLock()
+		defer info.mu.RUnlock()
+		return info.clusterNode(key, info.statusAt(now, h.staleAfter)), true
+	}
+	for _, node := range h.clusterNodesLocked(now) {
+		if node.depth > 0 && node.name == key {
+			return node, true
+		}
+	}
+	return clusterNode{}, false
+}
+
+// NodeData returns the latest payload a node sent, with its status. Offline
+// nodes return their last known payload, if any.
+func (h *Hub) NodeData(key string) (map[string]interface{}, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	node, ok := h.findClusterNodeLocked(key, time.Now())
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	return map[string]interface{}{
+		"name":        node.name,
+		"status":      node.status,
+		"last_update": node.lastUpdate,
+		"labels":      node.labels,
+		"connection":  node.connection,
+		"data":        node.data,
+	}, nil
+}
+
+// NodeProcesses returns the GPU processes a node last reported
+func (h *Hub) NodeProcesses(key string) (map[string]interface{}, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	node, ok := h.findClusterNodeLocked(key, time.Now())
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+	processes := []interface{}{}
+	if procs, ok := node.data["processes"].([]interface{}); ok {
+		processes = procs
+	}
+	return map[string]interface{}{
+		"name":        node.name,
+		"status":      node.status,
+		"last_update": node.lastUpdate,
+		"processes":   processes,
+	}, nil
+}
+
+// FindGPU looks up a GPU by UUID across all nodes with current data
+func (h *Hub) FindGPU(uuid string) (map[string]interface{}, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, node := range h.clusterNodesLocked(time.Now()) {
+		if node.status != "online" && node.status != "stale" {
+			continue
+		}
+		gpus, _ := node.data["gpus"].(map[string]interface{})
+		for gpuID, raw := range gpus {
+			gpu, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if gpuUUID, _ := gpu["uuid"].(string); gpuUUID == "" || !strings.EqualFold(gpuUUID, uuid) {
+				continue
+			}
+
+			processes := []interface{}{}
+			allProcs, _ := node.data["processes"].([]interface{})
+			for _, p := range allProcs {
+				if proc, ok := p.(map[string]interface{}); ok {
+					if procUUID, _ := proc["gpu_uuid"].(string); strings.EqualFold(procUUID, uuid) {
+						processes = append(processes, proc)
+					}
+				}
+			}
+
+			return map[string]interface{}{
+				"node":        node.name,
+				"gpu_id":      gpuID,
+				"status":      node.status,
+				"last_update": node.lastUpdate,
+				"gpu":         gpu,
+				"processes":   processes,
+			}, nil
+		}
+	}
+	return nil, ErrGPUNotFound
+}
+
+// GPUData returns every GPU with current data, keyed "<node>-<gpu id>" like the dashboard
+func (h *Hub) GPUData() map[string]interface{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make(map[string]interface{})
+	for _, node := range h.clusterNodesLocked(time.Now()) {
+		if node.status != "online" && node.status != "stale" {
+			continue
+		}
+		gpus, _ := node.data["gpus"].(map[string]interface{})
+		for gpuID, raw := range gpus {
+			gpu, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// Copy so the node's shared payload is not modified
+			entry := make(map[string]interface{}, len(gpu)+2)
+			for k, v := range gpu {
+				entry[k] = v
+			}
+			entry["node"] = node.name
+			entry["gpu_id"] = gpuID
+			result[node.name+"-"+gpuID] = entry
+		}
+	}
+	return result
+}
+
+// PlacementNodes returns the GPUs of online nodes matching the selector for placement ranking;
+// stale nodes are left out since their free memory may be outdated
+func (h *Hub) PlacementNodes(selector LabelSelector) []placement.Node {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var nodes []placement.Node
+	for _, node := range h.clusterNodesLocked(time.Now()) {
+		if node.status != "online" || !selector.Matches(node.labels) {
+			continue
+		}
+		gpus, _ := node.data["gpus"].(map[string]interface{})
+		nodes = append(nodes, placement.Node{Name: node.name, GPUs: gpus})
+	}
+	return nodes
+}
+
+// ClusterProcesses returns the GPU processes of every online node
+func (h *Hub) ClusterProcesses() map[string][]map[string]interface{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make(map[string][]map[string]interface{})
+	for _, node := range h.clusterNodesLocked(time.Now()) {
+		if node.status != "online" {
+			continue
+		}
+		procs, _ := node.data["processes"].([]interface{})
+		processes := make([]map[string]interface{}, 0, len(procs))
+		for _, p := range procs {
+			if proc, ok := p.(map[string]interface{}); ok {
+				processes = append(processes, proc)
+			}
+		}
+		result[node.name] = processes
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gpu-pro/config"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gorilla/websocket"
+)
+
+func TestClusterDataEndpoints(t *testing.T) {
+	h := newHub(nil, "")
+	h.attachAgent("worker-1", "")
+	h.updateNode(agentURL("worker-1"), map[string]interface{}{
+		"node_name": "worker-1",
+		"gpus": map[string]interface{}{
+			"0": map[string]interface{}{"name": "A100", "uuid": "GPU-aaaa"},
+			"1": map[string]interface{}{"name": "A100", "uuid": "GPU-bbbb"},
+		},
+		"processes": []interface{}{
+			map[string]interface{}{"pid": 10.0, "gpu_uuid": "GPU-aaaa"},
+			map[string]interface{}{"pid": 11.0, "gpu_uuid": "GPU-bbbb"},
+		},
+	})
+
+	var child map[string]interface{}
+	if err := json.Unmarshal([]byte(childHubPayload), &child); err != nil {
+		t.Fatal(err)
+	}
+	h.attachAgent("dc-eu", "")
+	h.updateNode(agentURL("dc-eu"), child)
+
+	app := fiber.New()
+	RegisterHubHandlers(app, h, &config.Config{})
+
+	get := func(path string, wantStatus int) map[string]interface{} {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("GET %s: status %d, want %d", path, resp.StatusCode, wantStatus)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("GET %s: decode: %v", path, err)
+		}
+		return body
+	}
+
+	if gpus := get("/api/gpu-data", 200)["gpus"].(map[string]interface{}); len(gpus) != 3 || gpus["worker-1-1"] == nil {
+		t.Errorf("/api/gpu-data gpus = %v", gpus)
+	}
+
+	snapshot := get("/api/v1/cluster/snapshot?labels=hub=dc-eu", 200)
+	if snapshot["mode"] != "hub" || len(snapshot["nodes"].(map[string]interface{})) != 2 {
+		t.Errorf("snapshot = %v", snapshot)
+	}
+
+	node := get("/api/v1/nodes/worker-1", 200)
+	if node["status"] != "online" || node["data"].(map[string]interface{})["node_name"] != "worker-1" {
+		t.Errorf("node = %v", node)
+	}
+	if node := get("/api/v1/nodes/dc-eu%2Fgpu01", 200); node["name"] != "dc-eu/gpu01" {
+		t.Errorf("federated node = %v", node)
+	}
+	get("/api/v1/nodes/missing", 404)
+
+	if procs := get("/api/v1/nodes/worker-1/processes", 200)["processes"].([]interface{}); len(procs) != 2 {
+		t.Errorf("processes = %v", procs)
+	}
+
+	gpu := get("/api/v1/gpus/gpu-bbbb", 200)
+	if gpu["node"] != "worker-1" || gpu["gpu_id"] != "1" || len(gpu["processes"].([]interface{})) != 1 {
+		t.Errorf("gpu = %v", gpu)
+	}
+	get("/api/v1/gpus/GPU-missing", 404)
+}
+
+func TestClusterWithoutDashboard(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"node_name":"worker-1","gpus":{"0":{"name":"A100"}}}`)); err != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer node.Close()
+
+	h := newHub([]string{node.URL}, "")
+	defer h.Shutdown()
+	app := fiber.New()
+	RegisterHubHandlers(app, h, &config.Config{})
+
+	// No dashboard ever connects; the REST API still sees the node
+	waitFor(t, func() bool {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/cluster", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var stats map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&stats)
+		return stats["online_nodes"] == 1.0 && stats["total_gpus"] == 1.0
+	})
+}
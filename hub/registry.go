@@ -0,0 +1,267 @@
+package hub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Errors returned by the node registry
+var (
+	ErrInvalidNodeURL = errors.New("invalid node URL")
+	ErrNodeNotFound   = errors.New("node not found")
+	ErrNodeExists     = errors.New("node already registered")
+	ErrNameInUse      = errors.New("node name already in use")
+)
+
+// RegistryEntry is one registered node as persisted to the registry file
+type RegistryEntry struct {
+	URL  string `json:"url"`
+	Name string `json:"name,omitempty"` // Display name overriding the node's reported name
+}
+
+// loadRegistry reads the registry file, returning nil entries if it does not exist yet,
+// and the saved names of nodes that are not registered in it
+func loadRegistry(path string) ([]RegistryEntry, map[string]string, error) {
+	if path == "" {
+		return nil, nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	var registry struct {
+		Nodes   []RegistryEntry   `json:"nodes"`
+		Aliases map[string]string `json:"aliases"`
+	}
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return nil, nil, err
+	}
+	if registry.Nodes == nil {
+		registry.Nodes = []RegistryEntry{}
+	}
+	return registry.Nodes, registry.Aliases, nil
+}
+
+// saveRegistryLocked persists the registered nodes, and separately the names given to
+// discovered nodes and agents (h.mu must be held)
+func (h *Hub) saveRegistryLocked() error {
+	if h.registryPath == "" {
+		return nil
+	}
+
+	entries := make([]RegistryEntry, 0, len(h.nodeURLs))
+	registered := make(map[string]bool, len(h.nodeURLs))
+	for _, u := range h.nodeURLs {
+		// Agents register themselves on every connect, discovered nodes on every refresh
+		if isAgentURL(u) || h.discovered[u] != nil {
+			continue
+		}
+		entries = append(entries, RegistryEntry{URL: u, Name: h.aliases[u]})
+		registered[u] = true
+	}
+	aliases := make(map[string]string)
+	for u, name := range h.aliases {
+		if !registered[u] {
+			aliases[u] = name
+		}
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{"nodes": entries, "aliases": aliases}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := h.registryPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, h.registryPath)
+}
+
+// NormalizeNodeURL validates a node URL and strips trailing slashes
+func NormalizeNodeURL(raw string) (string, error) {
+	raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidNodeURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%w %q: scheme must be http or https", ErrInvalidNodeURL, raw)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("%w %q: missing host", ErrInvalidNodeURL, raw)
+	}
+	return raw, nil
+}
+
+// findURLLocked resolves a node by display name or URL (h.mu must be held)
+func (h *Hub) findURLLocked(key string) (string, bool) {
+	for _, u := range h.nodeURLs {
+		if u == key || h.urlToNode[u] == key {
+			return u, true
+		}
+	}
+	return "", false
+}
+
+// AddNode registers a node at runtime and starts connecting to it if the hub is running
+func (h *Hub) AddNode(rawURL, name string) error {
+	nodeURL, err := NormalizeNodeURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.findURLLocked(nodeURL); exists {
+		return ErrNodeExists
+	}
+	if name != "" && !h.nameFreeLocked(nodeURL, name) {
+		return ErrNameInUse
+	}
+
+	h.addNodeLocked(nodeURL, name)
+	return h.saveRegistryLocked()
+}
+
+// RemoveNode unregisters a node by name or URL and stops its connection
+func (h *Hub) RemoveNode(key string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	nodeURL, ok := h.findURLLocked(key)
+	if !ok {
+		return ErrNodeNotFound
+	}
+
+	h.removeNodeLocked(nodeURL)
+	delete(h.aliases, nodeURL)
+	return h.saveRegistryLocked()
+}
+
+// removeNodeLocked unregisters a node and stops its connection, keeping its display name
+// for when it is discovered again (h.mu must be held)
+func (h *Hub) removeNodeLocked(nodeURL string) {
+	if stop, running := h.stopChans[nodeURL]; running {
+		close(stop)
+		delete(h.stopChans, nodeURL)
+	}
+
+	nodeName := h.urlToNode[nodeURL]
+	if node, exists := h.nodes[nodeName]; exists && node.URL == nodeURL {
+		node.mu.Lock()
+		if node.conn != nil {
+			node.conn.Close()
+		}
+		node.mu.Unlock()
+		delete(h.nodes, nodeName)
+	}
+	delete(h.urlToNode, nodeURL)
+	delete(h.discovered, nodeURL)
+
+	for i, u := range h.nodeURLs {
+		if u == nodeURL {
+			h.nodeURLs = append(h.nodeURLs[:i], h.nodeURLs[i+1:]...)
+			break
+		}
+	}
+}
+
+// RenameNode sets the display name of a node; an empty name reverts to the node's reported name
+func (h *Hub) RenameNode(key, name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	nodeURL, ok := h.findURLLocked(key)
+	if !ok {
+		return ErrNodeNotFound
+	}
+
+	if name == "" {
+		delete(h.aliases, nodeURL)
+		// The reported name is picked up again with the node's next update
+		name = nodeURL
+		if node, exists := h.nodes[h.urlToNode[nodeURL]]; exists {
+			node.mu.RLock()
+			if reported, ok := node.Data["node_name"].(string); ok && reported != "" {
+				name = reported
+			}
+			node.mu.RUnlock()
+		}
+	} else {
+		if !h.nameFreeLocked(nodeURL, name) {
+			return ErrNameInUse
+		}
+		h.aliases[nodeURL] = name
+	}
+
+	h.renameLocked(nodeURL, name)
+	return h.saveRegistryLocked()
+}
+
+// nodeSourceLocked describes how a node was registered (h.mu must be held)
+func (h *Hub) nodeSourceLocked(nodeURL string) string {
+	if isAgentURL(nodeURL) {
+		return "agent"
+	}
+	if sources := h.discovered[nodeURL]; len(sources) > 0 {
+		names := make([]string, 0, len(sources))
+		for source := range sources {
+			names = append(names, source)
+		}
+		sort.Strings(names)
+		return strings.Join(names, ",")
+	}
+	return "registry"
+}
+
+// ListNodes returns all registered nodes with their connection status, sorted by name
+func (h *Hub) ListNodes() []map[string]interface{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	now := time.Now()
+	nodes := make([]map[string]interface{}, 0, len(h.nodeURLs))
+	for _, u := range h.nodeURLs {
+		nodeName := h.urlToNode[u]
+		entry := map[string]interface{}{
+			"name":        nodeName,
+			"url":         u,
+			"status":      "offline",
+			"last_update": "",
+			"renamed":     h.aliases[u] != "",
+			"agent":       isAgentURL(u),
+			"labels":      map[string]string{},
+			"source":      h.nodeSourceLocked(u),
+		}
+		if node, ok := h.nodes[nodeName]; ok {
+			node.mu.RLock()
+			entry["status"] = node.statusAt(now, h.staleAfter)
+			entry["last_update"] = node.LastUpdate
+			entry["connection"] = node.connectionStats()
+			entry["labels"] = nodeLabels(node.Data)
+			if isHubPayload(node.Data) {
+				entry["federated_nodes"] = federatedNodeCount(node.Data)
+			}
+			node.mu.RUnlock()
+		}
+		nodes = append(nodes, entry)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i]["name"].(string) < nodes[j]["name"].(string)
+	})
+	return nodes
+}
@@ -0,0 +1,149 @@
+package hub
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRegistryAddRenameRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hub-nodes.json")
+	h := newHub([]string{"http://node1:1312"}, path)
+
+	if err := h.AddNode("http://node2:1312/", "gpu-box"); err != nil {
+		t.Fatalf("AddNode: %v", err)
+	}
+	if err := h.AddNode("http://node2:1312", ""); !errors.Is(err, ErrNodeExists) {
+		t.Errorf("duplicate AddNode error = %v, want ErrNodeExists", err)
+	}
+	if err := h.AddNode("node3:1312", ""); !errors.Is(err, ErrInvalidNodeURL) {
+		t.Errorf("AddNode without scheme error = %v, want ErrInvalidNodeURL", err)
+	}
+	if err := h.RenameNode("http://node1:1312", "gpu-box"); !errors.Is(err, ErrNameInUse) {
+		t.Errorf("RenameNode to taken name error = %v, want ErrNameInUse", err)
+	}
+	if err := h.RenameNode("http://node1:1312", "trainer"); err != nil {
+		t.Fatalf("RenameNode: %v", err)
+	}
+
+	// The registry file keeps its names on restart; NODE_URLS missing from it are added
+	restored := newHub([]string{"http://node1:1312", "http://node5:1312"}, path)
+	nodes := restored.ListNodes()
+	if len(nodes) != 3 {
+		t.Fatalf("restored %d nodes, want 3: %v", len(nodes), nodes)
+	}
+	if nodes[0]["name"] != "gpu-box" || nodes[0]["url"] != "http://node2:1312" {
+		t.Errorf("nodes[0] = %v, want gpu-box at http://node2:1312", nodes[0])
+	}
+	if nodes[1]["name"] != "http://node5:1312" {
+		t.Errorf("nodes[1] = %v, want http://node5:1312 from NODE_URLS", nodes[1])
+	}
+	if nodes[2]["name"] != "trainer" || nodes[2]["url"] != "http://node1:1312" {
+		t.Errorf("nodes[2] = %v, want trainer at http://node1:1312", nodes[2])
+	}
+
+	if err := restored.RemoveNode("trainer"); err != nil {
+		t.Fatalf("RemoveNode: %v", err)
+	}
+	if err := restored.RemoveNode("trainer"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("second RemoveNode error = %v, want ErrNodeNotFound", err)
+	}
+	if nodes := newHub(nil, path).ListNodes(); len(nodes) != 2 {
+		t.Errorf("after removal restored %d nodes, want 2: %v", len(nodes), nodes)
+	}
+}
+
+func TestAddNodeConnectsAndRemoveDisconnects(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"node_name":"worker-1","gpus":{}}`)); err != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	h := newHub(nil, "")
+	h.running = true
+	h.connecting = true
+	defer h.Shutdown()
+
+	if err := h.AddNode(server.URL, ""); err != nil {
+		t.Fatalf("AddNode: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		nodes := h.ListNodes()
+		return len(nodes) == 1 && nodes[0]["name"] == "worker-1" && nodes[0]["status"] == "online"
+	})
+
+	if err := h.RemoveNode("worker-1"); err != nil {
+		t.Fatalf("RemoveNode: %v", err)
+	}
+
+	// A message already in flight must not resurrect the node
+	time.Sleep(100 * time.Millisecond)
+	if stats := h.GetClusterData()["cluster_stats"].(map[string]interface{}); stats["total_nodes"] != 0 {
+		t.Errorf("total_nodes after removal = %v, want 0", stats["total_nodes"])
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("condition not met within 5s")
+}
+
+func TestDuplicateReportedNames(t *testing.T) {
+	h := newHub([]string{"http://a:1312", "http://b:1312"}, "")
+	for _, u := range h.nodeURLs {
+		h.stopChans[u] = make(chan struct{})
+	}
+
+	// The first node reporting a name keeps it; others are shown by URL
+	h.updateNode("http://a:1312", map[string]interface{}{"node_name": "gpu01"})
+	h.updateNode("http://b:1312", map[string]interface{}{"node_name": "gpu01"})
+	h.updateNode("http://a:1312", map[string]interface{}{"node_name": "gpu01"})
+	url, stop, _ := h.attachAgent("gpu01", "")
+	defer h.detachAgent(url, stop, nil)
+	h.updateNode(url, map[string]interface{}{"node_name": "gpu01"})
+
+	names := map[string]string{}
+	for _, n := range h.ListNodes() {
+		names[n["url"].(string)] = n["name"].(string)
+	}
+	want := map[string]string{"http://a:1312": "gpu01", "http://b:1312": "http://b:1312", url: url}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	if err := h.RenameNode("http://b:1312", "gpu01"); !errors.Is(err, ErrNameInUse) {
+		t.Errorf("RenameNode to a reported name error = %v, want ErrNameInUse", err)
+	}
+
+	// Removing by name removes the node holding it, not the others
+	if err := h.RemoveNode("gpu01"); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := h.ListNodes(); len(nodes) != 2 {
+		t.Errorf("after removal %d nodes, want 2: %v", len(nodes), nodes)
+	}
+}
@@ -0,0 +1,201 @@
+package kubernetes
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+const (
+	// DefaultPodResourcesSocket is the kubelet PodResources API socket path
+	DefaultPodResourcesSocket = "/var/lib/kubelet/pod-resources/kubelet.sock"
+
+	// Resource name prefix advertised by the NVIDIA device plugin (nvidia.com/gpu, nvidia.com/mig-1g.5gb, ...)
+	gpuResourcePrefix = "nvidia.com/"
+
+	listTimeout = 5 * time.Second
+)
+
+// PodAllocation identifies the pod container a GPU was allocated to
+type PodAllocation struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+}
+
+// PodResourcesClient maps GPU UUIDs to pods using the kubelet PodResources gRPC API
+type PodResourcesClient struct {
+	socket          string
+	refreshInterval time.Duration
+	allocations     map[string][]PodAllocation // GPU UUID -> pod containers sharing it
+	mu              sync.RWMutex
+	stopChan        chan bool
+	isRunning       bool
+}
+
+// NewPodResourcesClient creates a client for the kubelet PodResources socket
+func NewPodResourcesClient(socket string, refreshInterval time.Duration) *PodResourcesClient {
+	if socket == "" {
+		socket = DefaultPodResourcesSocket
+	}
+	return &PodResourcesClient{
+		socket:          socket,
+		refreshInterval: refreshInterval,
+		allocations:     make(map[string][]PodAllocation),
+		stopChan:        make(chan bool),
+	}
+}
+
+// Start refreshes allocations in the background
+func (c *PodResourcesClient) Start() {
+	if c.isRunning {
+		return
+	}
+	c.isRunning = true
+
+	go func() {
+		c.refresh()
+
+		ticker := time.NewTicker(c.refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.refresh()
+			case <-c.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background refresh
+func (c *PodResourcesClient) Stop() {
+	if !c.isRunning {
+		return
+	}
+	c.isRunning = false
+	close(c.stopChan)
+}
+
+func (c *PodResourcesClient) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+
+	allocations, err := c.List(ctx)
+	if err != nil {
+		log.Printf("Failed to list kubelet pod resources from %s: %v", c.socket, err)
+		return
+	}
+
+	c.mu.Lock()
+	c.allocations = allocations
+	c.mu.Unlock()
+}
+
+// List queries the kubelet and returns the pod allocations of every GPU device.
+// With time-slicing several pods can share one GPU, so a UUID may map to more
+// than one pod container. MIG devices are listed under their MIG UUID, which the
+// monitor does not report, so MIG-partitioned GPUs are not attributed.
+func (c *PodResourcesClient) List(ctx context.Context) (map[string][]PodAllocation, error) {
+	conn, err := grpc.NewClient("unix://"+c.socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	resp, err := podresourcesapi.NewPodResourcesListerClient(conn).List(ctx, &podresourcesapi.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	allocations := make(map[string][]PodAllocation)
+	for _, pod := range resp.GetPodResources() {
+		for _, container := range pod.GetContainers() {
+			for _, devices := range container.GetDevices() {
+				if !strings.HasPrefix(devices.GetResourceName(), gpuResourcePrefix) {
+					continue
+				}
+				alloc := PodAllocation{
+					Namespace: pod.GetNamespace(),
+					Pod:       pod.GetName(),
+					Container: container.GetName(),
+				}
+				for _, id := range devices.GetDeviceIds() {
+					uuid := normalizeDeviceID(id)
+					if !containsAllocation(allocations[uuid], alloc) {
+						allocations[uuid] = append(allocations[uuid], alloc)
+					}
+				}
+			}
+		}
+	}
+
+	return allocations, nil
+}
+
+// Allocations returns the pod containers a GPU UUID is allocated to
+func (c *PodResourcesClient) Allocations(uuid string) []PodAllocation {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.allocations[uuid]
+}
+
+// EnrichGPU adds pod attribution to a GPU entry. A GPU time-sliced between
+// several pods is marked shared and lists all of them instead.
+func (c *PodResourcesClient) EnrichGPU(gpu map[string]interface{}) {
+	uuid, ok := gpu["uuid"].(string)
+	if !ok {
+		return
+	}
+	allocs := c.Allocations(uuid)
+	switch {
+	case len(allocs) == 1:
+		apply(gpu, allocs[0])
+	case len(allocs) > 1:
+		gpu["k8s_shared"] = true
+		gpu["k8s_pods"] = allocs
+	}
+}
+
+// EnrichProcess adds pod attribution to a process entry based on the GPU it runs on.
+// Processes on a shared GPU are left unattributed since the owning pod is ambiguous.
+func (c *PodResourcesClient) EnrichProcess(proc map[string]interface{}) {
+	uuid, ok := proc["gpu_uuid"].(string)
+	if !ok {
+		return
+	}
+	if allocs := c.Allocations(uuid); len(allocs) == 1 {
+		apply(proc, allocs[0])
+	}
+}
+
+func apply(entry map[string]interface{}, alloc PodAllocation) {
+	entry["k8s_namespace"] = alloc.Namespace
+	entry["k8s_pod"] = alloc.Pod
+	entry["k8s_container"] = alloc.Container
+}
+
+func containsAllocation(allocs []PodAllocation, alloc PodAllocation) bool {
+	for _, a := range allocs {
+		if a == alloc {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeDeviceID strips the replica suffix the device plugin adds when time-slicing is enabled ("GPU-xxx::1")
+func normalizeDeviceID(id string) string {
+	if idx := strings.Index(id, "::"); idx >= 0 {
+		return id[:idx]
+	}
+	return id
+}
@@ -0,0 +1,172 @@
+package kubernetes
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+// fakePodResourcesServer serves a fixed pod list like the kubelet would
+type fakePodResourcesServer struct {
+	podresourcesapi.UnimplementedPodResourcesListerServer
+	pods []*podresourcesapi.PodResources
+}
+
+func (s *fakePodResourcesServer) List(ctx context.Context, req *podresourcesapi.ListPodResourcesRequest) (*podresourcesapi.ListPodResourcesResponse, error) {
+	return &podresourcesapi.ListPodResourcesResponse{PodResources: s.pods}, nil
+}
+
+func startFakeKubelet(t *testing.T, pods []*podresourcesapi.PodResources) string {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "kubelet.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen on %s: %v", socket, err)
+	}
+
+	server := grpc.NewServer()
+	podresourcesapi.RegisterPodResourcesListerServer(server, &fakePodResourcesServer{pods: pods})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	return socket
+}
+
+func TestPodResourcesList(t *testing.T) {
+	socket := startFakeKubelet(t, []*podresourcesapi.PodResources{
+		{
+			Name:      "trainer-0",
+			Namespace: "ml",
+			Containers: []*podresourcesapi.ContainerResources{
+				{
+					Name: "pytorch",
+					Devices: []*podresourcesapi.ContainerDevices{
+						{ResourceName: "nvidia.com/gpu", DeviceIds: []string{"GPU-aaaa", "GPU-bbbb::2"}},
+						{ResourceName: "example.com/nic", DeviceIds: []string{"nic-0"}},
+					},
+				},
+			},
+		},
+		{
+			Name:      "notebook",
+			Namespace: "research",
+			Containers: []*podresourcesapi.ContainerResources{
+				{
+					Name: "jupyter",
+					Devices: []*podresourcesapi.ContainerDevices{
+						{ResourceName: "nvidia.com/mig-1g.10gb", DeviceIds: []string{"MIG-cccc"}},
+					},
+				},
+			},
+		},
+	})
+
+	client := NewPodResourcesClient(socket, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	allocations, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	want := map[string]PodAllocation{
+		"GPU-aaaa": {Namespace: "ml", Pod: "trainer-0", Container: "pytorch"},
+		"GPU-bbbb": {Namespace: "ml", Pod: "trainer-0", Container: "pytorch"},
+		"MIG-cccc": {Namespace: "research", Pod: "notebook", Container: "jupyter"},
+	}
+	if len(allocations) != len(want) {
+		t.Fatalf("got %d allocations, want %d: %v", len(allocations), len(want), allocations)
+	}
+	for uuid, alloc := range want {
+		if len(allocations[uuid]) != 1 || allocations[uuid][0] != alloc {
+			t.Errorf("allocation for %s = %+v, want %+v", uuid, allocations[uuid], alloc)
+		}
+	}
+}
+
+func TestPodResourcesEnrich(t *testing.T) {
+	socket := startFakeKubelet(t, []*podresourcesapi.PodResources{
+		{
+			Name:      "trainer-0",
+			Namespace: "ml",
+			Containers: []*podresourcesapi.ContainerResources{
+				{
+					Name:    "pytorch",
+					Devices: []*podresourcesapi.ContainerDevices{{ResourceName: "nvidia.com/gpu", DeviceIds: []string{"GPU-aaaa"}}},
+				},
+			},
+		},
+	})
+
+	client := NewPodResourcesClient(socket, time.Minute)
+	client.refresh()
+
+	gpu := map[string]interface{}{"uuid": "GPU-aaaa"}
+	client.EnrichGPU(gpu)
+	if gpu["k8s_namespace"] != "ml" || gpu["k8s_pod"] != "trainer-0" || gpu["k8s_container"] != "pytorch" {
+		t.Errorf("GPU not enriched: %v", gpu)
+	}
+
+	proc := map[string]interface{}{"gpu_uuid": "GPU-aaaa"}
+	client.EnrichProcess(proc)
+	if proc["k8s_pod"] != "trainer-0" {
+		t.Errorf("process not enriched: %v", proc)
+	}
+
+	unallocated := map[string]interface{}{"uuid": "GPU-zzzz"}
+	client.EnrichGPU(unallocated)
+	if _, ok := unallocated["k8s_pod"]; ok {
+		t.Errorf("unallocated GPU should not be enriched: %v", unallocated)
+	}
+}
+
+func TestPodResourcesTimeSlicedGPU(t *testing.T) {
+	pod := func(name string, ids ...string) *podresourcesapi.PodResources {
+		return &podresourcesapi.PodResources{
+			Name:      name,
+			Namespace: "ml",
+			Containers: []*podresourcesapi.ContainerResources{
+				{
+					Name:    "worker",
+					Devices: []*podresourcesapi.ContainerDevices{{ResourceName: "nvidia.com/gpu", DeviceIds: ids}},
+				},
+			},
+		}
+	}
+	socket := startFakeKubelet(t, []*podresourcesapi.PodResources{
+		pod("infer-a", "GPU-aaaa::0", "GPU-aaaa::1"),
+		pod("infer-b", "GPU-aaaa::2"),
+	})
+
+	client := NewPodResourcesClient(socket, time.Minute)
+	client.refresh()
+
+	if allocs := client.Allocations("GPU-aaaa"); len(allocs) != 2 {
+		t.Fatalf("expected both pods on the shared GPU, got %+v", allocs)
+	}
+
+	gpu := map[string]interface{}{"uuid": "GPU-aaaa"}
+	client.EnrichGPU(gpu)
+	if gpu["k8s_shared"] != true {
+		t.Errorf("shared GPU not marked shared: %v", gpu)
+	}
+	if _, ok := gpu["k8s_pod"]; ok {
+		t.Errorf("shared GPU should not be attributed to a single pod: %v", gpu)
+	}
+	if pods, _ := gpu["k8s_pods"].([]PodAllocation); len(pods) != 2 || pods[0].Pod != "infer-a" || pods[1].Pod != "infer-b" {
+		t.Errorf("k8s_pods = %v, want infer-a and infer-b", gpu["k8s_pods"])
+	}
+
+	proc := map[string]interface{}{"gpu_uuid": "GPU-aaaa"}
+	client.EnrichProcess(proc)
+	if _, ok := proc["k8s_pod"]; ok {
+		t.Errorf("process on a shared GPU should be left unattributed: %v", proc)
+	}
+}
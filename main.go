@@ -1,20 +1,32 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"embed"
 	"fmt"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"gpu-pro/accounting"
+	"gpu-pro/analytics"
+	"gpu-pro/auth"
 	"gpu-pro/config"
+	"gpu-pro/energy"
 	"gpu-pro/handlers"
 	"gpu-pro/hub"
+	"gpu-pro/kubernetes"
 	"gpu-pro/monitor"
+	"gpu-pro/mounts"
+	"gpu-pro/ratelimit"
+	"gpu-pro/reservation"
+	"gpu-pro/tlsutil"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
@@ -41,13 +53,34 @@ func main() {
 		log.Println("Debug mode enabled")
 	}
 
+	// Anonymous usage heartbeat, set up before the monitor or hub starts it
+	analytics.Configure(analytics.Settings{
+		Enabled:  cfg.AnalyticsEnabled,
+		Endpoint: cfg.AnalyticsEndpoint,
+		DryRun:   cfg.AnalyticsDryRun,
+		Interval: time.Duration(cfg.AnalyticsInterval * float64(time.Second)),
+	})
+	if !cfg.AnalyticsEnabled {
+		log.Println("Analytics heartbeat disabled")
+	} else if cfg.AnalyticsDryRun {
+		log.Println("Analytics heartbeat in dry-run mode; payloads are logged, not sent")
+	}
+
 	// Create Fiber app with disabled prefork to avoid signal issues
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: false,
 		AppName:               "GPU Pro v2.0",
 		DisableKeepalive:      false,
 		Prefork:               false, // Disable prefork to prevent signal handling conflicts
+		BodyLimit:             cfg.BodyLimit,
+		// Client addresses for rate limits and logs behind a reverse proxy
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableTrustedProxyCheck: len(cfg.TrustedProxies) > 0,
+		TrustedProxies:          cfg.TrustedProxies,
 	})
+	if cfg.ProxyHeader != "" && len(cfg.TrustedProxies) == 0 {
+		log.Printf("WARNING: %s is trusted from any client; set TRUSTED_PROXIES unless GPU Pro is only reachable through the proxy", cfg.ProxyHeader)
+	}
 
 	// Serve static files from embedded FS
 	staticFS, err := fs.Sub(embeddedFiles, "static")
@@ -59,6 +92,65 @@ func main() {
 		Root: http.FS(staticFS),
 	}))
 
+	// Authentication for the dashboard, REST API and WebSocket (static files stay public)
+	authenticator, err := auth.NewAuthenticator(cfg.AuthTokens, cfg.AuthUsersFile, time.Duration(cfg.SessionTTL*float64(time.Hour)))
+	if err != nil {
+		log.Fatalf("Failed to set up authentication: %v", err)
+	}
+	if err := authenticator.SetRoles(cfg.AuthRoles, cfg.AuthDefaultRole); err != nil {
+		log.Fatalf("Failed to set up roles: %v", err)
+	}
+	if cfg.OIDCIssuerURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		provider, err := auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+			IssuerURL:     cfg.OIDCIssuerURL,
+			ClientID:      cfg.OIDCClientID,
+			ClientSecret:  cfg.OIDCClientSecret,
+			RedirectURL:   cfg.OIDCRedirectURL,
+			Scopes:        cfg.OIDCScopes,
+			UsernameClaim: cfg.OIDCUsernameClaim,
+			GroupsClaim:   cfg.OIDCGroupsClaim,
+			GroupRoles:    cfg.OIDCGroupRoles,
+		})
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to set up OIDC login: %v", err)
+		}
+		authenticator.SetOIDC(provider)
+		log.Printf("OIDC single sign-on enabled (issuer: %s)", cfg.OIDCIssuerURL)
+	}
+
+	// Audit trail of privileged calls, recorded before authorization so denied calls are kept too
+	auditLog := auth.NewAuditLog(cfg.AuditLog)
+	app.Use(auth.Audit(auditLog))
+
+	if authenticator.Enabled() {
+		loginPage, err := embeddedFiles.ReadFile("templates/login.html")
+		if err != nil {
+			log.Fatalf("Failed to load login.html: %v", err)
+		}
+		// Limits guessing of tokens and passwords, before the caller is known
+		app.Use(ratelimit.AuthFailures(cfg.RateLimitAuth, time.Minute))
+		app.Use(auth.Middleware(authenticator))
+		if err := auth.RegisterHandlers(app, authenticator, loginPage); err != nil {
+			log.Fatalf("Failed to set up login page: %v", err)
+		}
+		log.Printf("Authentication enabled (%d API token(s), users file: %q)", len(cfg.AuthTokens), cfg.AuthUsersFile)
+	} else {
+		log.Println("WARNING: authentication is disabled; set AUTH_TOKENS, AUTH_USERS_FILE or OIDC_ISSUER_URL to protect the dashboard and API")
+	}
+
+	// Per-client request budgets, keyed by the identity set above (or the IP address)
+	app.Use(ratelimit.Middleware(ratelimit.Config{
+		Max: cfg.RateLimit,
+		Groups: []ratelimit.Group{
+			{Name: "history", Max: cfg.RateLimitHistory, Endpoints: ratelimit.HistoryEndpoints},
+			{Name: "writes", Max: cfg.RateLimitWrites, Endpoints: ratelimit.WriteEndpoints},
+		},
+		Exempt: []string{"/static/", "/socket.io/", "/api/v1/agents/connect"},
+	}))
+	auth.RegisterAuditHandlers(app, auditLog)
+
 	// Index page
 	app.Get("/", func(c *fiber.Ctx) error {
 		data, err := embeddedFiles.ReadFile("templates/index.html")
@@ -69,29 +161,77 @@ func main() {
 		return c.Send(data)
 	})
 
+	// Client certificate and CA for hub -> node and agent -> hub connections
+	var peerCerts *tlsutil.CertReloader
+	var peerTLS *tls.Config
+	if cfg.TLSPeerCertFile != "" {
+		peerCerts, err = tlsutil.NewCertReloader(cfg.TLSPeerCertFile, cfg.TLSPeerKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load peer certificate: %v", err)
+		}
+		if err := peerCerts.Watch(); err != nil {
+			log.Printf("Cannot watch %s, rotating it requires a restart: %v", cfg.TLSPeerCertFile, err)
+		}
+	}
+	if peerCerts != nil || cfg.TLSPeerCAFile != "" {
+		peerTLS, err = tlsutil.ClientConfig(peerCerts, cfg.TLSPeerCAFile)
+		if err != nil {
+			log.Fatalf("Failed to set up peer TLS: %v", err)
+		}
+	}
+
 	// Mode selection
 	var monitorOrHub interface{}
+	var podResources *kubernetes.PodResourcesClient
+	var sampler *handlers.Sampler
+	var ledger *accounting.Ledger
+	var energyTracker *energy.Tracker
+	var agent *handlers.Agent
+	var discovery *hub.Discovery
+	var reservationWatcher *reservation.Watcher
+	var filesystemMonitor *mounts.Monitor
+	var reservationSource reservation.ProcessSource
+	var reservationNode string // Default node of new leases; a hub requires one per lease
 
 	if cfg.Mode == "hub" {
 		// Hub mode: aggregate data from multiple nodes
-		if len(cfg.NodeURLs) == 0 {
-			log.Fatal("Hub mode requires NODE_URLS environment variable")
-		}
-
 		log.Println("Starting GPU Pro in HUB mode")
-		log.Printf("Connecting to %d node(s): %v", len(cfg.NodeURLs), cfg.NodeURLs)
 
-		h := hub.NewHub(cfg.NodeURLs)
-		hub.RegisterHubHandlers(app, h)
-		monitorOrHub = h
+		h := hub.NewHub(cfg.NodeURLs, cfg.NodesFile)
+		h.SetStaleAfter(time.Duration(float64(cfg.StaleIntervals) * cfg.UpdateInterval * float64(time.Second)))
+		h.SetNodeToken(cfg.NodeToken)
+		h.SetTLSConfig(peerTLS)
 
-		// API endpoint for hub mode
-		app.Get("/api/gpu-data", func(c *fiber.Ctx) error {
-			return c.JSON(fiber.Map{
-				"gpus":      fiber.Map{},
-				"timestamp": "hub_mode",
-			})
-		})
+		// Optional node discovery from DNS and a file_sd targets file
+		var discoverers []hub.Discoverer
+		for _, name := range cfg.DiscoverySRV {
+			discoverers = append(discoverers, hub.NewSRVDiscoverer(name, cfg.DiscoveryScheme))
+		}
+		for _, host := range cfg.DiscoveryHosts {
+			d, err := hub.NewADiscoverer(host, cfg.DiscoveryScheme)
+			if err != nil {
+				log.Fatalf("Invalid DISCOVERY_DNS_A entry: %v", err)
+			}
+			discoverers = append(discoverers, d)
+		}
+		if cfg.DiscoveryFile != "" {
+			discoverers = append(discoverers, hub.NewFileDiscoverer(cfg.DiscoveryFile, cfg.DiscoveryScheme))
+		}
+		if len(discoverers) > 0 {
+			discovery = hub.NewDiscovery(h, time.Duration(cfg.DiscoveryInterval*float64(time.Second)), discoverers...)
+			discovery.Start()
+			log.Printf("Node discovery enabled (%d source(s), refresh every %.0fs)", len(discoverers), cfg.DiscoveryInterval)
+		}
+
+		nodes := h.ListNodes()
+		if len(nodes) == 0 && discovery == nil {
+			log.Printf("No nodes registered yet; add nodes with NODE_URLS, discovery or POST /api/v1/nodes")
+		} else {
+			log.Printf("Connecting to %d node(s) (registry: %s)", len(nodes), cfg.NodesFile)
+		}
+		hub.RegisterHubHandlers(app, h, cfg)
+		monitorOrHub = h
+		reservationSource = h.ClusterProcesses
 
 	} else {
 		// Default mode: monitor local GPUs
@@ -99,8 +239,89 @@ func main() {
 		log.Printf("Node name: %s", cfg.NodeName)
 
 		mon := monitor.NewGPUMonitor()
+
+		// Optional Kubernetes pod attribution via the kubelet PodResources API
+		if cfg.PodResources {
+			socket := cfg.PodResourcesSocket
+			if socket == "" {
+				socket = kubernetes.DefaultPodResourcesSocket
+			}
+			log.Printf("Kubernetes pod attribution enabled (socket: %s)", socket)
+			podResources = kubernetes.NewPodResourcesClient(socket, 10*time.Second)
+			podResources.Start()
+			mon.AddEnricher(podResources)
+		}
+
 		handlers.RegisterHandlers(app, mon, cfg)
+		handlers.RegisterPlacementHandlers(app, mon, cfg.NodeName)
+
+		// Sandboxed background scans behind /api/largest-files
+		fileScanner := handlers.NewFileScanner(handlers.NewFileSandbox(cfg.FileScanRoots), handlers.ScanLimits{
+			MaxDepth: cfg.FileScanMaxDepth,
+			MaxFiles: int64(cfg.FileScanMaxFiles),
+			Timeout:  time.Duration(cfg.FileScanTimeout * float64(time.Second)),
+		}, time.Duration(cfg.FileScanCacheTTL*float64(time.Second)))
+		fileScanner.SetBudget(ratelimit.NewBudget("scans", cfg.RateLimitScans, time.Minute))
+		handlers.RegisterFileScanHandlers(app, fileScanner)
+		log.Printf("File scans limited to %v", fileScanner.Sandbox().Roots())
+
+		// Usage, inodes and fill predictions of every mounted filesystem
+		filesystemMonitor = mounts.NewMonitor(mounts.Filter{
+			ExcludeTypes:  cfg.FSExcludeTypes,
+			ExcludeMounts: cfg.FSExcludeMounts,
+		}, time.Duration(cfg.FSPredictionWindow*float64(time.Second)), "gpu-alerts.log", handlers.FilesystemRules)
+		filesystemMonitor.Start(time.Duration(cfg.SampleInterval * float64(time.Second)))
+		handlers.RegisterFilesystemHandlers(app, filesystemMonitor)
+
+		// Geolocation of external connections for the world map
+		geoProvider, err := handlers.NewGeoIPProvider(cfg)
+		if err != nil {
+			log.Fatalf("Failed to set up GeoIP: %v", err)
+		}
+		if geoProvider != nil {
+			handlers.SetGeoIPProvider(geoProvider)
+			log.Printf("Connection geolocation enabled (provider: %s)", geoProvider.Name())
+		} else {
+			log.Printf("Connection geolocation disabled; set GEOIP_DATABASE to a MaxMind-format database to enable it")
+		}
+
+		// Background sampling for usage accounting and energy tracking
+		if cfg.AccountingEnabled || cfg.EnergyTracking {
+			sampler = handlers.NewSampler(mon, time.Duration(cfg.SampleInterval*float64(time.Second)))
+		}
+		if cfg.AccountingEnabled {
+			ledger = accounting.NewLedger(cfg.AccountingFile)
+			ledger.SetRetention(time.Duration(cfg.AccountingRetention * 24 * float64(time.Hour)))
+			handlers.RegisterAccountingHandlers(app, sampler, ledger)
+			log.Printf("Usage accounting enabled (ledger: %s)", cfg.AccountingFile)
+		}
+		if cfg.EnergyTracking {
+			energyTracker = energy.NewTracker(cfg.EnergyFile, energy.Pricing{
+				PricePerKWh:     cfg.ElectricityPrice,
+				Currency:        cfg.ElectricityCurrency,
+				CarbonIntensity: cfg.CarbonIntensity,
+			})
+			mon.AddEnricher(energyTracker)
+			handlers.RegisterEnergyHandlers(app, sampler, energyTracker)
+			log.Printf("Energy tracking enabled (%.4f %s/kWh, %.0f gCO2/kWh)", cfg.ElectricityPrice, cfg.ElectricityCurrency, cfg.CarbonIntensity)
+		}
+		if sampler != nil {
+			sampler.Start()
+		}
+
+		// Push-mode agent: stream samples to a hub over an outbound connection
+		if cfg.HubURL != "" {
+			if cfg.AgentToken == "" {
+				log.Printf("⚠️  HUB_URL is set without AGENT_TOKEN; the hub will reject this agent")
+			}
+			agent = handlers.NewAgent(mon, cfg)
+			agent.SetTLSConfig(peerTLS)
+			agent.Start()
+			log.Printf("Agent mode enabled, pushing samples to %s", cfg.HubURL)
+		}
 		monitorOrHub = mon
+		reservationSource = handlers.LocalProcessSource(mon, cfg.NodeName)
+		reservationNode = cfg.NodeName
 
 		// API endpoint for monitor mode
 		app.Get("/api/gpu-data", func(c *fiber.Ctx) error {
@@ -115,6 +336,18 @@ func main() {
 		})
 	}
 
+	// What the analytics heartbeat sends, and where
+	handlers.RegisterAnalyticsHandlers(app)
+
+	// Advisory GPU reservations, checked against running processes
+	if cfg.ReservationsEnabled {
+		store := reservation.NewStore(cfg.ReservationsFile)
+		reservationWatcher = reservation.NewWatcher(store, "gpu-alerts.log", reservationSource)
+		reservationWatcher.Start(time.Duration(cfg.SampleInterval * float64(time.Second)))
+		handlers.RegisterReservationHandlers(app, store, reservationWatcher, reservationNode)
+		log.Printf("GPU reservations enabled (store: %s)", cfg.ReservationsFile)
+	}
+
 	// Upgrade WebSocket connections
 	app.Use("/socket.io/", func(c *fiber.Ctx) error {
 		if websocket.IsWebSocketUpgrade(c) {
@@ -126,14 +359,47 @@ func main() {
 	// Start server
 	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 
+	// Native TLS, optionally requiring client certificates from a CA (mutual TLS)
+	var serverCerts *tlsutil.CertReloader
+	var serverTLS *tls.Config
+	scheme := "http"
+	if cfg.TLSCertFile != "" {
+		serverCerts, err = tlsutil.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		if err := serverCerts.Watch(); err != nil {
+			log.Printf("Cannot watch %s, rotating it requires a restart: %v", cfg.TLSCertFile, err)
+		}
+		serverTLS, err = tlsutil.ServerConfig(serverCerts, cfg.TLSClientCAFile, cfg.TLSClientAuth != "optional")
+		if err != nil {
+			log.Fatalf("Failed to set up TLS: %v", err)
+		}
+		scheme = "https"
+		if cfg.TLSClientCAFile != "" {
+			log.Printf("Mutual TLS enabled (client certificates: %s, CA: %s)", cfg.TLSClientAuth, cfg.TLSClientCAFile)
+		}
+	}
+
 	// Start server in a goroutine
 	serverErr := make(chan error, 1)
 	go func() {
 		log.Printf("🚀 GPU Pro server starting on %s", addr)
-		log.Printf("📊 Access the dashboard at http://%s", addr)
+		log.Printf("📊 Access the dashboard at %s://%s", scheme, addr)
 		log.Printf("⚡ Press Ctrl+\\ to stop the server\n")
 
-		if err := app.Listen(addr); err != nil {
+		if serverTLS == nil {
+			if err := app.Listen(addr); err != nil {
+				serverErr <- err
+			}
+			return
+		}
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		if err := app.Listener(tls.NewListener(ln, serverTLS)); err != nil {
 			serverErr <- err
 		}
 	}()
@@ -167,6 +433,40 @@ func main() {
 			}
 
 			log.Println("  → Cleaning up resources...")
+			if podResources != nil {
+				podResources.Stop()
+			}
+			if sampler != nil {
+				sampler.Stop()
+			}
+			if agent != nil {
+				agent.Stop()
+			}
+			if discovery != nil {
+				discovery.Stop()
+			}
+			if reservationWatcher != nil {
+				reservationWatcher.Stop()
+			}
+			if filesystemMonitor != nil {
+				filesystemMonitor.Stop()
+			}
+			if serverCerts != nil {
+				serverCerts.Stop()
+			}
+			if peerCerts != nil {
+				peerCerts.Stop()
+			}
+			if ledger != nil {
+				if err := ledger.Save(); err != nil {
+					log.Printf("  ⚠️  Failed to save accounting ledger: %v", err)
+				}
+			}
+			if energyTracker != nil {
+				if err := energyTracker.Save(); err != nil {
+					log.Printf("  ⚠️  Failed to save energy totals: %v", err)
+				}
+			}
 			if mon, ok := monitorOrHub.(*monitor.GPUMonitor); ok {
 				mon.Shutdown()
 			} else if h, ok := monitorOrHub.(*hub.Hub); ok {
@@ -0,0 +1,244 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContainerInfo describes the container a process runs in, derived from its cgroup
+type ContainerInfo struct {
+	CgroupPath string
+	Runtime    string // docker, containerd, cri-o, podman, lxc
+	ID         string
+	Name       string
+	PodUID     string
+}
+
+var (
+	// 64-character hex container IDs used by docker, containerd, cri-o and podman
+	containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+	// Pod UIDs appear as "pod<uid>" (cgroupfs driver) or "pod<uid_with_underscores>.slice" (systemd driver)
+	podUIDPattern = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+
+	// Root directories where container runtimes keep per-container metadata
+	dockerContainersDir  = "/var/lib/docker/containers"
+	containerdTaskDir    = "/run/containerd/io.containerd.runtime.v2.task"
+	containerNameCache   = make(map[string]*cachedContainerName)
+	containerNameCacheMu sync.Mutex
+	containerNamesPruned time.Time
+)
+
+// Container names stay cached while their processes keep being seen. Entries of
+// containers that stopped are evicted once idle, and the cache never exceeds its cap.
+const (
+	containerNameIdle      = 10 * time.Minute
+	maxCachedContainerName = 4096
+)
+
+type cachedContainerName struct {
+	name string
+	seen time.Time
+}
+
+// getContainerInfo resolves the cgroup of a process and derives its container attribution
+func getContainerInfo(pid int) (*ContainerInfo, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	info := parseCgroup(string(data))
+	if info.ID != "" {
+		info.Name = lookupContainerName(info.Runtime, info.ID)
+	}
+	return info, nil
+}
+
+// parseCgroup extracts container attribution from the contents of /proc/<pid>/cgroup.
+// Both cgroup v1 ("12:memory:/docker/<id>") and v2 ("0::/system.slice/docker-<id>.scope")
+// layouts are supported, with either the cgroupfs or the systemd cgroup driver.
+func parseCgroup(content string) *ContainerInfo {
+	info := &ContainerInfo{}
+
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+		if info.ID != "" {
+			break
+		}
+
+		// Prefer the unified hierarchy until a path identifies a container
+		if info.CgroupPath == "" || parts[0] == "0" {
+			info.CgroupPath = path
+		}
+
+		if m := podUIDPattern.FindStringSubmatch(path); m != nil {
+			info.PodUID = strings.ReplaceAll(m[1], "_", "-")
+		}
+
+		if id := containerIDPattern.FindString(path); id != "" {
+			info.ID = id
+			info.Runtime = detectRuntime(path, info.PodUID != "")
+			info.CgroupPath = path
+			continue
+		}
+
+		// LXC containers are identified by name rather than ID
+		if name := lxcContainerName(path); name != "" {
+			info.ID = name
+			info.Name = name
+			info.Runtime = "lxc"
+			info.CgroupPath = path
+		}
+	}
+
+	return info
+}
+
+// detectRuntime infers the container runtime from a cgroup path
+func detectRuntime(path string, isPod bool) string {
+	switch {
+	case strings.Contains(path, "containerd"):
+		return "containerd"
+	case strings.Contains(path, "crio-"):
+		return "cri-o"
+	case strings.Contains(path, "libpod-") || strings.Contains(path, "libpod_"):
+		return "podman"
+	case strings.Contains(path, "docker"):
+		return "docker"
+	case isPod:
+		// Kubernetes with the cgroupfs driver does not name the runtime in the path
+		return "containerd"
+	}
+	return "unknown"
+}
+
+// lxcContainerName returns the LXC container name for paths like "/lxc/<name>" or "/lxc.payload.<name>"
+func lxcContainerName(path string) string {
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "lxc.payload.") {
+			return strings.TrimPrefix(segment, "lxc.payload.")
+		}
+	}
+	if strings.HasPrefix(path, "/lxc/") {
+		return strings.SplitN(strings.TrimPrefix(path, "/lxc/"), "/", 2)[0]
+	}
+	return ""
+}
+
+// lookupContainerName resolves a human readable container name from runtime metadata (cached).
+// Names that cannot be read yet are not cached, so they are retried on the next refresh.
+func lookupContainerName(runtime, id string) string {
+	now := time.Now()
+	containerNameCacheMu.Lock()
+	if cached, ok := containerNameCache[id]; ok {
+		cached.seen = now
+		containerNameCacheMu.Unlock()
+		return cached.name
+	}
+	containerNameCacheMu.Unlock()
+
+	var name string
+	switch runtime {
+	case "docker":
+		name = dockerContainerName(id)
+	case "containerd":
+		name = containerdContainerName(id)
+	}
+	if name == "" {
+		return ""
+	}
+
+	containerNameCacheMu.Lock()
+	defer containerNameCacheMu.Unlock()
+	if len(containerNameCache) >= maxCachedContainerName || now.Sub(containerNamesPruned) > containerNameIdle {
+		pruneContainerNames(now)
+	}
+	if len(containerNameCache) < maxCachedContainerName {
+		containerNameCache[id] = &cachedContainerName{name: name, seen: now}
+	}
+	return name
+}
+
+// pruneContainerNames evicts the names of containers whose processes were not seen for
+// containerNameIdle. Callers hold containerNameCacheMu.
+func pruneContainerNames(now time.Time) {
+	containerNamesPruned = now
+	for id, cached := range containerNameCache {
+		if now.Sub(cached.seen) > containerNameIdle {
+			delete(containerNameCache, id)
+		}
+	}
+}
+
+// dockerContainerName reads the container name from Docker's config.v2.json
+func dockerContainerName(id string) string {
+	data, err := os.ReadFile(filepath.Join(dockerContainersDir, id, "config.v2.json"))
+	if err != nil {
+		return ""
+	}
+
+	var cfg struct {
+		Name string `json:"Name"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(cfg.Name, "/")
+}
+
+// containerdContainerName reads the CRI container name from the containerd task bundle annotations
+func containerdContainerName(id string) string {
+	matches, err := filepath.Glob(filepath.Join(containerdTaskDir, "*", id, "config.json"))
+	if err != nil || len(matches) == 0 {
+		return ""
+	}
+
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		return ""
+	}
+
+	var spec struct {
+		Annotations map[string]string `json:"annotations"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return ""
+	}
+	if name := spec.Annotations["io.kubernetes.cri.container-name"]; name != "" {
+		return name
+	}
+	return spec.Annotations["nerdctl/name"]
+}
+
+// addContainerInfo adds container attribution fields to a process entry
+func addContainerInfo(procInfo map[string]interface{}, pid int) {
+	info, err := getContainerInfo(pid)
+	if err != nil {
+		return
+	}
+
+	procInfo["cgroup"] = info.CgroupPath
+	if info.ID == "" {
+		return
+	}
+
+	procInfo["container_runtime"] = info.Runtime
+	procInfo["container_id"] = info.ID
+	if info.Name != "" {
+		procInfo["container_name"] = info.Name
+	}
+	if info.PodUID != "" {
+		procInfo["pod_uid"] = info.PodUID
+	}
+}
@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseCgroup(t *testing.T) {
+	const id = "3f4b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b"
+
+	tests := []struct {
+		name    string
+		content string
+		runtime string
+		id      string
+		podUID  string
+	}{
+		{
+			name:    "docker cgroup v1",
+			content: "12:memory:/docker/" + id + "\n11:cpu,cpuacct:/docker/" + id,
+			runtime: "docker",
+			id:      id,
+		},
+		{
+			name:    "docker cgroup v2 systemd",
+			content: "0::/system.slice/docker-" + id + ".scope",
+			runtime: "docker",
+			id:      id,
+		},
+		{
+			name:    "kubernetes containerd systemd",
+			content: "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1a2b3c4d_5e6f_7a8b_9c0d_1e2f3a4b5c6d.slice/cri-containerd-" + id + ".scope",
+			runtime: "containerd",
+			id:      id,
+			podUID:  "1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d",
+		},
+		{
+			name:    "kubernetes cgroupfs",
+			content: "4:devices:/kubepods/besteffort/pod1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d/" + id,
+			runtime: "containerd",
+			id:      id,
+			podUID:  "1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d",
+		},
+		{
+			name:    "cri-o",
+			content: "0::/kubepods.slice/kubepods-pod1a2b3c4d_5e6f_7a8b_9c0d_1e2f3a4b5c6d.slice/crio-" + id + ".scope",
+			runtime: "cri-o",
+			id:      id,
+			podUID:  "1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d",
+		},
+		{
+			name:    "podman",
+			content: "0::/machine.slice/libpod-" + id + ".scope/container",
+			runtime: "podman",
+			id:      id,
+		},
+		{
+			name:    "lxc",
+			content: "0::/lxc.payload.trainer/system.slice",
+			runtime: "lxc",
+			id:      "trainer",
+		},
+		{
+			name:    "host process",
+			content: "0::/user.slice/user-1000.slice/session-3.scope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parseCgroup(tt.content)
+			if info.Runtime != tt.runtime {
+				t.Errorf("runtime = %q, want %q", info.Runtime, tt.runtime)
+			}
+			if info.ID != tt.id {
+				t.Errorf("id = %q, want %q", info.ID, tt.id)
+			}
+			if info.PodUID != tt.podUID {
+				t.Errorf("pod uid = %q, want %q", info.PodUID, tt.podUID)
+			}
+			if info.CgroupPath == "" {
+				t.Error("cgroup path is empty")
+			}
+		})
+	}
+}
+
+func TestLookupContainerName(t *testing.T) {
+	const id = "3f4b2c1d0e9f8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b"
+	dockerContainersDir = t.TempDir()
+	defer func() { dockerContainersDir = "/var/lib/docker/containers" }()
+
+	// Metadata that is not written yet is retried rather than cached as unnamed
+	if name := lookupContainerName("docker", id); name != "" {
+		t.Fatalf("name without metadata = %q", name)
+	}
+	dir := filepath.Join(dockerContainersDir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.v2.json"), []byte(`{"Name": "/trainer"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if name := lookupContainerName("docker", id); name != "trainer" {
+		t.Errorf("name once metadata exists = %q, want trainer", name)
+	}
+
+	// Containers that are no longer seen are evicted
+	containerNameCacheMu.Lock()
+	containerNameCache[id].seen = time.Now().Add(-2 * containerNameIdle)
+	pruneContainerNames(time.Now())
+	_, cached := containerNameCache[id]
+	containerNameCacheMu.Unlock()
+	if cached {
+		t.Error("idle container name still cached")
+	}
+}
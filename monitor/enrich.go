@@ -0,0 +1,42 @@
+package monitor
+
+// Enricher adds attribution fields (pod, job, owner, ...) to collected GPU and process entries
+type Enricher interface {
+	EnrichGPU(gpu map[string]interface{})
+	EnrichProcess(proc map[string]interface{})
+}
+
+// AddEnricher registers an enricher applied to every GPU and process entry the monitor collects
+func (m *GPUMonitor) AddEnricher(e Enricher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enrichers = append(m.enrichers, e)
+}
+
+// enrichGPUs applies all registered enrichers to a GPU data map
+func (m *GPUMonitor) enrichGPUs(gpuData map[string]interface{}) {
+	m.mu.RLock()
+	enrichers := m.enrichers
+	m.mu.RUnlock()
+
+	for _, e := range enrichers {
+		for _, gpu := range gpuData {
+			if data, ok := gpu.(map[string]interface{}); ok {
+				e.EnrichGPU(data)
+			}
+		}
+	}
+}
+
+// enrichProcesses applies all registered enrichers to a list of process entries
+func (m *GPUMonitor) enrichProcesses(processes []map[string]interface{}) {
+	m.mu.RLock()
+	enrichers := m.enrichers
+	m.mu.RUnlock()
+
+	for _, e := range enrichers {
+		for _, proc := range processes {
+			e.EnrichProcess(proc)
+		}
+	}
+}
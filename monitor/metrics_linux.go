@@ -155,6 +155,11 @@ func (mc *MetricsCollector) addPowerThermal(device nvml.Device, data map[string]
 		data["power_limit_max"] = float64(maxLimit) / 1000.0
 	}
 
+	// Total energy since the driver was loaded (Volta and newer)
+	if energy, ret := device.GetTotalEnergyConsumption(); ret == nvml.SUCCESS {
+		data["energy_consumption_wh"] = float64(energy) / 3600000.0 // Convert mJ to Wh
+	}
+
 	// Fan speed
 	if fan, ret := device.GetFanSpeed(); ret == nvml.SUCCESS {
 		data["fan_speed"] = float64(fan)
@@ -231,16 +236,40 @@ func (mc *MetricsCollector) addConnectivity(device nvml.Device, data map[string]
 	}
 
 	if pci, ret := device.GetPciInfo(); ret == nvml.SUCCESS {
-		// Convert BusId int8 array to string
-		busIdBytes := make([]byte, 0, len(pci.BusId))
-		for _, b := range pci.BusId {
-			if b == 0 {
-				break
+		data["pci_bus_id"] = pciBusID(pci)
+	}
+
+	// NVLink: PCI bus IDs of the GPUs (or NVSwitches) at the other end of active links
+	var peers []string
+	seen := make(map[string]bool)
+	for link := 0; link < nvml.NVLINK_MAX_LINKS; link++ {
+		state, ret := device.GetNvLinkState(link)
+		if ret != nvml.SUCCESS || state != nvml.FEATURE_ENABLED {
+			continue
+		}
+		if remote, ret := device.GetNvLinkRemotePciInfo(link); ret == nvml.SUCCESS {
+			busID := pciBusID(remote)
+			if !seen[busID] {
+				seen[busID] = true
+				peers = append(peers, busID)
 			}
-			busIdBytes = append(busIdBytes, byte(b))
 		}
-		data["pci_bus_id"] = string(busIdBytes)
 	}
+	if len(peers) > 0 {
+		data["nvlink_peers"] = peers
+	}
+}
+
+// pciBusID converts the BusId int8 array of a PCI info struct to a string
+func pciBusID(pci nvml.PciInfo) string {
+	busIdBytes := make([]byte, 0, len(pci.BusId))
+	for _, b := range pci.BusId {
+		if b == 0 {
+			break
+		}
+		busIdBytes = append(busIdBytes, byte(b))
+	}
+	return string(busIdBytes)
 }
 
 // Helper functions
@@ -15,6 +15,7 @@ type GPUMonitor struct {
 	gpuData         map[string]interface{}
 	mu              sync.RWMutex
 	heartbeatClient *analytics.HeartbeatClient
+	enrichers       []Enricher
 }
 
 // IsInitialized returns whether GPU monitoring is initialized
@@ -21,6 +21,7 @@ type GPUMonitor struct {
 	gpuData         map[string]interface{}
 	mu              sync.RWMutex
 	heartbeatClient *analytics.HeartbeatClient
+	enrichers       []Enricher
 }
 
 // IsInitialized returns whether GPU monitoring is initialized
@@ -142,6 +143,9 @@ func (m *GPUMonitor) GetGPUData() (map[string]interface{}, error) {
 		gpuData[gpuID] = data
 	}
 
+	// Apply attribution enrichers (pods, jobs, ...)
+	m.enrichGPUs(gpuData)
+
 	m.mu.Lock()
 	m.gpuData = gpuData
 	m.mu.Unlock()
@@ -172,6 +176,7 @@ func (m *GPUMonitor) GetProcesses() ([]map[string]interface{}, error) {
 
 	var allProcesses []map[string]interface{}
 	gpuProcessCounts := make(map[string]map[string]int)
+	gpuSlurmJobs := make(map[string]*SlurmJob)
 
 	for i := 0; i < count; i++ {
 		gpuID := fmt.Sprintf("%d", i)
@@ -214,6 +219,17 @@ func (m *GPUMonitor) GetProcesses() ([]map[string]interface{}, error) {
 					if cpuPercent, err := p.CPUPercent(); err == nil {
 						procInfo["cpu_percent"] = cpuPercent
 					}
+
+					// Resolve the owning user and group
+					addOwnerInfo(procInfo, p)
+				}
+
+				// Attribute the process to its container (if any)
+				addContainerInfo(procInfo, int(proc.Pid))
+
+				// Tag the process (and its GPU) with its Slurm job
+				if job := addSlurmInfo(procInfo, int(proc.Pid)); job != nil && gpuSlurmJobs[gpuID] == nil {
+					gpuSlurmJobs[gpuID] = job
 				}
 
 				// Try to get GPU utilization (NVML may not provide per-process util on all GPUs)
@@ -252,6 +268,17 @@ func (m *GPUMonitor) GetProcesses() ([]map[string]interface{}, error) {
 				if cpuPercent, err := p.CPUPercent(); err == nil {
 					procInfo["cpu_percent"] = cpuPercent
 				}
+
+				// Resolve the owning user and group
+				addOwnerInfo(procInfo, p)
+			}
+
+			// Attribute the process to its container (if any)
+			addContainerInfo(procInfo, int(proc.Pid))
+
+			// Tag the process (and its GPU) with its Slurm job
+			if job := addSlurmInfo(procInfo, int(proc.Pid)); job != nil && gpuSlurmJobs[gpuID] == nil {
+				gpuSlurmJobs[gpuID] = job
 			}
 
 			// Try to get GPU utilization (NVML may not provide per-process util on all GPUs)
@@ -270,10 +297,16 @@ func (m *GPUMonitor) GetProcesses() ([]map[string]interface{}, error) {
 		if data, ok := m.gpuData[gpuID].(map[string]interface{}); ok {
 			data["compute_processes_count"] = counts["compute"]
 			data["graphics_processes_count"] = counts["graphics"]
+			if job, ok := gpuSlurmJobs[gpuID]; ok {
+				setSlurmFields(data, job)
+			}
 		}
 	}
 	m.mu.Unlock()
 
+	// Apply attribution enrichers (pods, jobs, ...)
+	m.enrichProcesses(allProcesses)
+
 	return allProcesses, nil
 }
 
@@ -23,6 +23,7 @@ type GPUMonitor struct {
 	gpuCount        int
 	mu              sync.RWMutex
 	heartbeatClient *analytics.HeartbeatClient
+	enrichers       []Enricher
 }
 
 // IsInitialized returns whether GPU monitoring is initialized
@@ -174,6 +175,9 @@ func (m *GPUMonitor) GetGPUData() (map[string]interface{}, error) {
 		gpuData[gpuID] = data
 	}
 
+	// Apply attribution enrichers (pods, jobs, ...)
+	m.enrichGPUs(gpuData)
+
 	m.mu.Lock()
 	m.gpuData = gpuData
 	m.mu.Unlock()
@@ -275,6 +279,7 @@ func (m *GPUMonitor) GetProcesses() ([]map[string]interface{}, error) {
 				if cpuPercent, err := p.CPUPercent(); err == nil {
 					procInfo["cpu_percent"] = cpuPercent
 				}
+				addOwnerInfo(procInfo, p)
 			}
 
 			procInfo["gpu_percent"] = 0.0 // nvidia-smi doesn't provide per-process GPU util
@@ -294,6 +299,9 @@ func (m *GPUMonitor) GetProcesses() ([]map[string]interface{}, error) {
 	}
 	m.mu.Unlock()
 
+	// Apply attribution enrichers (pods, jobs, ...)
+	m.enrichProcesses(allProcesses)
+
 	return allProcesses, nil
 }
 
@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"fmt"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ownerLookupRetry is how long a failed UID/GID lookup keeps the numeric fallback
+// before the name is looked up again, so a transient NSS/LDAP error does not stick
+const ownerLookupRetry = time.Minute
+
+// ownerName is a cached UID/GID name; failed lookups expire, resolved names do not
+type ownerName struct {
+	name       string
+	retryAfter time.Time
+}
+
+var (
+	// UID/GID to name lookups can hit NSS/LDAP, so cache them
+	userNameCache  = make(map[int32]ownerName)
+	groupNameCache = make(map[int32]ownerName)
+	ownerCacheMu   sync.RWMutex
+
+	// Replaced in tests to simulate directory service failures
+	lookupUser = func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	}
+	lookupGroup = func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	}
+)
+
+// addOwnerInfo adds the owning user and primary group of a process to its entry
+func addOwnerInfo(procInfo map[string]interface{}, p *process.Process) {
+	if uids, err := p.Uids(); err == nil && len(uids) > 0 {
+		// Effective UID is the second entry on Linux; fall back to the real UID
+		uid := uids[0]
+		if len(uids) > 1 {
+			uid = uids[1]
+		}
+		procInfo["uid"] = uid
+		procInfo["user"] = lookupUserName(uid)
+	} else if username, err := p.Username(); err == nil && username != "" {
+		procInfo["user"] = username
+	}
+
+	if gids, err := p.Gids(); err == nil && len(gids) > 0 {
+		gid := gids[0]
+		if len(gids) > 1 {
+			gid = gids[1]
+		}
+		procInfo["gid"] = gid
+		procInfo["group"] = lookupGroupName(gid)
+	}
+}
+
+// lookupUserName resolves a UID to a username, falling back to the numeric ID
+func lookupUserName(uid int32) string {
+	return lookupOwnerName(userNameCache, uid, lookupUser)
+}
+
+// lookupGroupName resolves a GID to a group name, falling back to the numeric ID
+func lookupGroupName(gid int32) string {
+	return lookupOwnerName(groupNameCache, gid, lookupGroup)
+}
+
+func lookupOwnerName(cache map[int32]ownerName, id int32, lookup func(string) (string, error)) string {
+	ownerCacheMu.RLock()
+	cached, ok := cache[id]
+	ownerCacheMu.RUnlock()
+	if ok && (cached.retryAfter.IsZero() || time.Now().Before(cached.retryAfter)) {
+		return cached.name
+	}
+
+	entry := ownerName{name: fmt.Sprintf("%d", id)}
+	if name, err := lookup(entry.name); err == nil {
+		entry.name = name
+	} else {
+		entry.retryAfter = time.Now().Add(ownerLookupRetry)
+	}
+
+	ownerCacheMu.Lock()
+	cache[id] = entry
+	ownerCacheMu.Unlock()
+	return entry.name
+}
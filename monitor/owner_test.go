@@ -0,0 +1,51 @@
+package monitor
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLookupUserNameRetriesFailedLookups(t *testing.T) {
+	orig := lookupUser
+	defer func() { lookupUser = orig }()
+
+	const uid = 4242
+	defer func() {
+		ownerCacheMu.Lock()
+		delete(userNameCache, uid)
+		ownerCacheMu.Unlock()
+	}()
+
+	calls := 0
+	available := false
+	lookupUser = func(id string) (string, error) {
+		calls++
+		if !available {
+			return "", errors.New("ldap unavailable")
+		}
+		return "alice", nil
+	}
+
+	if name := lookupUserName(uid); name != "4242" {
+		t.Fatalf("name = %q, want numeric fallback", name)
+	}
+	if name := lookupUserName(uid); name != "4242" || calls != 1 {
+		t.Fatalf("fallback should be cached until it expires (name %q, %d lookups)", name, calls)
+	}
+
+	// Expire the fallback once the directory service is back
+	available = true
+	ownerCacheMu.Lock()
+	entry := userNameCache[uid]
+	entry.retryAfter = time.Now().Add(-time.Second)
+	userNameCache[uid] = entry
+	ownerCacheMu.Unlock()
+
+	if name := lookupUserName(uid); name != "alice" {
+		t.Fatalf("name = %q after the lookup recovered, want alice", name)
+	}
+	if name := lookupUserName(uid); name != "alice" || calls != 2 {
+		t.Errorf("resolved name should be cached (name %q, %d lookups)", name, calls)
+	}
+}
@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SlurmJob identifies the Slurm job a process belongs to
+type SlurmJob struct {
+	ID        string
+	User      string
+	Name      string
+	Partition string
+}
+
+// Slurm cgroup layouts:
+//
+//	v1: /slurm/uid_1000/job_4242/step_0/task_0
+//	v2: /system.slice/slurmstepd.scope/job_4242/step_0/user/task_0
+var slurmCgroupPattern = regexp.MustCompile(`(?:/uid_(\d+))?/job_(\d+)(?:/|$)`)
+
+// getSlurmJob resolves the Slurm job of a process. The environment is controlled by the
+// process owner, so the job ID and user come from the cgroup when it names a job.
+func getSlurmJob(pid int, cgroupPath, owner string) *SlurmJob {
+	var envJob *SlurmJob
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid)); err == nil {
+		envJob = parseSlurmEnviron(data)
+	}
+	return resolveSlurmJob(parseSlurmCgroup(cgroupPath), envJob, owner)
+}
+
+// resolveSlurmJob combines the job from the cgroup with the job variables of the environment.
+// The environment only contributes the name and partition of the cgroup's job; without a
+// Slurm cgroup its job ID is used as a fallback, but the user is always the process owner.
+func resolveSlurmJob(cgroupJob, envJob *SlurmJob, owner string) *SlurmJob {
+	if cgroupJob == nil {
+		if envJob == nil {
+			return nil
+		}
+		return &SlurmJob{ID: envJob.ID, User: owner, Name: envJob.Name, Partition: envJob.Partition}
+	}
+
+	job := *cgroupJob
+	if job.User == "" {
+		job.User = owner
+	}
+	if envJob != nil && envJob.ID == job.ID {
+		job.Name = envJob.Name
+		job.Partition = envJob.Partition
+	}
+	return &job
+}
+
+// parseSlurmEnviron extracts Slurm job variables from a NUL-separated /proc/<pid>/environ
+func parseSlurmEnviron(data []byte) *SlurmJob {
+	job := &SlurmJob{}
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		key, value, ok := strings.Cut(string(entry), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "SLURM_JOB_ID":
+			job.ID = value
+		case "SLURM_JOBID":
+			if job.ID == "" {
+				job.ID = value
+			}
+		case "SLURM_JOB_USER":
+			job.User = value
+		case "SLURM_JOB_NAME":
+			job.Name = value
+		case "SLURM_JOB_PARTITION":
+			job.Partition = value
+		}
+	}
+
+	if job.ID == "" {
+		return nil
+	}
+	return job
+}
+
+// parseSlurmCgroup extracts the job ID (and owner, for cgroup v1) from a Slurm cgroup path
+func parseSlurmCgroup(path string) *SlurmJob {
+	if !strings.Contains(path, "slurm") {
+		return nil
+	}
+
+	m := slurmCgroupPattern.FindStringSubmatch(path)
+	if m == nil {
+		return nil
+	}
+
+	job := &SlurmJob{ID: m[2]}
+	if uid, err := strconv.ParseInt(m[1], 10, 32); err == nil {
+		job.User = lookupUserName(int32(uid))
+	}
+	return job
+}
+
+// addSlurmInfo tags a process entry with its Slurm job and returns the job (nil when not in a job)
+func addSlurmInfo(procInfo map[string]interface{}, pid int) *SlurmJob {
+	cgroupPath, _ := procInfo["cgroup"].(string)
+	owner, _ := procInfo["user"].(string)
+	job := getSlurmJob(pid, cgroupPath, owner)
+	if job == nil {
+		return nil
+	}
+
+	setSlurmFields(procInfo, job)
+	return job
+}
+
+// setSlurmFields writes Slurm job fields into a GPU or process entry
+func setSlurmFields(entry map[string]interface{}, job *SlurmJob) {
+	entry["slurm_job_id"] = job.ID
+	if job.User != "" {
+		entry["slurm_job_user"] = job.User
+	}
+	if job.Name != "" {
+		entry["slurm_job_name"] = job.Name
+	}
+	if job.Partition != "" {
+		entry["slurm_partition"] = job.Partition
+	}
+}
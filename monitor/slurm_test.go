@@ -0,0 +1,74 @@
+package monitor
+
+import "testing"
+
+func TestParseSlurmEnviron(t *testing.T) {
+	environ := []byte("PATH=/usr/bin\x00SLURM_JOB_ID=4242\x00SLURM_JOB_USER=alice\x00SLURM_JOB_NAME=train-llm\x00SLURM_JOB_PARTITION=gpu\x00")
+
+	job := parseSlurmEnviron(environ)
+	if job == nil {
+		t.Fatal("expected a Slurm job")
+	}
+	want := SlurmJob{ID: "4242", User: "alice", Name: "train-llm", Partition: "gpu"}
+	if *job != want {
+		t.Errorf("job = %+v, want %+v", *job, want)
+	}
+
+	if job := parseSlurmEnviron([]byte("PATH=/usr/bin\x00HOME=/root\x00")); job != nil {
+		t.Errorf("expected no job outside Slurm, got %+v", job)
+	}
+}
+
+func TestParseSlurmCgroup(t *testing.T) {
+	tests := []struct {
+		path string
+		id   string
+	}{
+		{"/slurm/uid_4242000/job_17/step_0/task_0", "17"},
+		{"/system.slice/slurmstepd.scope/job_98765/step_batch/user/task_0", "98765"},
+		{"/system.slice/docker-abc.scope", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		job := parseSlurmCgroup(tt.path)
+		if tt.id == "" {
+			if job != nil {
+				t.Errorf("%q: expected no job, got %+v", tt.path, job)
+			}
+			continue
+		}
+		if job == nil || job.ID != tt.id {
+			t.Errorf("%q: job = %+v, want ID %s", tt.path, job, tt.id)
+		}
+	}
+}
+
+func TestResolveSlurmJob(t *testing.T) {
+	cgroupJob := &SlurmJob{ID: "17", User: "alice"}
+	spoofed := &SlurmJob{ID: "99", User: "bob", Name: "train-llm", Partition: "gpu"}
+
+	// A forged environment cannot move the process to another job or user
+	if job := resolveSlurmJob(cgroupJob, spoofed, "alice"); *job != (SlurmJob{ID: "17", User: "alice"}) {
+		t.Errorf("job with spoofed environment = %+v, want the cgroup's job", *job)
+	}
+
+	// The environment of the same job adds its name and partition
+	env := &SlurmJob{ID: "17", User: "bob", Name: "train-llm", Partition: "gpu"}
+	if job := resolveSlurmJob(cgroupJob, env, "alice"); *job != (SlurmJob{ID: "17", User: "alice", Name: "train-llm", Partition: "gpu"}) {
+		t.Errorf("job = %+v", *job)
+	}
+
+	// cgroup v2 paths carry no UID, so the job belongs to the process owner
+	if job := resolveSlurmJob(&SlurmJob{ID: "17"}, nil, "carol"); job.User != "carol" {
+		t.Errorf("cgroup v2 job user = %q, want the process owner", job.User)
+	}
+
+	// Without a Slurm cgroup the environment's job is used, but never its user
+	if job := resolveSlurmJob(nil, spoofed, "carol"); job == nil || job.ID != "99" || job.User != "carol" {
+		t.Errorf("environment-only job = %+v, want job 99 owned by carol", job)
+	}
+	if job := resolveSlurmJob(nil, nil, "carol"); job != nil {
+		t.Errorf("expected no job, got %+v", job)
+	}
+}
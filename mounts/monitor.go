@@ -0,0 +1,349 @@
+package mounts
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// usageTimeout bounds how long a single filesystem may take to report its usage
+var usageTimeout = 5 * time.Second
+
+// minPredictionSpan is the history needed before predicting when a filesystem fills up
+const minPredictionSpan = 2 * time.Minute
+
+// Rules are the alert thresholds for filesystems. A zero threshold disables its rule.
+type Rules struct {
+	DiskWarning    float64       // Percent of space used
+	DiskCritical   float64       //
+	InodesWarning  float64       // Percent of inodes used
+	InodesCritical float64       //
+	FullWarning    time.Duration // Predicted time until space or inodes run out
+	FullCritical   time.Duration //
+}
+
+// DefaultRules are used until thresholds are configured
+var DefaultRules = Rules{
+	DiskWarning:    85,
+	DiskCritical:   95,
+	InodesWarning:  85,
+	InodesCritical: 95,
+	FullWarning:    24 * time.Hour,
+	FullCritical:   2 * time.Hour,
+}
+
+// Alert is a rule a filesystem currently breaks
+type Alert struct {
+	Mountpoint string    `json:"mountpoint"`
+	Metric     string    `json:"metric"` // "disk", "inodes" or "time_until_full"
+	Level      string    `json:"level"`  // "warning" or "critical"
+	Value      float64   `json:"value"`  // Percent used, or hours until full
+	Threshold  float64   `json:"threshold"`
+	Message    string    `json:"message"`
+	Since      time.Time `json:"since"`
+}
+
+func (a Alert) key() string {
+	return a.Mountpoint + "|" + a.Metric + "|" + a.Level
+}
+
+// Monitor samples every mounted filesystem in the background, predicts when each
+// fills up from its recent fill rate, and raises alerts from the rules
+type Monitor struct {
+	filter   Filter
+	window   time.Duration // History the fill rate is computed over
+	alertLog string
+	rules    func() Rules
+
+	partitions func() ([]disk.PartitionStat, error)
+	usage      func(path string) (*disk.UsageStat, error)
+
+	history  map[string][]usageSample
+	last     map[string]Filesystem // Last answer per mount point, reported while stale
+	pending  map[string]bool       // Mount points whose usage call has not returned yet
+	alerts   map[string]Alert      // Alert key -> alert
+	current  []Filesystem
+	mu       sync.RWMutex
+	stopChan chan struct{}
+}
+
+// NewMonitor creates a filesystem monitor. New alerts are appended to alertLog (if set);
+// rules is called on every check so threshold changes apply without a restart.
+func NewMonitor(filter Filter, window time.Duration, alertLog string, rules func() Rules) *Monitor {
+	if rules == nil {
+		rules = func() Rules { return DefaultRules }
+	}
+	return &Monitor{
+		filter:     filter,
+		window:     window,
+		alertLog:   alertLog,
+		rules:      rules,
+		partitions: func() ([]disk.PartitionStat, error) { return disk.Partitions(true) },
+		usage:      disk.Usage,
+		history:    make(map[string][]usageSample),
+		last:       make(map[string]Filesystem),
+		pending:    make(map[string]bool),
+		alerts:     make(map[string]Alert),
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start checks the filesystems now and then every interval until Stop is called
+func (m *Monitor) Start(interval time.Duration) {
+	go func() {
+		m.check(time.Now())
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stopChan:
+				return
+			case at := <-ticker.C:
+				m.check(at)
+			}
+		}
+	}()
+}
+
+// Stop stops the monitor
+func (m *Monitor) Stop() {
+	close(m.stopChan)
+}
+
+// Filesystems returns the filesystems found by the last check, sorted by mount point
+func (m *Monitor) Filesystems() []Filesystem {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Filesystem{}, m.current...)
+}
+
+// Alerts returns the alerts raised by the last check
+func (m *Monitor) Alerts() []Alert {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	alerts := make([]Alert, 0, len(m.alerts))
+	for _, a := range m.alerts {
+		alerts = append(alerts, a)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].key() < alerts[j].key()
+	})
+	return alerts
+}
+
+func (m *Monitor) check(at time.Time) {
+	parts, err := m.partitions()
+	if err != nil {
+		log.Printf("Failed to list filesystems: %v", err)
+		return
+	}
+
+	rules := m.rules()
+	seen := make(map[string]bool)
+	var filesystems []Filesystem
+	var fresh []Alert
+
+	for _, p := range parts {
+		if m.filter.Skip(p) || seen[p.Mountpoint] {
+			continue
+		}
+		seen[p.Mountpoint] = true
+
+		fs, ok := m.collect(p, at)
+		if !ok {
+			continue
+		}
+		fs.Alerts = evaluate(fs, rules)
+		filesystems = append(filesystems, fs)
+	}
+	sort.Slice(filesystems, func(i, j int) bool {
+		return filesystems[i].Mountpoint < filesystems[j].Mountpoint
+	})
+
+	m.mu.Lock()
+	alerts := make(map[string]Alert)
+	for i := range filesystems {
+		for j, a := range filesystems[i].Alerts {
+			if prev, ok := m.alerts[a.key()]; ok {
+				a.Since = prev.Since
+			} else {
+				a.Since = at
+				fresh = append(fresh, a)
+			}
+			filesystems[i].Alerts[j] = a
+			alerts[a.key()] = a
+		}
+	}
+	for mountpoint := range m.history {
+		if !seen[mountpoint] {
+			delete(m.history, mountpoint)
+			delete(m.last, mountpoint)
+		}
+	}
+	m.alerts = alerts
+	m.current = filesystems
+	m.mu.Unlock()
+
+	for _, a := range fresh {
+		log.Printf("⚠️  Filesystem %s: %s", a.Mountpoint, a.Message)
+		m.appendAlert(a)
+	}
+}
+
+// collect reads a filesystem's usage and updates its fill rate. A filesystem that does
+// not answer within usageTimeout is reported stale with its last known usage.
+func (m *Monitor) collect(p disk.PartitionStat, at time.Time) (Filesystem, bool) {
+	m.mu.Lock()
+	busy := m.pending[p.Mountpoint]
+	last, hasLast := m.last[p.Mountpoint]
+	m.pending[p.Mountpoint] = true
+	m.mu.Unlock()
+	if busy {
+		last.Stale = true
+		return last, hasLast
+	}
+
+	result := make(chan *disk.UsageStat, 1)
+	go func() {
+		u, err := m.usage(p.Mountpoint)
+		if err != nil {
+			u = nil
+		}
+		m.mu.Lock()
+		delete(m.pending, p.Mountpoint)
+		m.mu.Unlock()
+		result <- u
+	}()
+
+	var u *disk.UsageStat
+	select {
+	case u = <-result:
+	case <-time.After(usageTimeout):
+		log.Printf("Filesystem %s did not report its usage within %s", p.Mountpoint, usageTimeout)
+		last.Stale = true
+		return last, hasLast
+	}
+	if u == nil || u.Total == 0 {
+		return Filesystem{}, false
+	}
+
+	fs := Filesystem{
+		Mountpoint:        p.Mountpoint,
+		Device:            p.Device,
+		Fstype:            p.Fstype,
+		Options:           p.Opts,
+		ReadOnly:          isReadOnly(p.Opts),
+		Total:             u.Total,
+		Used:              u.Used,
+		Free:              u.Free,
+		UsedPercent:       u.UsedPercent,
+		InodesTotal:       u.InodesTotal,
+		InodesUsed:        u.InodesUsed,
+		InodesFree:        u.InodesFree,
+		InodesUsedPercent: u.InodesUsedPercent,
+	}
+	if fs.Options == nil {
+		fs.Options = []string{}
+	}
+
+	m.mu.Lock()
+	samples := append(m.history[p.Mountpoint], usageSample{at: at, used: float64(u.Used), inodesUsed: float64(u.InodesUsed)})
+	cutoff := at.Add(-m.window)
+	for len(samples) > 0 && samples[0].at.Before(cutoff) {
+		samples = samples[1:]
+	}
+	m.history[p.Mountpoint] = samples
+	m.mu.Unlock()
+
+	if bytes, inodes, ok := fillRate(samples, minPredictionSpan); ok {
+		fs.FillRate = bytes
+		fs.InodeFillRate = inodes
+		fs.SecondsUntilFull = secondsUntil(fs.Free, bytes)
+		if fs.InodesTotal > 0 {
+			fs.SecondsUntilInodesFull = secondsUntil(fs.InodesFree, inodes)
+		}
+	}
+
+	m.mu.Lock()
+	m.last[p.Mountpoint] = fs
+	m.mu.Unlock()
+	return fs, true
+}
+
+// evaluate returns the alerts a filesystem raises, at most one level per metric.
+// Read-only filesystems cannot fill up and never alert.
+func evaluate(fs Filesystem, rules Rules) []Alert {
+	if fs.ReadOnly {
+		return nil
+	}
+	var alerts []Alert
+	add := func(metric string, value, warning, critical float64, above bool, format string) {
+		breaks := func(threshold float64) bool {
+			if threshold <= 0 {
+				return false
+			}
+			if above {
+				return value >= threshold
+			}
+			return value < threshold
+		}
+		level, threshold := "", 0.0
+		switch {
+		case breaks(critical):
+			level, threshold = "critical", critical
+		case breaks(warning):
+			level, threshold = "warning", warning
+		default:
+			return
+		}
+		alerts = append(alerts, Alert{
+			Mountpoint: fs.Mountpoint,
+			Metric:     metric,
+			Level:      level,
+			Value:      value,
+			Threshold:  threshold,
+			Message:    fmt.Sprintf(format, value, threshold),
+		})
+	}
+
+	add("disk", fs.UsedPercent, rules.DiskWarning, rules.DiskCritical, true,
+		"Disk: %.1f%% used (threshold: %.1f%%)")
+	if fs.InodesTotal > 0 {
+		add("inodes", fs.InodesUsedPercent, rules.InodesWarning, rules.InodesCritical, true,
+			"Inodes: %.1f%% used (threshold: %.1f%%)")
+	}
+
+	var until *float64
+	for _, secs := range []*float64{fs.SecondsUntilFull, fs.SecondsUntilInodesFull} {
+		if secs != nil && (until == nil || *secs < *until) {
+			until = secs
+		}
+	}
+	if until != nil {
+		add("time_until_full", *until/3600, rules.FullWarning.Hours(), rules.FullCritical.Hours(), false,
+			"Time until full: %.1f hours at the current fill rate (threshold: %.1f hours)")
+	}
+	return alerts
+}
+
+// appendAlert records an alert in the alert history log shown by the dashboard and CLI
+func (m *Monitor) appendAlert(a Alert) {
+	if m.alertLog == "" {
+		return
+	}
+	f, err := os.OpenFile(m.alertLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Failed to write alert log: %v", err)
+		return
+	}
+	defer f.Close()
+
+	// Mount points may contain newlines, so quote them to keep one alert per line
+	fmt.Fprintf(f, "[%s] Filesystem %q - %s %s\n", a.Since.Format("2006-01-02 15:04:05"), a.Mountpoint, a.Level, a.Message)
+}
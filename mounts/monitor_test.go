@@ -0,0 +1,163 @@
+package mounts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// fakeDisks serves partitions and usage from memory
+type fakeDisks struct {
+	mu    sync.Mutex
+	used  map[string]uint64
+	hang  map[string]chan struct{}
+	parts []disk.PartitionStat
+}
+
+func (f *fakeDisks) usage(path string) (*disk.UsageStat, error) {
+	f.mu.Lock()
+	hang := f.hang[path]
+	used := f.used[path]
+	f.mu.Unlock()
+	if hang != nil {
+		<-hang
+	}
+	const total, inodes = 1000 << 30, 1000000
+	return &disk.UsageStat{
+		Path:              path,
+		Total:             total,
+		Used:              used,
+		Free:              total - used,
+		UsedPercent:       float64(used) / total * 100,
+		InodesTotal:       inodes,
+		InodesUsed:        inodes / 10,
+		InodesFree:        inodes - inodes/10,
+		InodesUsedPercent: 10,
+	}, nil
+}
+
+func TestMonitor(t *testing.T) {
+	fake := &fakeDisks{
+		used: map[string]uint64{"/": 100 << 30, "/data": 700 << 30, "/scratch": 990 << 30},
+		hang: map[string]chan struct{}{},
+		parts: []disk.PartitionStat{
+			{Device: "/dev/nvme0n1p1", Mountpoint: "/", Fstype: "ext4", Opts: []string{"rw", "relatime"}},
+			{Device: "proc", Mountpoint: "/proc", Fstype: "proc"},
+			{Device: "tmpfs", Mountpoint: "/dev/shm", Fstype: "tmpfs"},
+			{Device: "/dev/loop3", Mountpoint: "/snap/core/1", Fstype: "ext4"},
+			{Device: "nas:/export/data", Mountpoint: "/data", Fstype: "nfs4", Opts: []string{"rw", "hard"}},
+			{Device: "nas:/export/data", Mountpoint: "/data", Fstype: "nfs4"},
+			{Device: "/dev/nvme1n1", Mountpoint: "/scratch", Fstype: "xfs", Opts: []string{"ro"}},
+		},
+	}
+
+	alertLog := filepath.Join(t.TempDir(), "gpu-alerts.log")
+	m := NewMonitor(Filter{ExcludeMounts: []string{"/snap/*"}}, time.Hour, alertLog, nil)
+	m.partitions = func() ([]disk.PartitionStat, error) { return fake.parts, nil }
+	m.usage = fake.usage
+
+	// /data fills 10 GB a minute: 300 GB free lasts 30 minutes
+	start := time.Now()
+	for i := 0; i <= 5; i++ {
+		fake.mu.Lock()
+		fake.used["/data"] = uint64(700+10*i) << 30
+		fake.mu.Unlock()
+		m.check(start.Add(time.Duration(i) * time.Minute))
+	}
+
+	fs := m.Filesystems()
+	if len(fs) != 3 || fs[0].Mountpoint != "/" || fs[1].Mountpoint != "/data" || fs[2].Mountpoint != "/scratch" {
+		t.Fatalf("filesystems = %+v", fs)
+	}
+	data := fs[1]
+	if data.Fstype != "nfs4" || data.Options[1] != "hard" || data.InodesTotal != 1000000 {
+		t.Errorf("/data = %+v", data)
+	}
+	if rate := data.FillRate / (1 << 30) * 60; rate < 9.99 || rate > 10.01 {
+		t.Errorf("/data fill rate = %.2f GB/min, want 10", rate)
+	}
+	if data.SecondsUntilFull == nil || *data.SecondsUntilFull < 1499 || *data.SecondsUntilFull > 1501 {
+		t.Errorf("/data seconds until full = %v, want 1500", data.SecondsUntilFull)
+	}
+	if fs[0].SecondsUntilFull != nil || fs[0].FillRate != 0 {
+		t.Errorf("/ is not filling but predicts %v", fs[0].SecondsUntilFull)
+	}
+	if !fs[2].ReadOnly {
+		t.Error("/scratch not reported read-only")
+	}
+
+	// 75% used is below the thresholds, but filling up in 25 minutes is critical;
+	// the read-only /scratch is 99% used but cannot fill up
+	alerts := m.Alerts()
+	if len(alerts) != 1 || alerts[0].Mountpoint != "/data" || alerts[0].Metric != "time_until_full" || alerts[0].Level != "critical" {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	if !alerts[0].Since.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("alert since %v, want the first check that predicted it", alerts[0].Since)
+	}
+	logged, _ := os.ReadFile(alertLog)
+	if lines := strings.Count(string(logged), "\n"); lines != 1 || !strings.Contains(string(logged), `Filesystem "/data" - critical Time until full`) {
+		t.Errorf("alert log = %q, want the alert once", logged)
+	}
+
+	// Space thresholds follow the rules, which are read on every check
+	m.rules = func() Rules { return Rules{DiskWarning: 5, DiskCritical: 80} }
+	m.check(start.Add(6 * time.Minute))
+	levels := map[string]string{}
+	for _, a := range m.Alerts() {
+		levels[a.Mountpoint+" "+a.Metric] = a.Level
+	}
+	if len(levels) != 2 || levels["/ disk"] != "warning" || levels["/data disk"] != "warning" {
+		t.Errorf("alerts with custom rules = %v", levels)
+	}
+
+	// A filesystem that stops answering is reported stale with its last usage
+	usageTimeout = 50 * time.Millisecond
+	defer func() { usageTimeout = 5 * time.Second }()
+	release := make(chan struct{})
+	fake.mu.Lock()
+	fake.hang["/data"] = release
+	fake.mu.Unlock()
+	m.check(start.Add(7 * time.Minute))
+	m.check(start.Add(8 * time.Minute))
+	if fs := m.Filesystems(); len(fs) != 3 || !fs[1].Stale || fs[1].Total == 0 {
+		t.Errorf("hung /data = %+v, want stale", fs[1])
+	}
+	close(release)
+}
+
+func TestFillRate(t *testing.T) {
+	at := time.Unix(0, 0)
+	samples := []usageSample{
+		{at: at, used: 100},
+		{at: at.Add(time.Minute), used: 80},
+		{at: at.Add(2 * time.Minute), used: 60},
+	}
+	if _, _, ok := fillRate(samples, 5*time.Minute); ok {
+		t.Error("fill rate predicted from too short a history")
+	}
+	bytes, _, ok := fillRate(samples, time.Minute)
+	if !ok || bytes > -0.33 || bytes < -0.34 {
+		t.Errorf("fill rate = %v, %v; want -1/3 per second", bytes, ok)
+	}
+	if secondsUntil(100, bytes) != nil {
+		t.Error("a shrinking filesystem predicts a time until full")
+	}
+}
+
+func TestAppendAlertQuotesMountpoint(t *testing.T) {
+	alertLog := filepath.Join(t.TempDir(), "gpu-alerts.log")
+	m := &Monitor{alertLog: alertLog}
+
+	m.appendAlert(Alert{Mountpoint: "/mnt/x\n[2025-01-01 00:00:00] GPU 0 - critical forged", Level: "warning", Message: "Disk usage", Since: time.Now()})
+
+	logged, _ := os.ReadFile(alertLog)
+	if lines := strings.Count(string(logged), "\n"); lines != 1 {
+		t.Errorf("alert log = %q, want a single line", logged)
+	}
+}
@@ -0,0 +1,127 @@
+package mounts
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// pseudoFilesystems hold no user data and are never reported
+var pseudoFilesystems = map[string]bool{
+	"autofs": true, "binfmt_misc": true, "bpf": true, "cgroup": true, "cgroup2": true,
+	"configfs": true, "debugfs": true, "devfs": true, "devpts": true, "devtmpfs": true,
+	"efivarfs": true, "fusectl": true, "hugetlbfs": true, "mqueue": true, "nsfs": true,
+	"proc": true, "pstore": true, "ramfs": true, "rpc_pipefs": true, "securityfs": true,
+	"selinuxfs": true, "squashfs": true, "sysfs": true, "tmpfs": true, "tracefs": true,
+	"fuse.gvfsd-fuse": true, "fuse.portal": true, "nullfs": true, "map": true,
+}
+
+// Filesystem is the usage of one mounted filesystem
+type Filesystem struct {
+	Mountpoint        string   `json:"mountpoint"`
+	Device            string   `json:"device"`
+	Fstype            string   `json:"fstype"`
+	Options           []string `json:"options"`
+	ReadOnly          bool     `json:"read_only"`
+	Total             uint64   `json:"total"`
+	Used              uint64   `json:"used"`
+	Free              uint64   `json:"free"`
+	UsedPercent       float64  `json:"used_percent"`
+	InodesTotal       uint64   `json:"inodes_total"` // 0 if the filesystem has no inode limit
+	InodesUsed        uint64   `json:"inodes_used"`
+	InodesFree        uint64   `json:"inodes_free"`
+	InodesUsedPercent float64  `json:"inodes_used_percent"`
+
+	FillRate               float64  `json:"fill_rate"`                           // Bytes per second over the prediction window, negative when freeing
+	InodeFillRate          float64  `json:"inode_fill_rate"`                     // Inodes per second
+	SecondsUntilFull       *float64 `json:"seconds_until_full,omitempty"`        // At the fill rate; nil unless filling
+	SecondsUntilInodesFull *float64 `json:"seconds_until_inodes_full,omitempty"` // At the inode fill rate
+	Stale                  bool     `json:"stale,omitempty"`                     // Usage did not answer in time (e.g., a hung NFS server)
+
+	Alerts []Alert `json:"alerts,omitempty"`
+}
+
+// Filter selects the filesystems to report. Pseudo filesystems are always skipped.
+type Filter struct {
+	ExcludeTypes  []string // Additional filesystem types to skip
+	ExcludeMounts []string // Mount point globs to skip, with everything mounted below ("/snap/*")
+}
+
+// Skip reports whether a partition is filtered out
+func (f Filter) Skip(p disk.PartitionStat) bool {
+	if pseudoFilesystems[p.Fstype] {
+		return true
+	}
+	for _, t := range f.ExcludeTypes {
+		if strings.EqualFold(t, p.Fstype) {
+			return true
+		}
+	}
+	for path := p.Mountpoint; ; path = filepath.Dir(path) {
+		for _, pattern := range f.ExcludeMounts {
+			if ok, _ := filepath.Match(pattern, path); ok {
+				return true
+			}
+		}
+		if path == filepath.Dir(path) {
+			return false
+		}
+	}
+}
+
+// usageSample is a filesystem's usage at one point in time
+type usageSample struct {
+	at         time.Time
+	used       float64
+	inodesUsed float64
+}
+
+// fillRate returns the least-squares slope of used space and inodes over the samples,
+// per second. It returns ok=false until the samples span at least minSpan.
+func fillRate(samples []usageSample, minSpan time.Duration) (bytes, inodes float64, ok bool) {
+	if len(samples) < 2 || samples[len(samples)-1].at.Sub(samples[0].at) < minSpan {
+		return 0, 0, false
+	}
+
+	origin := samples[0].at
+	var sumT, sumB, sumI float64
+	for _, s := range samples {
+		sumT += s.at.Sub(origin).Seconds()
+		sumB += s.used
+		sumI += s.inodesUsed
+	}
+	n := float64(len(samples))
+	meanT, meanB, meanI := sumT/n, sumB/n, sumI/n
+
+	var varT, covB, covI float64
+	for _, s := range samples {
+		dt := s.at.Sub(origin).Seconds() - meanT
+		varT += dt * dt
+		covB += dt * (s.used - meanB)
+		covI += dt * (s.inodesUsed - meanI)
+	}
+	if varT == 0 {
+		return 0, 0, false
+	}
+	return covB / varT, covI / varT, true
+}
+
+// secondsUntil returns how long until free runs out at rate, or nil if it is not shrinking
+func secondsUntil(free uint64, rate float64) *float64 {
+	if rate <= 0 {
+		return nil
+	}
+	secs := float64(free) / rate
+	return &secs
+}
+
+func isReadOnly(opts []string) bool {
+	for _, opt := range opts {
+		if opt == "ro" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,311 @@
+// Package placement ranks GPUs by availability and recommends where to place a job
+package placement
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Weights of the availability score (0-100)
+const (
+	memoryWeight      = 0.5 // Share of free memory
+	utilizationWeight = 0.4 // Idle compute
+	idleWeight        = 0.1 // No compute processes at all
+)
+
+// Request describes the GPUs a job needs
+type Request struct {
+	Count     int     `json:"count"`      // Number of GPUs
+	MemoryMiB float64 `json:"memory_mib"` // Minimum free memory per GPU
+	SameNode  bool    `json:"same_node"`  // All GPUs on one node
+	NVLink    bool    `json:"nvlink"`     // All GPUs connected to each other over NVLink (implies same node)
+}
+
+// Node is the GPU payload of one node
+type Node struct {
+	Name string
+	GPUs map[string]interface{} // GPU ID -> GPU data, as in the monitor payload
+}
+
+// Candidate is a GPU that satisfies the memory request
+type Candidate struct {
+	Node        string  `json:"node"`
+	GPUID       string  `json:"gpu_id"`
+	UUID        string  `json:"uuid,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	MemoryFree  float64 `json:"memory_free"`
+	MemoryTotal float64 `json:"memory_total"`
+	Utilization float64 `json:"utilization"`
+	Processes   int     `json:"processes"`
+	Score       float64 `json:"score"`
+
+	busID string
+	peers map[string]bool
+}
+
+// Result lists eligible GPUs by availability and the recommended placement, if the request can be met
+type Result struct {
+	Request            Request     `json:"request"`
+	GPUs               []Candidate `json:"gpus"`
+	Placement          []Candidate `json:"placement"`
+	Satisfiable        bool        `json:"satisfiable"`
+	CUDAVisibleDevices string      `json:"cuda_visible_devices,omitempty"` // Set when the placement is on one node
+}
+
+// ParseRequest reads a request from query parameters: count, memory (MiB, or with a
+// G/GB/GiB suffix), same_node and nvlink
+func ParseRequest(query map[string]string) (Request, error) {
+	req := Request{Count: 1}
+
+	if v := strings.TrimSpace(query["count"]); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil || count < 1 {
+			return req, fmt.Errorf("invalid count %q", v)
+		}
+		req.Count = count
+	}
+
+	if v := strings.TrimSpace(query["memory"]); v != "" {
+		mib, err := parseMemory(v)
+		if err != nil {
+			return req, err
+		}
+		req.MemoryMiB = mib
+	}
+
+	for key, dst := range map[string]*bool{"same_node": &req.SameNode, "nvlink": &req.NVLink} {
+		if v := strings.TrimSpace(query[key]); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return req, fmt.Errorf("invalid %s %q", key, v)
+			}
+			*dst = b
+		}
+	}
+	if req.NVLink {
+		req.SameNode = true
+	}
+	return req, nil
+}
+
+// parseMemory parses a memory amount in MiB, or in GiB with a G/GB/GiB suffix
+func parseMemory(s string) (float64, error) {
+	lower := strings.ToLower(s)
+	factor := 1.0
+	for _, suffix := range []string{"gib", "gb", "g"} {
+		if strings.HasSuffix(lower, suffix) {
+			lower = strings.TrimSuffix(lower, suffix)
+			factor = 1024
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(lower), 64)
+	if err != nil || value < 0 {
+		return 0, errors.New("invalid memory " + strconv.Quote(s))
+	}
+	return value * factor, nil
+}
+
+// Rank returns the GPUs with enough free memory, most available first, and picks the
+// best set of req.Count GPUs that satisfies the placement constraints
+func Rank(nodes []Node, req Request) Result {
+	if req.Count < 1 {
+		req.Count = 1
+	}
+	if req.NVLink {
+		req.SameNode = true
+	}
+
+	result := Result{Request: req, GPUs: []Candidate{}, Placement: []Candidate{}}
+	byNode := make(map[string][]Candidate)
+	busIDs := make(map[string]map[string]bool) // node -> PCI bus IDs of all its GPUs
+	for _, node := range nodes {
+		busIDs[node.Name] = make(map[string]bool)
+		for gpuID, raw := range node.GPUs {
+			gpu, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			c := newCandidate(node.Name, gpuID, gpu)
+			if c.busID != "" {
+				busIDs[node.Name][c.busID] = true
+			}
+			if c.MemoryFree < req.MemoryMiB {
+				continue
+			}
+			result.GPUs = append(result.GPUs, c)
+			byNode[node.Name] = append(byNode[node.Name], c)
+		}
+	}
+	sortCandidates(result.GPUs)
+
+	var placement []Candidate
+	if !req.SameNode {
+		if len(result.GPUs) >= req.Count {
+			placement = result.GPUs[:req.Count]
+		}
+	} else {
+		bestScore := -1.0
+		for nodeName, candidates := range byNode {
+			sortCandidates(candidates)
+			var set []Candidate
+			if req.NVLink {
+				set = nvlinkSet(candidates, req.Count, busIDs[nodeName])
+			} else if len(candidates) >= req.Count {
+				set = candidates[:req.Count]
+			}
+			if set == nil {
+				continue
+			}
+			if score := totalScore(set); score > bestScore || (score == bestScore && set[0].Node < placement[0].Node) {
+				bestScore = score
+				placement = set
+			}
+		}
+	}
+
+	if placement != nil {
+		result.Satisfiable = true
+		result.Placement = append(result.Placement, placement...)
+		if sameNode(placement) {
+			ids := make([]string, len(placement))
+			for i, c := range placement {
+				// UUIDs select the same GPUs whatever CUDA_DEVICE_ORDER is
+				ids[i] = c.UUID
+				if ids[i] == "" {
+					ids[i] = c.GPUID
+				}
+			}
+			result.CUDAVisibleDevices = strings.Join(ids, ",")
+		}
+	}
+	return result
+}
+
+func newCandidate(node, gpuID string, gpu map[string]interface{}) Candidate {
+	c := Candidate{Node: node, GPUID: gpuID}
+	c.UUID, _ = gpu["uuid"].(string)
+	c.Name, _ = gpu["name"].(string)
+	c.MemoryTotal, _ = gpu["memory_total"].(float64)
+	c.Utilization, _ = gpu["utilization"].(float64)
+	if free, ok := gpu["memory_free"].(float64); ok {
+		c.MemoryFree = free
+	} else if used, ok := gpu["memory_used"].(float64); ok {
+		c.MemoryFree = c.MemoryTotal - used
+	}
+	// Local payloads carry an int, payloads decoded from JSON a float64
+	switch procs := gpu["compute_processes_count"].(type) {
+	case int:
+		c.Processes = procs
+	case float64:
+		c.Processes = int(procs)
+	}
+
+	c.Score = utilizationWeight * (100 - c.Utilization)
+	if c.MemoryTotal > 0 {
+		c.Score += memoryWeight * c.MemoryFree / c.MemoryTotal * 100
+	}
+	if c.Processes == 0 {
+		c.Score += idleWeight * 100
+	}
+
+	c.busID, _ = gpu["pci_bus_id"].(string)
+	c.busID = strings.ToLower(c.busID)
+	c.peers = make(map[string]bool)
+	switch peers := gpu["nvlink_peers"].(type) {
+	case []string:
+		for _, p := range peers {
+			c.peers[strings.ToLower(p)] = true
+		}
+	case []interface{}:
+		for _, p := range peers {
+			if s, ok := p.(string); ok {
+				c.peers[strings.ToLower(s)] = true
+			}
+		}
+	}
+	return c
+}
+
+// sortCandidates orders by score, then by node and GPU ID for stable output
+func sortCandidates(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].Node != candidates[j].Node {
+			return candidates[i].Node < candidates[j].Node
+		}
+		return candidates[i].GPUID < candidates[j].GPUID
+	})
+}
+
+// nvlinked reports whether two GPUs of a node are connected over NVLink, either
+// directly or through a shared NVSwitch (a peer that is not one of the node's GPUs)
+func nvlinked(a, b Candidate, gpuBusIDs map[string]bool) bool {
+	if (b.busID != "" && a.peers[b.busID]) || (a.busID != "" && b.peers[a.busID]) {
+		return true
+	}
+	for peer := range a.peers {
+		if b.peers[peer] && !gpuBusIDs[peer] {
+			return true
+		}
+	}
+	return false
+}
+
+// nvlinkSet greedily builds the best-scoring set of count GPUs that are all NVLink-connected
+// to each other, trying every GPU as the seed; candidates must be sorted by score and
+// gpuBusIDs holds the bus IDs of all GPUs of the node, including ineligible ones
+func nvlinkSet(candidates []Candidate, count int, gpuBusIDs map[string]bool) []Candidate {
+	if count == 1 && len(candidates) > 0 {
+		return candidates[:1]
+	}
+
+	var best []Candidate
+	for i, seed := range candidates {
+		set := []Candidate{seed}
+		for j, c := range candidates {
+			if len(set) == count {
+				break
+			}
+			if i == j {
+				continue
+			}
+			connected := true
+			for _, member := range set {
+				if !nvlinked(member, c, gpuBusIDs) {
+					connected = false
+					break
+				}
+			}
+			if connected {
+				set = append(set, c)
+			}
+		}
+		if len(set) == count && (best == nil || totalScore(set) > totalScore(best)) {
+			best = set
+		}
+	}
+	return best
+}
+
+func totalScore(set []Candidate) float64 {
+	total := 0.0
+	for _, c := range set {
+		total += c.Score
+	}
+	return total
+}
+
+func sameNode(set []Candidate) bool {
+	for _, c := range set[1:] {
+		if c.Node != set[0].Node {
+			return false
+		}
+	}
+	return true
+}
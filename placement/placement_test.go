@@ -0,0 +1,99 @@
+package placement
+
+import (
+	"reflect"
+	"testing"
+)
+
+func gpu(memFree, memTotal, util float64, procs int, busID string, peers ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"memory_free":             memFree,
+		"memory_total":            memTotal,
+		"utilization":             util,
+		"compute_processes_count": procs,
+		"pci_bus_id":              busID,
+		"nvlink_peers":            peers,
+	}
+}
+
+func ids(set []Candidate) []string {
+	out := make([]string, len(set))
+	for i, c := range set {
+		out[i] = c.Node + ":" + c.GPUID
+	}
+	return out
+}
+
+func TestParseRequest(t *testing.T) {
+	req, err := ParseRequest(map[string]string{"count": "2", "memory": "24G", "nvlink": "true"})
+	if err != nil {
+		t.Fatalf("ParseRequest: %v", err)
+	}
+	want := Request{Count: 2, MemoryMiB: 24 * 1024, SameNode: true, NVLink: true}
+	if req != want {
+		t.Errorf("request = %+v, want %+v", req, want)
+	}
+
+	for _, bad := range []map[string]string{{"count": "0"}, {"memory": "lots"}, {"same_node": "maybe"}} {
+		if _, err := ParseRequest(bad); err == nil {
+			t.Errorf("ParseRequest(%v) succeeded", bad)
+		}
+	}
+}
+
+func TestRankAndPlace(t *testing.T) {
+	nodes := []Node{
+		{Name: "a", GPUs: map[string]interface{}{
+			"0": gpu(80000, 80000, 0, 0, "00:01"),
+			"1": gpu(10000, 80000, 90, 2, "00:02"),
+		}},
+		{Name: "b", GPUs: map[string]interface{}{
+			"0": gpu(60000, 80000, 10, 0, "00:01"),
+			"1": gpu(60000, 80000, 20, 0, "00:02"),
+		}},
+	}
+	nodes[1].GPUs["0"].(map[string]interface{})["uuid"] = "GPU-b0"
+	nodes[1].GPUs["1"].(map[string]interface{})["uuid"] = "GPU-b1"
+
+	// Unconstrained: the two most available GPUs, wherever they are
+	result := Rank(nodes, Request{Count: 2, MemoryMiB: 20000})
+	if got := ids(result.GPUs); !reflect.DeepEqual(got, []string{"a:0", "b:0", "b:1"}) {
+		t.Errorf("ranked GPUs = %v", got)
+	}
+	if got := ids(result.Placement); !reflect.DeepEqual(got, []string{"a:0", "b:0"}) || result.CUDAVisibleDevices != "" {
+		t.Errorf("placement = %v (CUDA_VISIBLE_DEVICES %q)", got, result.CUDAVisibleDevices)
+	}
+
+	// Same node: only node b has two GPUs with enough memory, selected by UUID
+	result = Rank(nodes, Request{Count: 2, MemoryMiB: 20000, SameNode: true})
+	if !result.Satisfiable || result.CUDAVisibleDevices != "GPU-b0,GPU-b1" || result.Placement[0].Node != "b" {
+		t.Errorf("same-node placement = %+v", result)
+	}
+
+	if result := Rank(nodes, Request{Count: 3, SameNode: true}); result.Satisfiable || len(result.Placement) != 0 {
+		t.Errorf("3 GPUs on one node should not be satisfiable: %+v", result)
+	}
+}
+
+func TestNVLinkPlacement(t *testing.T) {
+	// GPUs 0-1 are linked directly, 2-3 through an NVSwitch; no links across pairs
+	node := Node{Name: "n", GPUs: map[string]interface{}{
+		"0": gpu(40000, 40000, 0, 0, "00:01", "00:02"),
+		"1": gpu(40000, 40000, 50, 0, "00:02", "00:01"),
+		"2": gpu(40000, 40000, 10, 0, "00:03", "ff:00"),
+		"3": gpu(40000, 40000, 10, 0, "00:04", "ff:00"),
+	}}
+
+	result := Rank([]Node{node}, Request{Count: 2, NVLink: true})
+	if got := ids(result.Placement); !reflect.DeepEqual(got, []string{"n:2", "n:3"}) {
+		t.Errorf("NVLink placement = %v, want the switch-connected pair", got)
+	}
+	// Without UUIDs the NVML indices are listed
+	if result.CUDAVisibleDevices != "2,3" {
+		t.Errorf("CUDA_VISIBLE_DEVICES = %q, want 2,3", result.CUDAVisibleDevices)
+	}
+
+	if result := Rank([]Node{node}, Request{Count: 3, NVLink: true}); result.Satisfiable {
+		t.Errorf("3 NVLink-connected GPUs should not be satisfiable: %v", ids(result.Placement))
+	}
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrRateLimited is returned by Budget.Spend once the caller's budget is used up
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// Budget counts requests of each client in a sliding window. Unlike Middleware it is
+// spent by handlers themselves, for requests whose cost is only known once they run.
+type Budget struct {
+	name   string
+	max    int // 0 for no limit
+	window time.Duration
+	spent  map[string][]time.Time // Client -> times of the requests in the window, oldest first
+	mu     sync.Mutex
+	now    func() time.Time
+}
+
+// NewBudget creates a budget of max requests per window (default: 1 minute) for each
+// client; name appears in the error of a client over its budget
+func NewBudget(name string, max int, window time.Duration) *Budget {
+	if window <= 0 {
+		window = time.Minute
+	}
+	return &Budget{
+		name:   name,
+		max:    max,
+		window: window,
+		spent:  make(map[string][]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Spend counts a request against the caller's budget. Once the budget is used up it
+// sets the Retry-After header and returns an error wrapping ErrRateLimited instead.
+// A nil Budget never limits.
+func (b *Budget) Spend(c *fiber.Ctx) error {
+	if b == nil {
+		return nil
+	}
+	if wait := b.take(clientKey(c)); wait > 0 {
+		return b.limitReached(c, wait)
+	}
+	return nil
+}
+
+// take counts a request of key, or returns how long until it is allowed again
+func (b *Budget) take(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wait := b.waitLocked(key)
+	if wait == 0 && b.max > 0 {
+		b.spent[key] = append(b.spent[key], b.now())
+	}
+	return wait
+}
+
+// wait returns how long until key may make another request, 0 if it may now
+func (b *Budget) wait(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.waitLocked(key)
+}
+
+func (b *Budget) waitLocked(key string) time.Duration {
+	if b.max <= 0 {
+		return 0
+	}
+	now := b.now()
+	b.pruneLocked(now)
+	times := b.spent[key]
+	if len(times) < b.max {
+		return 0
+	}
+	return times[len(times)-b.max].Add(b.window).Sub(now)
+}
+
+// pruneLocked forgets requests older than the window (b.mu must be held)
+func (b *Budget) pruneLocked(now time.Time) {
+	cutoff := now.Add(-b.window)
+	for key, times := range b.spent {
+		i := 0
+		for i < len(times) && !times[i].After(cutoff) {
+			i++
+		}
+		if i == len(times) {
+			delete(b.spent, key)
+		} else if i > 0 {
+			b.spent[key] = times[i:]
+		}
+	}
+}
+
+// limitReached sets Retry-After and returns the error to answer with
+func (b *Budget) limitReached(c *fiber.Ctx, wait time.Duration) error {
+	seconds := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+	c.Set(fiber.HeaderRetryAfter, seconds)
+	return fmt.Errorf("%w: %d %s in %.0f seconds, retry in %s seconds", ErrRateLimited, b.max, b.name, b.window.Seconds(), seconds)
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+// ConnLimiter caps the number of concurrent WebSocket clients
+type ConnLimiter struct {
+	max    int // 0 for no limit
+	active int
+	mu     sync.Mutex
+}
+
+// NewConnLimiter creates a limiter allowing max concurrent connections (0 for no limit)
+func NewConnLimiter(max int) *ConnLimiter {
+	return &ConnLimiter{max: max}
+}
+
+// Active returns the number of open connections
+func (l *ConnLimiter) Active() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active
+}
+
+// Upgrade reserves a connection slot before the WebSocket handshake and answers 429 Too
+// Many Requests when all slots are taken. Register it in front of websocket.New and wrap
+// the connection handler with Handler, which frees the slot when the connection closes.
+func (l *ConnLimiter) Upgrade() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+
+		l.mu.Lock()
+		if l.max > 0 && l.active >= l.max {
+			l.mu.Unlock()
+			c.Set(fiber.HeaderRetryAfter, "30")
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many WebSocket clients",
+			})
+		}
+		l.active++
+		l.mu.Unlock()
+
+		// The handler only runs (and frees the slot) once the handshake succeeded
+		err := c.Next()
+		if err != nil || c.Response().StatusCode() != fiber.StatusSwitchingProtocols {
+			l.release()
+		}
+		return err
+	}
+}
+
+// Handler wraps a WebSocket connection handler to free its slot when it returns
+func (l *ConnLimiter) Handler(handler func(*websocket.Conn)) func(*websocket.Conn) {
+	return func(c *websocket.Conn) {
+		defer l.release()
+		handler(c)
+	}
+}
+
+func (l *ConnLimiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+}
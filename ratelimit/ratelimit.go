@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gpu-pro/auth"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// Endpoint is a route a stricter budget applies to. Path matches itself and everything below it.
+type Endpoint struct {
+	Method string
+	Path   string
+}
+
+// Endpoints with stricter budgets because each call is expensive or writes state
+var (
+	// HistoryEndpoints read and aggregate log and ledger files
+	HistoryEndpoints = []Endpoint{
+		{fiber.MethodGet, "/api/alert-history"},
+		{fiber.MethodGet, "/api/v1/accounting"},
+		{fiber.MethodGet, "/api/v1/energy"},
+		{fiber.MethodGet, "/api/v1/audit"},
+	}
+	// WriteEndpoints rewrite configuration files
+	WriteEndpoints = []Endpoint{
+		{fiber.MethodPost, "/api/alert-thresholds"},
+	}
+)
+
+// Group is a set of endpoints sharing a stricter budget
+type Group struct {
+	Name      string
+	Max       int // Requests per window from each client; 0 for no limit
+	Endpoints []Endpoint
+}
+
+// matches compares the normalized route path, since routes also match other
+// spellings such as "/API/v1/audit" or a trailing slash
+func (g Group) matches(c *fiber.Ctx) bool {
+	path := auth.RoutePath(c)
+	for _, e := range g.Endpoints {
+		if c.Method() == e.Method && (path == e.Path || strings.HasPrefix(path, e.Path+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config sets the request budgets of each client
+type Config struct {
+	Max    int           // Requests per window for endpoints outside any group; 0 for no limit
+	Window time.Duration // Default: 1 minute
+	Groups []Group       // A request counts against its group's budget instead of Max
+	Exempt []string      // Path prefixes never limited (static files, WebSockets)
+}
+
+// Middleware limits how many requests each client makes per window, answering 429 Too
+// Many Requests with a Retry-After header once a budget is spent. Clients are told apart
+// by their authenticated identity, or by IP address without authentication, so it must
+// run after auth.Middleware.
+func Middleware(cfg Config) fiber.Handler {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	newLimiter := func(name string, max int) fiber.Handler {
+		if max <= 0 {
+			return nil
+		}
+		return limiter.New(limiter.Config{
+			Max:          max,
+			Expiration:   cfg.Window,
+			KeyGenerator: clientKey,
+			LimitReached: func(c *fiber.Ctx) error {
+				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+					"error": fmt.Sprintf("Rate limit of %d %s requests exceeded, retry in %s seconds",
+						max, name, c.GetRespHeader(fiber.HeaderRetryAfter)),
+				})
+			},
+			LimiterMiddleware: limiter.SlidingWindow{},
+		})
+	}
+
+	defaultLimiter := newLimiter("API", cfg.Max)
+	groupLimiters := make([]fiber.Handler, len(cfg.Groups))
+	for i, g := range cfg.Groups {
+		groupLimiters[i] = newLimiter(g.Name, g.Max)
+	}
+
+	return func(c *fiber.Ctx) error {
+		// The normalized path loses its trailing slash, so "/socket.io/" still matches "/socket.io/"
+		routePath := auth.RoutePath(c) + "/"
+		for _, prefix := range cfg.Exempt {
+			if strings.HasPrefix(routePath, strings.ToLower(prefix)) {
+				return c.Next()
+			}
+		}
+		handler := defaultLimiter
+		for i, g := range cfg.Groups {
+			if g.matches(c) {
+				handler = groupLimiters[i]
+				break
+			}
+		}
+		if handler == nil {
+			return c.Next()
+		}
+		return handler(c)
+	}
+}
+
+// clientKey identifies the client a request counts against
+func clientKey(c *fiber.Ctx) string {
+	if id, ok := auth.IdentityFrom(c); ok && id.Name != "" {
+		return "user:" + id.Name
+	}
+	return "ip:" + clientIP(c)
+}
+
+// clientIP is the client's address, from the proxy header if one is configured
+// and sent, otherwise the peer's
+func clientIP(c *fiber.Ctx) string {
+	if ip := c.IP(); ip != "" {
+		return ip
+	}
+	return c.Context().RemoteIP().String()
+}
+
+// AuthFailures limits how many wrong API tokens and passwords each IP address may send
+// per window. Once an address has used up its budget, its requests carrying credentials
+// are answered 429 Too Many Requests without checking them, while requests with a
+// session go on. It must run before auth.Middleware and the login handler, which tell
+// it about rejected credentials.
+func AuthFailures(max int, window time.Duration) fiber.Handler {
+	budget := NewBudget("failed logins", max, window)
+	return func(c *fiber.Ctx) error {
+		if max <= 0 {
+			return c.Next()
+		}
+		key := "ip:" + clientIP(c)
+		if wait := budget.wait(key); wait > 0 && auth.PresentsCredentials(c) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": budget.limitReached(c, wait).Error(),
+			})
+		}
+
+		err := c.Next()
+		if auth.CredentialsRejected(c) {
+			budget.take(key)
+		}
+		return err
+	}
+}
@@ -0,0 +1,260 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gpu-pro/auth"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	gorilla "github.com/gorilla/websocket"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMiddleware(t *testing.T) {
+	a, err := auth.NewAuthenticator([]string{"ci:ci-token", "ops:ops-token"}, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetRoles(map[string]string{"ci": "admin"}, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Use(auth.Middleware(a))
+	app.Use(Middleware(Config{
+		Max:    3,
+		Groups: []Group{{Name: "writes", Max: 1, Endpoints: WriteEndpoints}},
+		Exempt: []string{"/static/"},
+	}))
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/api/gpu-data", ok)
+	app.Post("/api/alert-thresholds", ok)
+	app.Get("/static/app.js", ok)
+
+	do := func(method, path, token string) *httpResponse {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &httpResponse{status: resp.StatusCode, retryAfter: resp.Header.Get("Retry-After")}
+		json.NewDecoder(resp.Body).Decode(&r.body)
+		return r
+	}
+
+	for i := 1; i <= 3; i++ {
+		if r := do("GET", "/api/gpu-data", "ci-token"); r.status != 200 {
+			t.Fatalf("request %d status = %d", i, r.status)
+		}
+	}
+	r := do("GET", "/api/gpu-data", "ci-token")
+	if r.status != 429 || r.retryAfter == "" || !strings.Contains(r.body.Error, "Rate limit of 3 API requests") {
+		t.Errorf("over budget = %+v, want 429 with Retry-After", r)
+	}
+
+	// Writes have their own, stricter budget
+	if r := do("POST", "/api/alert-thresholds", "ci-token"); r.status != 200 {
+		t.Errorf("first write status = %d", r.status)
+	}
+	if r := do("POST", "/api/alert-thresholds", "ci-token"); r.status != 429 || !strings.Contains(r.body.Error, "writes") {
+		t.Errorf("second write = %+v, want 429", r)
+	}
+	// Other spellings of the route share its budget
+	for _, path := range []string{"/API/Alert-Thresholds", "/api/alert-thresholds/"} {
+		if r := do("POST", path, "ci-token"); r.status != 429 || !strings.Contains(r.body.Error, "writes") {
+			t.Errorf("write to %s = %+v, want 429", path, r)
+		}
+	}
+
+	// Exempt paths and other clients are unaffected
+	for _, path := range []string{"/static/app.js", "/STATIC/app.js"} {
+		if r := do("GET", path, "ci-token"); r.status != 200 {
+			t.Errorf("%s status = %d", path, r.status)
+		}
+	}
+	if r := do("GET", "/api/gpu-data", "ops-token"); r.status != 200 {
+		t.Errorf("other client status = %d", r.status)
+	}
+}
+
+func TestAuthFailures(t *testing.T) {
+	a, err := auth.NewAuthenticator([]string{"ci:ci-token"}, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New(fiber.Config{ProxyHeader: "X-Real-IP"})
+	app.Use(AuthFailures(2, time.Minute))
+	app.Use(auth.Middleware(a))
+	app.Get("/api/gpu-data", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	get := func(ip, token string) *httpResponse {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/gpu-data", nil)
+		req.Header.Set("X-Real-IP", ip)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &httpResponse{status: resp.StatusCode, retryAfter: resp.Header.Get("Retry-After")}
+		json.NewDecoder(resp.Body).Decode(&r.body)
+		return r
+	}
+
+	// Requests without credentials are not failures
+	for i := 0; i < 3; i++ {
+		if r := get("10.0.0.1", ""); r.status != 401 {
+			t.Fatalf("anonymous request status = %d, want 401", r.status)
+		}
+	}
+	for i := 1; i <= 2; i++ {
+		if r := get("10.0.0.1", "guess"); r.status != 401 {
+			t.Fatalf("wrong token %d status = %d, want 401", i, r.status)
+		}
+	}
+	// Once the budget is spent even the right token is not checked
+	if r := get("10.0.0.1", "ci-token"); r.status != 429 || r.retryAfter == "" || !strings.Contains(r.body.Error, "failed logins") {
+		t.Errorf("after 2 failures = %+v, want 429 with Retry-After", r)
+	}
+	// Other addresses, told apart by the proxy header, are unaffected
+	if r := get("10.0.0.2", "ci-token"); r.status != 200 {
+		t.Errorf("other address status = %d, want 200", r.status)
+	}
+}
+
+func TestAuthFailuresLoginPaths(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usersFile := filepath.Join(t.TempDir(), "users")
+	if err := os.WriteFile(usersFile, []byte("alice:"+string(hash)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := auth.NewAuthenticator(nil, usersFile, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Use(AuthFailures(2, time.Minute))
+	app.Use(auth.Middleware(a))
+	if err := auth.RegisterHandlers(app, a, []byte("login")); err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(path, password string) int {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, strings.NewReader(`{"username":"alice","password":"`+password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	for i := 1; i <= 2; i++ {
+		if status := login("/login", "guess"); status != 401 {
+			t.Fatalf("wrong password %d status = %d, want 401", i, status)
+		}
+	}
+	// Every spelling of the login route is refused once the budget is spent
+	for _, path := range []string{"/login", "/LOGIN", "/login/"} {
+		if status := login(path, "guess"); status != 429 {
+			t.Errorf("login at %s status = %d, want 429", path, status)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Now()
+	b := NewBudget("scans", 2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	if b.take("a") != 0 || b.take("a") != 0 {
+		t.Fatal("requests within the budget were limited")
+	}
+	if wait := b.take("a"); wait != time.Minute {
+		t.Errorf("wait = %v, want 1m", wait)
+	}
+	if b.take("b") != 0 {
+		t.Error("another client was limited")
+	}
+	now = now.Add(30 * time.Second)
+	if wait := b.wait("a"); wait != 30*time.Second {
+		t.Errorf("wait after 30s = %v, want 30s", wait)
+	}
+	now = now.Add(31 * time.Second)
+	if b.take("a") != 0 {
+		t.Error("budget not restored after the window")
+	}
+
+	var unlimited *Budget
+	if err := unlimited.Spend(nil); err != nil {
+		t.Errorf("nil budget Spend = %v", err)
+	}
+}
+
+type httpResponse struct {
+	status     int
+	retryAfter string
+	body       struct {
+		Error string `json:"error"`
+	}
+}
+
+func TestConnLimiter(t *testing.T) {
+	limit := NewConnLimiter(1)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/socket.io/", limit.Upgrade(), websocket.New(limit.Handler(func(c *websocket.Conn) {
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+	url := "ws://" + ln.Addr().String() + "/socket.io/"
+
+	first, _, err := gorilla.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, resp, err := gorilla.DefaultDialer.Dial(url, nil); err == nil || resp == nil || resp.StatusCode != 429 {
+		t.Fatalf("second client = %v, %v; want 429", resp, err)
+	}
+
+	// A plain request is not a WebSocket client and takes no slot
+	if resp, err := app.Test(httptest.NewRequest("GET", "/socket.io/", nil)); err != nil || resp.StatusCode == 429 {
+		t.Errorf("plain request = %v, %v", resp, err)
+	}
+
+	// Closing a client frees its slot
+	first.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for limit.Active() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	second, _, err := gorilla.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("client after close: %v", err)
+	}
+	second.Close()
+}
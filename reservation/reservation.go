@@ -0,0 +1,289 @@
+// Package reservation keeps advisory GPU leases and detects processes of other users on reserved GPUs
+package reservation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLeaseNotFound = errors.New("reservation not found")
+	ErrInvalidLease  = errors.New("invalid reservation")
+	ErrConflict      = errors.New("GPU already reserved")
+)
+
+// Lease reserves one or more GPUs of a node for a user during a time window.
+// User is the Unix account whose processes may use the GPUs; Owner is the
+// authenticated caller that created the lease and may change it.
+type Lease struct {
+	ID        string    `json:"id"`
+	User      string    `json:"user"`
+	Owner     string    `json:"owner,omitempty"`
+	Node      string    `json:"node"`
+	GPUs      []string  `json:"gpus"` // GPU UUIDs
+	Note      string    `json:"note,omitempty"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Active reports whether the lease is in effect at the given time
+func (l *Lease) Active(at time.Time) bool {
+	return !at.Before(l.Start) && at.Before(l.End)
+}
+
+// OwnedBy reports whether the authenticated caller name may change the lease.
+// Leases created before owners were recorded belong to their user.
+func (l *Lease) OwnedBy(name string) bool {
+	if l.Owner == "" {
+		return l.User == name
+	}
+	return l.Owner == name
+}
+
+// covers reports whether the lease includes the GPU on the node
+func (l *Lease) covers(node, uuid string) bool {
+	if l.Node != node {
+		return false
+	}
+	for _, gpu := range l.GPUs {
+		if strings.EqualFold(gpu, uuid) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store holds leases and persists them to a JSON file
+type Store struct {
+	path   string
+	leases map[string]*Lease
+	now    func() time.Time
+	mu     sync.RWMutex
+}
+
+// NewStore creates a store backed by the given file, loading previously saved leases
+func NewStore(path string) *Store {
+	s := &Store{
+		path:   path,
+		leases: make(map[string]*Lease),
+		now:    time.Now,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+
+	var leases []*Lease
+	if err := json.Unmarshal(data, &leases); err != nil {
+		return s
+	}
+	for _, l := range leases {
+		s.leases[l.ID] = l
+	}
+	return s
+}
+
+// Create validates and stores a new lease. A zero start means now.
+func (s *Store) Create(l Lease) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if l.Start.IsZero() {
+		l.Start = now
+	}
+	l.User = strings.TrimSpace(l.User)
+	l.Node = strings.TrimSpace(l.Node)
+	gpus := make([]string, 0, len(l.GPUs))
+	for _, gpu := range l.GPUs {
+		if gpu = strings.TrimSpace(gpu); gpu != "" {
+			gpus = append(gpus, gpu)
+		}
+	}
+	l.GPUs = gpus
+
+	switch {
+	case l.User == "":
+		return Lease{}, fmt.Errorf("%w: missing user", ErrInvalidLease)
+	case l.Node == "":
+		return Lease{}, fmt.Errorf("%w: missing node", ErrInvalidLease)
+	case len(l.GPUs) == 0:
+		return Lease{}, fmt.Errorf("%w: no GPUs", ErrInvalidLease)
+	case !l.End.After(l.Start) || !l.End.After(now):
+		return Lease{}, fmt.Errorf("%w: end must be in the future and after start", ErrInvalidLease)
+	}
+
+	s.pruneLocked(now)
+	if err := s.conflictLocked(&l); err != nil {
+		return Lease{}, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Lease{}, err
+	}
+	l.ID = id
+	l.CreatedAt = now
+	s.leases[id] = &l
+	return l, s.saveLocked()
+}
+
+// Extend moves the end of a lease
+func (s *Store) Extend(id string, end time.Time) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.leases[id]
+	if !ok {
+		return Lease{}, ErrLeaseNotFound
+	}
+	return s.extendLocked(l, end)
+}
+
+// ExtendBy moves the end of a lease d later than its current end
+func (s *Store) ExtendBy(id string, d time.Duration) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.leases[id]
+	if !ok {
+		return Lease{}, ErrLeaseNotFound
+	}
+	return s.extendLocked(l, l.End.Add(d))
+}
+
+// extendLocked moves the end of l unless it conflicts with another lease (s.mu must be held)
+func (s *Store) extendLocked(l *Lease, end time.Time) (Lease, error) {
+	if !end.After(l.Start) || !end.After(s.now()) {
+		return Lease{}, fmt.Errorf("%w: end must be in the future and after start", ErrInvalidLease)
+	}
+
+	extended := *l
+	extended.End = end
+	if err := s.conflictLocked(&extended); err != nil {
+		return Lease{}, err
+	}
+	l.End = end
+	return *l, s.saveLocked()
+}
+
+// Release removes a lease
+func (s *Store) Release(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.leases[id]; !ok {
+		return ErrLeaseNotFound
+	}
+	delete(s.leases, id)
+	return s.saveLocked()
+}
+
+// Get returns a lease by ID
+func (s *Store) Get(id string) (Lease, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.leases[id]
+	if !ok {
+		return Lease{}, ErrLeaseNotFound
+	}
+	return *l, nil
+}
+
+// List returns current and upcoming leases, earliest first
+func (s *Store) List() []Lease {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	leases := make([]Lease, 0, len(s.leases))
+	for _, l := range s.leases {
+		if l.End.After(now) {
+			leases = append(leases, *l)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		if !leases[i].Start.Equal(leases[j].Start) {
+			return leases[i].Start.Before(leases[j].Start)
+		}
+		return leases[i].ID < leases[j].ID
+	})
+	return leases
+}
+
+// ActiveLease returns the lease in effect for a GPU of a node, if any
+func (s *Store) ActiveLease(node, uuid string, at time.Time) (Lease, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, l := range s.leases {
+		if l.Active(at) && l.covers(node, uuid) {
+			return *l, true
+		}
+	}
+	return Lease{}, false
+}
+
+// conflictLocked returns ErrConflict if another lease overlaps l on one of its GPUs (s.mu must be held)
+func (s *Store) conflictLocked(l *Lease) error {
+	for _, other := range s.leases {
+		if other.ID == l.ID || !other.Start.Before(l.End) || !l.Start.Before(other.End) {
+			continue
+		}
+		for _, gpu := range l.GPUs {
+			if other.covers(l.Node, gpu) {
+				return fmt.Errorf("%w: %s on %s is reserved by %s until %s",
+					ErrConflict, gpu, l.Node, other.User, other.End.Format(time.RFC3339))
+			}
+		}
+	}
+	return nil
+}
+
+// pruneLocked drops leases that have ended (s.mu must be held)
+func (s *Store) pruneLocked(now time.Time) {
+	for id, l := range s.leases {
+		if !l.End.After(now) {
+			delete(s.leases, id)
+		}
+	}
+}
+
+// saveLocked writes all leases to the store file (s.mu must be held)
+func (s *Store) saveLocked() error {
+	leases := make([]*Lease, 0, len(s.leases))
+	for _, l := range s.leases {
+		leases = append(leases, l)
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].ID < leases[j].ID })
+
+	data, err := json.MarshalIndent(leases, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,116 @@
+package reservation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gpu-reservations.json")
+	s := NewStore(path)
+	now := time.Now()
+
+	lease, err := s.Create(Lease{User: "alice", Node: "node1", GPUs: []string{"GPU-aaaa", " "}, Note: "training", End: now.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if lease.ID == "" || len(lease.GPUs) != 1 {
+		t.Fatalf("lease = %+v", lease)
+	}
+
+	// Overlapping lease on the same GPU, case-insensitively
+	if _, err := s.Create(Lease{User: "bob", Node: "node1", GPUs: []string{"gpu-AAAA"}, Start: now.Add(time.Hour), End: now.Add(3 * time.Hour)}); !errors.Is(err, ErrConflict) {
+		t.Errorf("overlapping Create error = %v, want ErrConflict", err)
+	}
+	// Same GPU after the lease ends, or the same UUID on another node, is fine
+	later, err := s.Create(Lease{User: "bob", Node: "node1", GPUs: []string{"GPU-aaaa"}, Start: now.Add(2 * time.Hour), End: now.Add(3 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Create after end: %v", err)
+	}
+	if _, err := s.Create(Lease{User: "bob", Node: "node2", GPUs: []string{"GPU-aaaa"}, End: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Create on other node: %v", err)
+	}
+
+	if _, err := s.Extend(lease.ID, now.Add(150*time.Minute)); !errors.Is(err, ErrConflict) {
+		t.Errorf("Extend into the next lease error = %v, want ErrConflict", err)
+	}
+	if _, err := s.ExtendBy(lease.ID, 30*time.Minute); !errors.Is(err, ErrConflict) {
+		t.Errorf("ExtendBy into the next lease error = %v, want ErrConflict", err)
+	}
+	if _, err := s.ExtendBy("missing", time.Hour); !errors.Is(err, ErrLeaseNotFound) {
+		t.Errorf("ExtendBy of a missing lease error = %v, want ErrLeaseNotFound", err)
+	}
+	extended, err := s.ExtendBy(lease.ID, -30*time.Minute)
+	if err != nil || !extended.End.Equal(lease.End.Add(-30*time.Minute)) {
+		t.Errorf("ExtendBy = %+v, %v; want end moved 30m earlier", extended, err)
+	}
+	if _, err := s.Create(Lease{User: "carol", Node: "node1", GPUs: []string{"GPU-bbbb"}, End: now.Add(-time.Minute)}); !errors.Is(err, ErrInvalidLease) {
+		t.Errorf("Create in the past error = %v, want ErrInvalidLease", err)
+	}
+
+	// Leases survive a restart
+	restored := NewStore(path)
+	if got := len(restored.List()); got != 3 {
+		t.Fatalf("restored %d leases, want 3", got)
+	}
+	if active, ok := restored.ActiveLease("node1", "GPU-aaaa", now.Add(time.Minute)); !ok || active.User != "alice" {
+		t.Errorf("ActiveLease = %+v, %v", active, ok)
+	}
+
+	if err := restored.Release(later.ID); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := restored.Release(later.ID); !errors.Is(err, ErrLeaseNotFound) {
+		t.Errorf("second Release error = %v, want ErrLeaseNotFound", err)
+	}
+}
+
+func TestWatcherReportsViolationsOnce(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(filepath.Join(dir, "gpu-reservations.json"))
+	if _, err := s.Create(Lease{User: "alice", Node: "node1", GPUs: []string{"GPU-aaaa"}, End: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	processes := []map[string]interface{}{
+		{"pid": "100", "name": "train.py", "user": "alice", "gpu_uuid": "GPU-aaaa", "gpu_id": "0"},
+		{"pid": "200", "name": "python", "user": "bob", "gpu_uuid": "GPU-aaaa", "gpu_id": "0"},
+		{"pid": "300", "name": "python", "user": "bob", "gpu_uuid": "GPU-bbbb", "gpu_id": "1"},
+		{"pid": "400", "name": "unknown", "gpu_uuid": "GPU-aaaa", "gpu_id": "0"},
+	}
+	alertLog := filepath.Join(dir, "gpu-alerts.log")
+	w := NewWatcher(s, alertLog, func() map[string][]map[string]interface{} {
+		return map[string][]map[string]interface{}{"node1": processes}
+	})
+
+	first := time.Now()
+	w.check(first)
+	w.check(first.Add(time.Minute))
+
+	violations := w.Violations()
+	if len(violations) != 1 {
+		t.Fatalf("violations = %+v, want bob's process only", violations)
+	}
+	if v := violations[0]; v.PID != "200" || v.ReservedBy != "alice" || !v.DetectedAt.Equal(first) {
+		t.Errorf("violation = %+v", v)
+	}
+
+	data, err := os.ReadFile(alertLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 || !strings.Contains(string(data), "GPU 0 - warning Reservation: bob (pid 200") {
+		t.Errorf("alert log = %q, want one reservation alert", data)
+	}
+}
+
+func TestWatcherStopTwice(t *testing.T) {
+	w := NewWatcher(NewStore(""), "", func() map[string][]map[string]interface{} { return nil })
+	w.Start(time.Hour)
+	w.Stop()
+	w.Stop()
+}
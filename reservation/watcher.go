@@ -0,0 +1,170 @@
+package reservation
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Violation is a process of another user running on a reserved GPU
+type Violation struct {
+	LeaseID     string    `json:"lease_id"`
+	Node        string    `json:"node"`
+	GPU         string    `json:"gpu"`    // GPU UUID
+	GPUID       string    `json:"gpu_id"` // GPU index on the node
+	PID         string    `json:"pid"`
+	ProcessName string    `json:"process_name"`
+	User        string    `json:"user"`
+	ReservedBy  string    `json:"reserved_by"`
+	Until       time.Time `json:"until"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+func (v Violation) key() string {
+	return v.LeaseID + "|" + v.Node + "|" + v.PID
+}
+
+// Check returns the processes of a node that run on a GPU reserved by someone else.
+// Processes without a known owner are skipped.
+func (s *Store) Check(node string, processes []map[string]interface{}, at time.Time) []Violation {
+	var violations []Violation
+	for _, proc := range processes {
+		uuid, _ := proc["gpu_uuid"].(string)
+		user, _ := proc["user"].(string)
+		if uuid == "" || user == "" {
+			continue
+		}
+
+		lease, ok := s.ActiveLease(node, uuid, at)
+		if !ok || lease.User == user {
+			continue
+		}
+
+		v := Violation{
+			LeaseID:    lease.ID,
+			Node:       node,
+			GPU:        uuid,
+			User:       user,
+			ReservedBy: lease.User,
+			Until:      lease.End,
+			DetectedAt: at,
+		}
+		v.GPUID, _ = proc["gpu_id"].(string)
+		v.PID = fmt.Sprint(proc["pid"])
+		v.ProcessName, _ = proc["name"].(string)
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// ProcessSource returns the current GPU processes per node
+type ProcessSource func() map[string][]map[string]interface{}
+
+// Watcher periodically checks running processes against the active leases,
+// logging and alerting once for each new violation
+type Watcher struct {
+	store    *Store
+	source   ProcessSource
+	alertLog string
+	current  []Violation
+	seen     map[string]time.Time // Violation key -> first detection
+	mu       sync.RWMutex
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewWatcher creates a watcher; new violations are appended to alertLog (if set)
+func NewWatcher(store *Store, alertLog string, source ProcessSource) *Watcher {
+	return &Watcher{
+		store:    store,
+		source:   source,
+		alertLog: alertLog,
+		seen:     make(map[string]time.Time),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start checks for violations every interval until Stop is called
+func (w *Watcher) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stopChan:
+				return
+			case at := <-ticker.C:
+				w.check(at)
+			}
+		}
+	}()
+}
+
+// Stop stops the watcher; calling it again has no effect
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stopChan) })
+}
+
+// Violations returns the violations found by the last check
+func (w *Watcher) Violations() []Violation {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return append([]Violation{}, w.current...)
+}
+
+func (w *Watcher) check(at time.Time) {
+	var violations []Violation
+	for node, processes := range w.source() {
+		violations = append(violations, w.store.Check(node, processes, at)...)
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		return violations[i].key() < violations[j].key()
+	})
+
+	w.mu.Lock()
+	seen := make(map[string]time.Time, len(violations))
+	var fresh []Violation
+	for i, v := range violations {
+		if first, ok := w.seen[v.key()]; ok {
+			violations[i].DetectedAt = first
+		} else {
+			fresh = append(fresh, v)
+		}
+		seen[v.key()] = violations[i].DetectedAt
+	}
+	w.seen = seen
+	w.current = violations
+	w.mu.Unlock()
+
+	for _, v := range fresh {
+		message := fmt.Sprintf("%s (pid %s, %s) on %s uses a GPU reserved by %s until %s",
+			v.User, v.PID, v.ProcessName, v.Node, v.ReservedBy, v.Until.Local().Format("2006-01-02 15:04"))
+		log.Printf("⚠️  Reservation: %s", message)
+		w.appendAlert(v, message)
+	}
+}
+
+// appendAlert records a violation in the alert history log shown by the dashboard and CLI
+func (w *Watcher) appendAlert(v Violation, message string) {
+	if w.alertLog == "" {
+		return
+	}
+	f, err := os.OpenFile(w.alertLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Failed to write alert log: %v", err)
+		return
+	}
+	defer f.Close()
+
+	gpu := v.GPUID
+	if gpu == "" {
+		gpu = v.GPU
+	}
+	fmt.Fprintf(f, "[%s] GPU %s - warning Reservation: %s\n",
+		v.DetectedAt.Format("2006-01-02 15:04:05"), gpu, strings.ReplaceAll(message, "\n", " "))
+}
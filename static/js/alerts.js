@@ -15,7 +15,13 @@ const AlertManager = {
         memory_warning: 85,
         memory_critical: 95,
         power_warning: 90,
-        power_critical: 98
+        power_critical: 98,
+        disk_warning: 85,
+        disk_critical: 95,
+        inodes_warning: 85,
+        inodes_critical: 95,
+        disk_full_hours_warning: 24,
+        disk_full_hours_critical: 2
     },
     settings: {
         enableBrowserNotifications: true,
@@ -41,7 +47,10 @@ const AlertTypes = {
     MEMORY: 'Memory',
     POWER: 'Power',
     UTILIZATION: 'Utilization',
-    FAN: 'Fan Speed'
+    FAN: 'Fan Speed',
+    DISK: 'Disk',
+    INODES: 'Inodes',
+    DISK_FULL: 'Time Until Full'
 };
 
 /**
@@ -81,7 +90,8 @@ async function loadThresholds() {
         const response = await fetch('/api/alert-thresholds');
         if (response.ok) {
             const thresholds = await response.json();
-            AlertManager.thresholds = thresholds;
+            // Thresholds saved by older versions lack newer keys; keep their defaults
+            AlertManager.thresholds = { ...AlertManager.thresholds, ...thresholds };
             console.log('Loaded alert thresholds:', thresholds);
             updateThresholdUI();
         }
@@ -103,6 +113,10 @@ async function saveThresholds() {
 
         if (response.ok) {
             showToast('Alert thresholds saved successfully', 'success');
+        } else if (response.status === 403) {
+            showToast('Your role does not allow changing alert thresholds', 'error');
+        } else if (response.status === 429) {
+            showToast('Too many changes, try again in ' + (response.headers.get('Retry-After') || 'a few') + ' seconds', 'error');
         } else {
             showToast('Failed to save thresholds', 'error');
         }
@@ -296,6 +310,32 @@ function checkAlerts(gpuData) {
     alerts.forEach(alertData => processAlert(alertData));
 }
 
+/**
+ * Raise the filesystem alerts evaluated by the server (space, inodes, time until full)
+ */
+function checkFilesystemAlerts(filesystems) {
+    if (!Array.isArray(filesystems)) return;
+
+    const types = {
+        disk: AlertTypes.DISK,
+        inodes: AlertTypes.INODES,
+        time_until_full: AlertTypes.DISK_FULL
+    };
+    filesystems.forEach(fs => {
+        (fs.alerts || []).forEach(alert => {
+            processAlert({
+                gpu_id: fs.mountpoint,
+                gpu_name: fs.mountpoint,
+                type: types[alert.metric] || alert.metric,
+                severity: alert.level,
+                value: alert.value,
+                threshold: alert.threshold,
+                message: `${fs.mountpoint}: ${alert.message}`
+            });
+        });
+    });
+}
+
 /**
  * Update Alert Banner
  */
@@ -385,7 +425,7 @@ function updateAlertHistoryUI() {
                         <span class="alert-icon">${severityInfo.icon}</span>
                         <span class="alert-time">${formatTime(alert.timestamp)}</span>
                         <span class="alert-severity alert-severity-${alert.severity}">${severityInfo.label}</span>
-                        <span class="alert-gpu">${alert.gpuName}</span>
+                        <span class="alert-gpu">${escapeHTML(alert.gpuName)}</span>
                         ${badge}
                     </div>
                     ${isActive && !isAcknowledged ? `
@@ -397,7 +437,7 @@ function updateAlertHistoryUI() {
                     ` : ''}
                 </div>
                 <div class="alert-item-content">
-                    <div class="alert-message">${alert.message}</div>
+                    <div class="alert-message">${escapeHTML(alert.message)}</div>
                     <div class="alert-details">
                         <span class="alert-detail-item">${alert.type}: <strong>${formatAlertValue(alert.type, alert.value)}</strong></span>
                         <span class="alert-detail-item">Threshold: <strong>${formatAlertValue(alert.type, alert.threshold)}</strong></span>
@@ -426,6 +466,14 @@ function updateThresholdUI() {
     document.getElementById('threshold-power-warning').value = AlertManager.thresholds.power_warning;
     document.getElementById('threshold-power-critical').value = AlertManager.thresholds.power_critical;
 
+    // Filesystems
+    document.getElementById('threshold-disk-warning').value = AlertManager.thresholds.disk_warning;
+    document.getElementById('threshold-disk-critical').value = AlertManager.thresholds.disk_critical;
+    document.getElementById('threshold-inodes-warning').value = AlertManager.thresholds.inodes_warning;
+    document.getElementById('threshold-inodes-critical').value = AlertManager.thresholds.inodes_critical;
+    document.getElementById('threshold-full-warning').value = AlertManager.thresholds.disk_full_hours_warning;
+    document.getElementById('threshold-full-critical').value = AlertManager.thresholds.disk_full_hours_critical;
+
     // Update preview values
     updateThresholdPreviews();
 }
@@ -448,6 +496,19 @@ function updateThresholdPreviews() {
         document.getElementById('threshold-power-warning').value + '%';
     document.getElementById('preview-power-critical').textContent =
         document.getElementById('threshold-power-critical').value + '%';
+
+    document.getElementById('preview-disk-warning').textContent =
+        document.getElementById('threshold-disk-warning').value + '%';
+    document.getElementById('preview-disk-critical').textContent =
+        document.getElementById('threshold-disk-critical').value + '%';
+    document.getElementById('preview-inodes-warning').textContent =
+        document.getElementById('threshold-inodes-warning').value + '%';
+    document.getElementById('preview-inodes-critical').textContent =
+        document.getElementById('threshold-inodes-critical').value + '%';
+    document.getElementById('preview-full-warning').textContent =
+        document.getElementById('threshold-full-warning').value + 'h';
+    document.getElementById('preview-full-critical').textContent =
+        document.getElementById('threshold-full-critical').value + 'h';
 }
 
 /**
@@ -688,6 +749,12 @@ function saveAlertConfig() {
     AlertManager.thresholds.memory_critical = parseInt(document.getElementById('threshold-memory-critical').value);
     AlertManager.thresholds.power_warning = parseInt(document.getElementById('threshold-power-warning').value);
     AlertManager.thresholds.power_critical = parseInt(document.getElementById('threshold-power-critical').value);
+    AlertManager.thresholds.disk_warning = parseInt(document.getElementById('threshold-disk-warning').value);
+    AlertManager.thresholds.disk_critical = parseInt(document.getElementById('threshold-disk-critical').value);
+    AlertManager.thresholds.inodes_warning = parseInt(document.getElementById('threshold-inodes-warning').value);
+    AlertManager.thresholds.inodes_critical = parseInt(document.getElementById('threshold-inodes-critical').value);
+    AlertManager.thresholds.disk_full_hours_warning = parseInt(document.getElementById('threshold-full-warning').value);
+    AlertManager.thresholds.disk_full_hours_critical = parseInt(document.getElementById('threshold-full-critical').value);
 
     // Validate thresholds
     if (AlertManager.thresholds.temp_warning >= AlertManager.thresholds.temp_critical) {
@@ -702,6 +769,18 @@ function saveAlertConfig() {
         showToast('Power warning must be less than critical', 'error');
         return;
     }
+    if (AlertManager.thresholds.disk_warning >= AlertManager.thresholds.disk_critical) {
+        showToast('Disk space warning must be less than critical', 'error');
+        return;
+    }
+    if (AlertManager.thresholds.inodes_warning >= AlertManager.thresholds.inodes_critical) {
+        showToast('Inode warning must be less than critical', 'error');
+        return;
+    }
+    if (AlertManager.thresholds.disk_full_hours_warning <= AlertManager.thresholds.disk_full_hours_critical) {
+        showToast('Time until full warning must be longer than critical', 'error');
+        return;
+    }
 
     // Save to backend
     saveThresholds();
@@ -719,7 +798,13 @@ function resetThresholds() {
         memory_warning: 85,
         memory_critical: 95,
         power_warning: 90,
-        power_critical: 98
+        power_critical: 98,
+        disk_warning: 85,
+        disk_critical: 95,
+        inodes_warning: 85,
+        inodes_critical: 95,
+        disk_full_hours_warning: 24,
+        disk_full_hours_critical: 2
     };
 
     updateThresholdUI();
@@ -783,6 +868,11 @@ function formatAlertValue(type, value) {
             return `${value.toFixed(1)}%`;
         case AlertTypes.FAN:
             return `${Math.round(value)} RPM`;
+        case AlertTypes.DISK:
+        case AlertTypes.INODES:
+            return `${value.toFixed(1)}%`;
+        case AlertTypes.DISK_FULL:
+            return `${value.toFixed(1)} h`;
         default:
             return value.toFixed(1);
     }
@@ -9,7 +9,7 @@ function createOverviewCard(gpuId, gpuInfo) {
     const memPercent = (memory_used / memory_total) * 100;
 
     return `
-        <div class="overview-gpu-card" data-gpu-id="${gpuId}" onclick="switchToView('gpu-${gpuId}')" style="pointer-events: auto;">
+        <div class="overview-gpu-card" data-gpu-id="${gpuId}" data-gpu-uuid="${gpuInfo.uuid || ''}" onclick="switchToView('gpu-${gpuId}')" style="pointer-events: auto;">
             <div class="overview-header">
                 <div>
                     <h2 style="font-size: 1.5rem; font-weight: 700; background: var(--primary-gradient); -webkit-background-clip: text; -webkit-text-fill-color: transparent; background-clip: text; margin-bottom: 0.25rem;">
@@ -738,6 +738,13 @@ function createGPUCard(gpuId, gpuInfo) {
     `;
 }
 
+// Helper function to escape text from the server before putting it into markup
+function escapeHTML(value) {
+    return String(value).replace(/[&<>"']/g, ch => ({
+        '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'
+    })[ch]);
+}
+
 // Helper function to format memory values
 function formatMemory(mb) {
     if (mb >= 1024) {
@@ -1085,6 +1092,9 @@ function updateProcesses(processes) {
                     <span style="color: var(--text-secondary); font-size: 0.85rem; margin-left: 0.5rem;">PID: ${proc.pid}</span>
                     <span style="background: ${typeBadgeColor}; color: white; font-size: 0.65rem; padding: 0.15rem 0.4rem; border-radius: 0.25rem; margin-left: 0.5rem; font-weight: 600; text-transform: uppercase;">${procType}</span>
                     ${proc.gpu_id !== undefined ? `<span style="color: var(--text-secondary); font-size: 0.75rem; margin-left: 0.5rem;">GPU ${proc.gpu_id}</span>` : ''}
+                    ${proc.container_id ? `<span style="color: var(--text-secondary); font-size: 0.75rem; margin-left: 0.5rem;" title="${escapeHTML(proc.container_id)}">${escapeHTML(proc.container_runtime)}: ${escapeHTML(proc.container_name || proc.container_id.substring(0, 12))}</span>` : ''}
+                    ${proc.k8s_pod ? `<span style="color: var(--text-secondary); font-size: 0.75rem; margin-left: 0.5rem;">pod: ${escapeHTML(proc.k8s_namespace)}/${escapeHTML(proc.k8s_pod)}</span>` : ''}
+                    ${proc.slurm_job_id ? `<span style="color: var(--text-secondary); font-size: 0.75rem; margin-left: 0.5rem;">job ${escapeHTML(proc.slurm_job_id)}${proc.slurm_job_user ? ` (${escapeHTML(proc.slurm_job_user)})` : ''}</span>` : ''}
                 </div>
                 <div class="process-memory">
                     <span style="font-size: 1.1rem; font-weight: 700;">${formatMemory(proc.memory)}</span>
@@ -0,0 +1,76 @@
+/**
+ * GPU reservations - shows advisory leases and conflicting processes on GPU cards
+ */
+
+const RESERVATION_REFRESH_MS = 15000;
+
+async function refreshReservations() {
+    let data;
+    try {
+        const response = await fetch('/api/v1/reservations');
+        if (response.status === 404) {
+            // Reservations are disabled on this server
+            return false;
+        }
+        if (!response.ok) {
+            return true;
+        }
+        data = await response.json();
+    } catch (error) {
+        console.error('Error loading reservations:', error);
+        return true;
+    }
+
+    const now = new Date();
+    const byGPU = {};
+    (data.reservations || []).forEach(lease => {
+        if (new Date(lease.start) > now) {
+            return; // Upcoming leases are not shown on the cards yet
+        }
+        lease.gpus.forEach(uuid => {
+            byGPU[uuid.toLowerCase()] = { lease, violations: [] };
+        });
+    });
+    (data.violations || []).forEach(v => {
+        const entry = byGPU[v.gpu.toLowerCase()];
+        if (entry) {
+            entry.violations.push(v);
+        }
+    });
+
+    document.querySelectorAll('.overview-gpu-card[data-gpu-uuid]').forEach(card => {
+        const uuid = card.getAttribute('data-gpu-uuid').toLowerCase();
+        let badge = card.querySelector('.reservation-badge');
+        const entry = byGPU[uuid];
+
+        if (!entry) {
+            if (badge) {
+                badge.remove();
+            }
+            return;
+        }
+
+        if (!badge) {
+            card.querySelector('.overview-header').insertAdjacentHTML('afterend', '<div class="reservation-badge"></div>');
+            badge = card.querySelector('.reservation-badge');
+        }
+
+        const until = new Date(entry.lease.end).toLocaleString([], { month: 'short', day: 'numeric', hour: '2-digit', minute: '2-digit' });
+        let text = `🔒 Reserved by ${entry.lease.user} until ${until}`;
+        if (entry.lease.note) {
+            text += ` — ${entry.lease.note}`;
+        }
+        entry.violations.forEach(v => {
+            text += `\n⚠️ ${v.user} is running ${v.process_name || 'a process'} (PID ${v.pid}) here`;
+        });
+        badge.textContent = text;
+        badge.classList.toggle('reservation-conflict', entry.violations.length > 0);
+    });
+    return true;
+}
+
+document.addEventListener('DOMContentLoaded', async function() {
+    if (await refreshReservations()) {
+        setInterval(refreshReservations, RESERVATION_REFRESH_MS);
+    }
+});
@@ -292,6 +292,89 @@ socket.onerror = function(error) {
     document.getElementById('connection-status').style.color = '#f5576c';
 };
 
+// Hub mode view options from the URL, e.g. ?labels=team=ml&group_by=rack
+const clusterViewParams = new URLSearchParams(window.location.search);
+const clusterLabelSelector = parseLabelSelector(clusterViewParams.get('labels') || '');
+const clusterGroupBy = clusterViewParams.get('group_by') || '';
+
+/**
+ * Parse a label selector like "rack=r1,team=ml,gpu" (a key without value only requires presence)
+ */
+function parseLabelSelector(selector) {
+    const result = {};
+    selector.split(',').forEach(part => {
+        const [key, ...rest] = part.split('=');
+        if (key.trim()) {
+            result[key.trim()] = rest.join('=').trim();
+        }
+    });
+    return result;
+}
+
+function matchesLabelSelector(selector, labels) {
+    return Object.entries(selector).every(([key, value]) =>
+        key in labels && (value === '' || labels[key] === value));
+}
+
+/**
+ * Remove a node group and clean up its GPU charts and tabs
+ */
+function removeNodeGroup(nodeGroup) {
+    nodeGroup.querySelectorAll('[data-gpu-id]').forEach(card => {
+        const gpuId = card.getAttribute('data-gpu-id');
+        // Clean up chart data
+        if (chartData[gpuId]) {
+            delete chartData[gpuId];
+        }
+        if (lastDOMUpdate[gpuId]) {
+            delete lastDOMUpdate[gpuId];
+        }
+        // Remove the GPU tab
+        removeGPUTab(gpuId);
+    });
+    
+    // Remove the entire node group from the UI
+    nodeGroup.remove();
+}
+
+/**
+ * Order node groups under one header per value of the grouping label
+ */
+function applyNodeGrouping(overviewContainer, nodes) {
+    if (!clusterGroupBy) {
+        return;
+    }
+    
+    const groupOf = nodeData => (nodeData.labels || {})[clusterGroupBy] || '(none)';
+    const values = [...new Set(Object.values(nodes).map(groupOf))].sort();
+    
+    overviewContainer.querySelectorAll('[data-label-group]').forEach(header => {
+        if (!values.includes(header.getAttribute('data-label-group'))) {
+            header.remove();
+        }
+    });
+    
+    values.forEach((value, index) => {
+        let header = overviewContainer.querySelector(`[data-label-group="${CSS.escape(value)}"]`);
+        if (!header) {
+            // Group key and label values are untrusted, so build the header as text
+            header = document.createElement('div');
+            header.className = 'label-group-header';
+            header.setAttribute('data-label-group', value);
+            header.textContent = `${clusterGroupBy}: ${value}`;
+            overviewContainer.appendChild(header);
+        }
+        header.style.order = 2 * index;
+    });
+    
+    Object.entries(nodes).forEach(([nodeName, nodeData]) => {
+        const nodeGroup = overviewContainer.querySelector(`[data-node="${CSS.escape(nodeName)}"]`);
+        if (nodeGroup) {
+            nodeGroup.style.order = 2 * values.indexOf(groupOf(nodeData)) + 1;
+        }
+    });
+}
+
 /**
  * Handle cluster/hub mode data
  * Data structure: { mode: 'hub', nodes: {...}, cluster_stats: {...} }
@@ -322,23 +405,49 @@ function handleClusterData(data) {
         return;
     }
     
-    // Render GPUs grouped by node (minimal grouping)
+    // Only show nodes matching the label selector from the URL
+    const visibleNodes = {};
     Object.entries(data.nodes).forEach(([nodeName, nodeData]) => {
+        if (matchesLabelSelector(clusterLabelSelector, nodeData.labels || {})) {
+            visibleNodes[nodeName] = nodeData;
+        } else {
+            const hiddenGroup = overviewContainer.querySelector(`[data-node="${CSS.escape(nodeName)}"]`);
+            if (hiddenGroup) {
+                removeNodeGroup(hiddenGroup);
+            }
+        }
+    });
+    
+    // Render GPUs grouped by node (minimal grouping)
+    Object.entries(visibleNodes).forEach(([nodeName, nodeData]) => {
         // Get or create node group container
-        let nodeGroup = overviewContainer.querySelector(`[data-node="${nodeName}"]`);
+        let nodeGroup = overviewContainer.querySelector(`[data-node="${CSS.escape(nodeName)}"]`);
         if (!nodeGroup) {
-            overviewContainer.insertAdjacentHTML('beforeend', `
-                <div class="node-group" data-node="${nodeName}">
-                    <div class="node-label">${nodeName}</div>
-                    <div class="node-grid"></div>
-                </div>
-            `);
-            nodeGroup = overviewContainer.querySelector(`[data-node="${nodeName}"]`);
+            // Node names come from the nodes, so build the group as text
+            nodeGroup = document.createElement('div');
+            nodeGroup.className = 'node-group';
+            nodeGroup.setAttribute('data-node', nodeName);
+            const nodeLabel = document.createElement('div');
+            nodeLabel.className = 'node-label';
+            nodeLabel.textContent = nodeName;
+            const nodeGridEl = document.createElement('div');
+            nodeGridEl.className = 'node-grid';
+            nodeGroup.append(nodeLabel, nodeGridEl);
+            overviewContainer.appendChild(nodeGroup);
         }
         
         const nodeGrid = nodeGroup.querySelector('.node-grid');
         
-        if (nodeData.status === 'online') {
+        // Stale nodes are still connected but stopped sending data - keep their last values visible
+        const isStale = nodeData.status === 'stale';
+        const labelText = Object.entries(nodeData.labels || {}).map(([key, value]) => `${key}=${value}`).join(' · ');
+        nodeGroup.querySelector('.node-label').textContent =
+            nodeName + (labelText ? ` · ${labelText}` : '') + (isStale ? ' (stale)' : '');
+        nodeGroup.style.opacity = isStale ? '0.5' : '';
+        
+        if (isStale) {
+            return;
+        } else if (nodeData.status === 'online') {
             // Node is online - process its GPUs normally
             Object.entries(nodeData.gpus).forEach(([gpuId, gpuInfo]) => {
                 const fullGpuId = `${nodeName}-${gpuId}`;
@@ -367,27 +476,14 @@ function handleClusterData(data) {
             });
         } else {
             // Node is offline - remove entire node group
-            const existingCards = nodeGrid.querySelectorAll('[data-gpu-id]');
-            existingCards.forEach(card => {
-                const gpuId = card.getAttribute('data-gpu-id');
-                // Clean up chart data
-                if (chartData[gpuId]) {
-                    delete chartData[gpuId];
-                }
-                if (lastDOMUpdate[gpuId]) {
-                    delete lastDOMUpdate[gpuId];
-                }
-                // Remove the GPU tab
-                removeGPUTab(gpuId);
-            });
-            
-            // Remove the entire node group from the UI
-            nodeGroup.remove();
+            removeNodeGroup(nodeGroup);
         }
     });
     
+    applyNodeGrouping(overviewContainer, visibleNodes);
+    
     // Update processes and system info (use first online node)
-    const firstOnlineNode = Object.values(data.nodes).find(n => n.status === 'online');
+    const firstOnlineNode = Object.values(visibleNodes).find(n => n.status === 'online');
     if (firstOnlineNode) {
         if (!lastDOMUpdate.system || (now - lastDOMUpdate.system) >= DOM_UPDATE_INTERVAL) {
             pendingUpdates.set('_system', {
@@ -415,7 +511,7 @@ function createClusterGPUCard(nodeName, gpuId, gpuInfo) {
     const memPercent = (memory_used / memory_total) * 100;
 
     return `
-        <div class="overview-gpu-card" data-gpu-id="${fullGpuId}" onclick="switchToView('gpu-${fullGpuId}')" style="pointer-events: auto;">
+        <div class="overview-gpu-card" data-gpu-id="${fullGpuId}" data-gpu-uuid="${gpuInfo.uuid || ''}" onclick="switchToView('gpu-${fullGpuId}')" style="pointer-events: auto;">
             <div class="overview-header">
                 <div>
                     <h2 style="font-size: 1.5rem; font-weight: 700; background: var(--primary-gradient); -webkit-background-clip: text; -webkit-text-fill-color: transparent; background-clip: text; margin-bottom: 0.25rem;">
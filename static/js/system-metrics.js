@@ -342,16 +342,73 @@ function updateSystemMetrics(metricsData) {
 
     // Update network connections
     updateNetworkConnections(metricsData.connections || []);
+    updateFilesystems(metricsData.filesystems);
 
     // Update largest files
     updateLargestFiles(metricsData.largest_files || []);
 
     // Update connection map with geolocation data
     if (metricsData.geo_locations) {
-        updateConnectionMap(metricsData.geo_locations);
+        updateConnectionMap(metricsData.geo_locations, metricsData.geo_provider);
     }
 }
 
+// Human readable duration of a time-until-full prediction
+function formatTimeUntilFull(seconds) {
+    if (seconds === undefined || seconds === null) return '—';
+    if (seconds < 3600) return Math.max(1, Math.round(seconds / 60)) + ' min';
+    if (seconds < 48 * 3600) return (seconds / 3600).toFixed(1) + ' h';
+    return Math.round(seconds / 86400) + ' days';
+}
+
+// Update mounted filesystems table and raise their alerts
+function updateFilesystems(filesystems) {
+    const tbody = document.getElementById('filesystems-tbody');
+    const countEl = document.getElementById('filesystem-count');
+
+    if (!tbody || !Array.isArray(filesystems)) return;
+
+    if (typeof checkFilesystemAlerts === 'function') {
+        checkFilesystemAlerts(filesystems);
+    }
+    if (countEl) {
+        countEl.textContent = filesystems.length + ' mounted filesystem' + (filesystems.length !== 1 ? 's' : '');
+    }
+    if (filesystems.length === 0) {
+        tbody.innerHTML = '<tr><td colspan="7" style="text-align: center; padding: 2rem; color: var(--text-secondary);">No filesystems</td></tr>';
+        return;
+    }
+
+    const levelColor = (fs, metric) => {
+        const alert = (fs.alerts || []).find(a => a.metric === metric);
+        if (!alert) return 'var(--text-primary)';
+        return alert.level === 'critical' ? '#f5576c' : '#fbbf24';
+    };
+
+    tbody.innerHTML = filesystems.map(fs => {
+        const flags = (fs.read_only ? ' <span style="color: var(--text-secondary); font-size: 0.7rem;">RO</span>' : '') +
+            (fs.stale ? ' <span style="color: #fbbf24; font-size: 0.7rem;" title="Not responding; showing the last known usage">STALE</span>' : '');
+        const inodes = fs.inodes_total > 0 ? fs.inodes_used_percent.toFixed(1) + '%' : '—';
+        const rate = fs.fill_rate > 0 ? '+' + formatDiskUsageBytes(fs.fill_rate * 3600) + '/h'
+            : fs.fill_rate < 0 ? '−' + formatDiskUsageBytes(-fs.fill_rate * 3600) + '/h' : '—';
+        let fullIn = fs.seconds_until_full;
+        if (fs.seconds_until_inodes_full !== undefined && (fullIn === undefined || fs.seconds_until_inodes_full < fullIn)) {
+            fullIn = fs.seconds_until_inodes_full;
+        }
+
+        return '<tr>' +
+            '<td style="font-family: monospace; font-weight: 500;" title="' + escapeHTML((fs.options || []).join(',')) + '">' + escapeHTML(fs.mountpoint) + flags + '</td>' +
+            '<td style="font-family: monospace; font-size: 0.85rem;">' + escapeHTML(fs.device) + '</td>' +
+            '<td>' + escapeHTML(fs.fstype) + '</td>' +
+            '<td style="color: ' + levelColor(fs, 'disk') + ';">' + fs.used_percent.toFixed(1) + '% of ' + formatDiskUsageBytes(fs.total) +
+            ' <span style="color: var(--text-secondary); font-size: 0.8rem;">(' + formatDiskUsageBytes(fs.free) + ' free)</span></td>' +
+            '<td style="color: ' + levelColor(fs, 'inodes') + ';">' + inodes + '</td>' +
+            '<td>' + rate + '</td>' +
+            '<td style="color: ' + levelColor(fs, 'time_until_full') + ';">' + formatTimeUntilFull(fullIn) + '</td>' +
+            '</tr>';
+    }).join('');
+}
+
 // Update network connections table
 function updateNetworkConnections(connections) {
     const tbody = document.getElementById('connections-tbody');
@@ -428,7 +485,7 @@ function updateLargestFiles(files) {
         return '<div class="file-item">' +
                '<div class="file-rank">#' + (index + 1) + '</div>' +
                '<div class="file-details">' +
-               '<div class="file-path" title="' + file.path + '">' + file.path + '</div>' +
+               '<div class="file-path" title="' + escapeHTML(file.path) + '">' + escapeHTML(file.path) + '</div>' +
                '<div class="file-meta">' +
                '<span class="file-size">' + sizeDisplay + '</span>' +
                '<span class="file-date">Modified: ' + file.mod_time + '</span>' +
@@ -436,6 +493,26 @@ function updateLargestFiles(files) {
     }).join('');
 }
 
+// Poll a background filesystem scan until it finishes, showing its progress
+async function waitForScan(job, progressEl) {
+    while (job.status === 'running') {
+        if (progressEl) {
+            progressEl.textContent = 'Scanned ' + job.files_scanned.toLocaleString() + ' files in ' +
+                job.dirs_scanned.toLocaleString() + ' directories';
+        }
+        await new Promise(resolve => setTimeout(resolve, 1000));
+        const response = await fetch('/api/v1/files/scans/' + job.id);
+        job = await response.json();
+        if (!response.ok) {
+            throw new Error(job.error || response.statusText);
+        }
+    }
+    if (job.status !== 'done') {
+        throw new Error(job.error || 'Scan ' + job.status);
+    }
+    return job;
+}
+
 // Refresh largest files from a specific directory
 async function refreshLargestFiles() {
     const directoryInput = document.getElementById('largest-files-directory');
@@ -459,20 +536,35 @@ async function refreshLargestFiles() {
     listEl.innerHTML = '<div style="display: flex; flex-direction: column; align-items: center; justify-content: center; padding: 3rem; color: var(--text-secondary);">' +
         '<div class="spinner" style="margin-bottom: 1.5rem; border: 3px solid rgba(0, 212, 255, 0.1); border-top: 3px solid #00d4ff; border-radius: 50%; width: 48px; height: 48px; animation: spin 0.8s linear infinite;"></div>' +
         '<div style="font-size: 1rem; font-weight: 600; color: var(--text-primary);">Loading files...</div>' +
-        '<div style="font-size: 0.85rem; margin-top: 0.5rem; opacity: 0.7;">Scanning ' + directory + '</div>' +
+        '<div style="font-size: 0.85rem; margin-top: 0.5rem; opacity: 0.7;">Scanning ' + escapeHTML(directory) + '</div>' +
+        '<div class="scan-progress" style="font-size: 0.8rem; margin-top: 0.25rem; opacity: 0.7;"></div>' +
         '</div>';
 
     try {
-        const response = await fetch('/api/largest-files?directory=' + encodeURIComponent(directory));
-        const data = await response.json();
+        const response = await fetch('/api/largest-files?directory=' + encodeURIComponent(directory) + '&refresh=true');
+        let data = await response.json();
+
+        if (!response.ok) {
+            throw new Error(data.error || response.statusText);
+        }
+
+        // Large directories are scanned in the background
+        const job = data.job && await waitForScan(data.job, listEl.querySelector('.scan-progress'));
+        if (job && job.result !== undefined) {
+            data = { files: job.result || [] };
+        }
 
         if (data.files && data.files.length > 0) {
             updateLargestFiles(data.files);
+            if (job && job.truncated) {
+                listEl.insertAdjacentHTML('beforeend', '<div style="text-align: center; padding: 0.5rem; font-size: 0.8rem; color: var(--text-secondary);">' +
+                    'Partial results: scan stopped at the ' + job.truncated.replace('_', ' ') + ' limit</div>');
+            }
         } else {
             listEl.innerHTML = '<div style="text-align: center; padding: 2rem; color: var(--text-secondary);">' +
                 '<div style="font-size: 3rem; margin-bottom: 1rem;">📂</div>' +
                 '<div style="font-size: 1rem; font-weight: 600; margin-bottom: 0.5rem;">No files found</div>' +
-                '<div style="font-size: 0.85rem; opacity: 0.7;">No files found in ' + directory + '</div>' +
+                '<div style="font-size: 0.85rem; opacity: 0.7;">No files found in ' + escapeHTML(directory) + '</div>' +
                 '</div>';
         }
     } catch (error) {
@@ -480,7 +572,7 @@ async function refreshLargestFiles() {
         listEl.innerHTML = '<div style="text-align: center; padding: 2rem; color: #f5576c;">' +
             '<div style="font-size: 3rem; margin-bottom: 1rem;">⚠️</div>' +
             '<div style="font-size: 1rem; font-weight: 600; margin-bottom: 0.5rem;">Error loading files</div>' +
-            '<div style="font-size: 0.85rem; opacity: 0.8;">' + error.message + '</div>' +
+            '<div style="font-size: 0.85rem; opacity: 0.8;">' + escapeHTML(error.message) + '</div>' +
             '</div>';
     }
 }
@@ -736,7 +828,7 @@ function initConnectionMap() {
 }
 
 // Update connection map with geolocation data
-function updateConnectionMap(geoLocations) {
+function updateConnectionMap(geoLocations, provider) {
     if (!geoLocations) return;
 
     // Initialize map if needed
@@ -744,7 +836,7 @@ function updateConnectionMap(geoLocations) {
     if (!connectionMap) return;
 
     // Check if geolocation data has changed to avoid unnecessary updates
-    const currentGeoJson = JSON.stringify(geoLocations);
+    const currentGeoJson = JSON.stringify(geoLocations) + provider;
     if (currentGeoJson === lastGeoLocationsJson) {
         // Data hasn't changed, skip update to avoid closing popups
         return;
@@ -759,7 +851,9 @@ function updateConnectionMap(geoLocations) {
     const ipCount = Object.keys(geoLocations).length;
     const countEl = document.getElementById('map-connection-count');
     if (countEl) {
-        countEl.textContent = ipCount + ' external IP' + (ipCount !== 1 ? 's' : '');
+        countEl.textContent = provider === 'none'
+            ? 'Geolocation disabled'
+            : ipCount + ' external IP' + (ipCount !== 1 ? 's' : '');
     }
 
     // If no locations, reset to world view
@@ -879,6 +973,127 @@ function updateConnectionMap(geoLocations) {
     console.log('Updated map with ' + ipCount + ' locations, auto-zoomed to fit');
 }
 
+// Directory currently shown in the disk usage explorer
+let diskUsageDirectory = '';
+
+// Show the disk usage tree of a directory. Subdirectories of a scanned directory
+// are served from its cached scan, so drilling down is instant.
+async function refreshDiskUsage(directory, refresh) {
+    const directoryInput = document.getElementById('disk-usage-directory');
+    const categorySelect = document.getElementById('disk-usage-category');
+    const listEl = document.getElementById('disk-usage-list');
+
+    if (!directoryInput || !listEl) return;
+
+    if (directory !== undefined) {
+        directoryInput.value = directory;
+    }
+    directory = directoryInput.value.trim();
+    if (!directory) {
+        alert('Please enter a directory path');
+        return;
+    }
+
+    const query = '/api/v1/files/usage?directory=' + encodeURIComponent(directory) +
+        '&category=' + encodeURIComponent(categorySelect ? categorySelect.value : '');
+
+    listEl.innerHTML = '<div style="text-align: center; padding: 3rem; color: var(--text-secondary);">' +
+        '<div class="spinner" style="margin-bottom: 1.5rem; border: 3px solid rgba(0, 212, 255, 0.1); border-top: 3px solid #00d4ff; border-radius: 50%; width: 48px; height: 48px; animation: spin 0.8s linear infinite;"></div>' +
+        '<div style="font-size: 0.85rem; opacity: 0.7;">Scanning ' + escapeHTML(directory) + '</div>' +
+        '<div class="scan-progress" style="font-size: 0.8rem; margin-top: 0.25rem; opacity: 0.7;"></div>' +
+        '</div>';
+
+    try {
+        let response = await fetch(query + (refresh ? '&refresh=true' : ''));
+        let data = await response.json();
+        if (response.status === 202) {
+            await waitForScan(data.job, listEl.querySelector('.scan-progress'));
+            response = await fetch(query);
+            data = await response.json();
+        }
+        if (!response.ok) {
+            throw new Error(data.error || response.statusText);
+        }
+
+        diskUsageDirectory = data.directory;
+        directoryInput.value = data.directory;
+        updateDiskUsage(data.usage, data.job);
+    } catch (error) {
+        console.error('Error fetching disk usage:', error);
+        listEl.innerHTML = '<div style="text-align: center; padding: 2rem; color: #f5576c;">' +
+            '<div style="font-size: 3rem; margin-bottom: 1rem;">⚠️</div>' +
+            '<div style="font-size: 1rem; font-weight: 600; margin-bottom: 0.5rem;">Error loading disk usage</div>' +
+            '<div style="font-size: 0.85rem; opacity: 0.8;">' + escapeHTML(error.message) + '</div>' +
+            '</div>';
+    }
+}
+
+// Go up one level in the disk usage explorer
+function diskUsageUp() {
+    if (!diskUsageDirectory) return;
+    const parent = diskUsageDirectory.replace(/[\\/][^\\/]*$/, '') || '/';
+    if (parent !== diskUsageDirectory) {
+        refreshDiskUsage(parent);
+    }
+}
+
+// Render a directory's children as bars relative to the directory's size
+function updateDiskUsage(usage, job) {
+    const listEl = document.getElementById('disk-usage-list');
+    if (!listEl) return;
+
+    const children = usage.children || [];
+    let header = '<div style="padding: 0.5rem 0.75rem; font-size: 0.85rem; color: var(--text-secondary);">' +
+        '<strong style="color: var(--text-primary);">' + usage.actual_size_human + '</strong> in ' +
+        usage.files.toLocaleString() + ' files';
+    if (usage.sparse_files > 0) {
+        header += ' <span style="color: #fbbf24;">(' + usage.sparse_files + ' sparse, ' + usage.size_human + ' apparent)</span>';
+    }
+    if (job && job.truncated) {
+        header += ' · partial: scan stopped at the ' + job.truncated.replace('_', ' ') + ' limit';
+    }
+    header += '</div>';
+
+    if (children.length === 0) {
+        listEl.innerHTML = header + '<div style="text-align: center; padding: 2rem; color: var(--text-secondary);">No subdirectories with matching files</div>';
+        return;
+    }
+
+    const total = usage.actual_size || 1;
+    listEl.innerHTML = header + children.map(child => {
+        const percent = Math.min(100, child.actual_size / total * 100);
+        const categories = Object.entries(child.categories || {})
+            .map(([name, totals]) => '<span style="margin-left: 0.5rem; color: var(--text-secondary);">' + escapeHTML(name) + ' ' + formatDiskUsageBytes(totals.actual_size) + '</span>')
+            .join('');
+        return '<div class="file-item" style="cursor: pointer;" data-path="' + escapeHTML(child.path) + '">' +
+            '<div class="file-details">' +
+            '<div class="file-path" title="' + escapeHTML(child.path) + '">📁 ' + escapeHTML(child.name) + '</div>' +
+            '<div style="height: 6px; margin: 0.35rem 0; background: rgba(255, 255, 255, 0.05); border-radius: 3px;">' +
+            '<div style="height: 100%; width: ' + percent.toFixed(1) + '%; background: var(--primary-gradient); border-radius: 3px;"></div></div>' +
+            '<div class="file-meta">' +
+            '<span class="file-size">' + child.actual_size_human + ' (' + percent.toFixed(1) + '%)</span>' +
+            '<span class="file-date">' + child.files.toLocaleString() + ' files' + categories + '</span>' +
+            '</div></div></div>';
+    }).join('') + (usage.omitted_children > 0
+        ? '<div style="text-align: center; padding: 0.5rem; font-size: 0.8rem; color: var(--text-secondary);">' + usage.omitted_children + ' smaller directories not shown</div>'
+        : '');
+
+    listEl.querySelectorAll('.file-item[data-path]').forEach(item => {
+        item.addEventListener('click', () => refreshDiskUsage(item.dataset.path));
+    });
+}
+
+// Human readable byte count, matching the server's formatting
+function formatDiskUsageBytes(bytes) {
+    const units = ['B', 'KB', 'MB', 'GB', 'TB', 'PB'];
+    let i = 0;
+    while (bytes >= 1024 && i < units.length - 1) {
+        bytes /= 1024;
+        i++;
+    }
+    return (i === 0 ? bytes : bytes.toFixed(1)) + ' ' + units[i];
+}
+
 // Initialize largest files with user's home directory
 async function initializeLargestFiles() {
     try {
@@ -892,6 +1107,11 @@ async function initializeLargestFiles() {
                 directoryInput.placeholder = 'Enter directory path';
                 // Don't automatically query - wait for user to click refresh
             }
+            const usageInput = document.getElementById('disk-usage-directory');
+            if (usageInput) {
+                usageInput.value = data.home;
+                usageInput.placeholder = 'Enter directory path';
+            }
         }
     } catch (error) {
         console.error('Error fetching home directory:', error);
@@ -0,0 +1,160 @@
+// Package tlsutil builds TLS configurations for the server and for hub/node connections,
+// reloading certificates when their files are rotated
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// CertReloader serves a certificate/key pair and reloads it when the files change
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	watcher  *fsnotify.Watcher
+	mu       sync.RWMutex
+}
+
+// NewCertReloader loads a certificate/key pair
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the certificate/key pair again. The previous pair stays in use if that fails.
+func (r *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate %s: %w", r.certFile, err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the pair whenever something changes next to the files. Directories are
+// watched so that atomically replaced files and Kubernetes secret updates are noticed.
+func (r *CertReloader) Watch() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{filepath.Dir(r.certFile), filepath.Dir(r.keyFile)} {
+		if err := w.Add(dir); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	r.watcher = w
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				// A rotation may be caught half way, with a new certificate but the old key;
+				// the next event of the rotation then loads the complete pair
+				if err := r.Reload(); err == nil {
+					log.Printf("Reloaded TLS certificate %s", r.certFile)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Error watching %s: %v", r.certFile, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops watching the files
+func (r *CertReloader) Stop() {
+	if r.watcher != nil {
+		r.watcher.Close()
+	}
+}
+
+// GetCertificate serves the current pair as a server certificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// GetClientCertificate serves the current pair as a client certificate
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// ServerConfig returns a server configuration serving the reloader's certificate.
+// With a clientCAFile, clients must present a certificate signed by that CA,
+// unless requireClientCert is false, in which case certificates are only verified if given.
+func ServerConfig(r *CertReloader, clientCAFile string, requireClientCert bool) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if requireClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return cfg, nil
+}
+
+// ClientConfig returns a configuration for connections to other GPU Pro servers.
+// r (optional) supplies a client certificate; caFile (optional) replaces the system
+// roots for verifying the server.
+func ClientConfig(r *CertReloader, caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if r != nil {
+		cfg.GetClientCertificate = r.GetClientCertificate
+	}
+	if caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// loadCertPool reads PEM certificates from a file
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,165 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "GPU Pro test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a leaf certificate and key to dir/name.crt and dir/name.key
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestMutualTLSAndRotation(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, ca.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	serverCert, serverKey := ca.issue(t, dir, "node", 10)
+	clientCert, clientKey := ca.issue(t, dir, "hub", 20)
+
+	serverCerts, err := NewCertReloader(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := serverCerts.Watch(); err != nil {
+		t.Fatal(err)
+	}
+	defer serverCerts.Stop()
+	serverTLS, err := ServerConfig(serverCerts, caFile, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.Listener = tls.NewListener(srv.Listener, serverTLS)
+	srv.Start()
+	defer srv.Close()
+
+	get := func(cfg *tls.Config) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, DisableKeepAlives: true}}
+		return client.Get("https://" + srv.Listener.Addr().String())
+	}
+
+	// Without a client certificate the handshake fails
+	anonymous, err := ClientConfig(nil, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := get(anonymous); err == nil {
+		resp.Body.Close()
+		t.Error("server accepted a client without a certificate")
+	}
+
+	clientCerts, err := NewCertReloader(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutual, err := ClientConfig(clientCerts, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := get(mutual)
+	if err != nil {
+		t.Fatalf("mutual TLS request: %v", err)
+	}
+	if serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64(); serial != 10 {
+		t.Errorf("server certificate serial = %d, want 10", serial)
+	}
+	resp.Body.Close()
+
+	// Rotating the server certificate on disk is picked up without a restart
+	ca.issue(t, dir, "node", 11)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := get(mutual)
+		if err == nil {
+			serial := resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+			resp.Body.Close()
+			if serial == 11 {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("rotated certificate was not reloaded")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}